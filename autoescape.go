@@ -0,0 +1,500 @@
+package sintax
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/toaweme/sintax/functions"
+)
+
+// AutoEscape is how an engine escapes the values a template interpolates. See
+// WithAutoEscape.
+type AutoEscape int
+
+const (
+	// EscapeNone writes values as they are. It is the default.
+	EscapeNone AutoEscape = iota
+	// EscapeHTML escapes every value for HTML text or a quoted attribute value.
+	EscapeHTML
+	// EscapeXML escapes every value for XML character data or a quoted
+	// attribute value, using only the five entities XML predefines.
+	EscapeXML
+	// EscapeHTMLContext escapes every value for where it sits in the HTML
+	// around it: as text, as an attribute value, or as part of a URL in an
+	// attribute such as href or src. Inside a <script> element or an on*
+	// event-handler attribute it follows the JavaScript too: a value in a
+	// string literal is escaped as the string's body, and a value in code
+	// becomes a literal of its own, encoded as JSON. A value in a JavaScript
+	// comment or regular expression, and any value inside a <style> element or
+	// a style attribute, which it has no escaping for, fails the render with
+	// ErrUnescapable, unless it is a SafeString.
+	EscapeHTMLContext
+)
+
+// SafeString is text an engine with auto-escaping on writes out as is. See
+// functions.SafeString.
+type SafeString = functions.SafeString
+
+// WithAutoEscape makes the engine escape every value a template interpolates,
+// so a forgotten `| escape_html` cannot turn data into markup. Text written in
+// the template itself is trusted and never escaped. A SafeString is written as
+// is, which is what the `safe` modifier returns, and so is what a macro call, a
+// capture block, super() and the `template` modifier render, since the values
+// in it were escaped on the way in. The default is EscapeNone.
+func WithAutoEscape(mode AutoEscape) Option {
+	return func(c *config) { c.escape = mode }
+}
+
+// markup marks s, the text a macro, capture block or nested template rendered,
+// as safe under auto-escaping, so writing it does not escape it twice.
+func (r *TokenRenderer) markup(s string) any {
+	if r.escape == EscapeNone {
+		return s
+	}
+	return SafeString(s)
+}
+
+// markupText is what a modifier decorated with functions.AsMarkup returns under
+// EscapeHTMLContext. It is HTML already, which is fit as is only for text and
+// for a quoted attribute that is neither a URL nor a script, so anywhere else
+// it is decoded and escaped for where it lands like any other value. A
+// modifier it is handed to takes it as a SafeString.
+type markupText string
+
+// markedUp marks s, what a modifier decorated with functions.AsMarkup returned,
+// as markup under auto-escaping.
+func (r *TokenRenderer) markedUp(s string) any {
+	if r.escape == EscapeHTMLContext {
+		return markupText(s)
+	}
+	return SafeString(s)
+}
+
+// xmlEscaper escapes the five characters XML predefines entities for.
+var xmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+// unquotedEscaper escapes what html.EscapeString leaves alone but would end
+// or split an unquoted attribute value.
+var unquotedEscaper = strings.NewReplacer(
+	" ", "&#32;",
+	"\t", "&#9;",
+	"\n", "&#10;",
+	"\r", "&#13;",
+	"\f", "&#12;",
+	"=", "&#61;",
+	"`", "&#96;",
+)
+
+// markupPos is where in HTML markup the output has got to.
+type markupPos int
+
+const (
+	inText markupPos = iota
+	// inOpen is just past a "<" that may open a tag.
+	inOpen
+	// inBang is just past "<!", which opens a comment or a declaration.
+	inBang
+	inComment
+	inTagName
+	// inTag is inside a tag, between its attributes.
+	inTag
+	inAttrName
+	inAfterAttrName
+	inBeforeValue
+	inValue
+	// inRawText is inside a <script> or <style> element, whose text runs to its
+	// end tag.
+	inRawText
+)
+
+// urlPart is how far into a URL attribute value the output has got.
+type urlPart int
+
+const (
+	// urlStart is the start of the value, where a scheme would be.
+	urlStart urlPart = iota
+	urlPath
+	// urlQuery is past a "?" or "#".
+	urlQuery
+)
+
+// markupState follows the HTML an EscapeHTMLContext render writes, as far as
+// escaping needs to: whether the output is in text, inside a tag, or in an
+// attribute value, for a URL attribute, how far into the URL, and for a script,
+// where in its JavaScript. It reads
+// everything written, which is how a value lands in the right context whatever
+// branch, loop or include the text around it came from. An escaped value never
+// moves it out of the context it was escaped for.
+type markupState struct {
+	pos markupPos
+	// dashes counts the "-" just read, to find where a comment opens and ends.
+	dashes int
+	// attr is the lowercased name of the attribute being read, and quote the
+	// quote around its value, 0 when it has none.
+	attr  strings.Builder
+	quote byte
+	url   urlPart
+	// tag is the lowercased name of the tag being read, and closing whether it
+	// is an end tag.
+	tag     strings.Builder
+	closing bool
+	// raw is the name of the raw text element the output is inside, and end how
+	// many bytes of its end tag have just been read.
+	raw string
+	end int
+	// js follows the JavaScript of a <script> element, or of an on* attribute
+	// value when jsAttr is set. ent holds a character reference that attribute
+	// value is part way through, which the JavaScript reads decoded.
+	js     jsState
+	jsAttr bool
+	ent    []byte
+}
+
+// feed moves the state past s.
+func (m *markupState) feed(s string) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch m.pos {
+		case inText:
+			if c == '<' {
+				m.pos = inOpen
+			}
+		case inOpen:
+			switch {
+			case isLetter(c) || c == '/':
+				m.pos, m.closing = inTagName, c == '/'
+				m.tag.Reset()
+				if c != '/' {
+					m.tag.WriteByte(toLower(c))
+				}
+			case c == '!':
+				m.pos, m.dashes = inBang, 0
+			case c == '<':
+			default:
+				m.pos = inText
+			}
+		case inBang:
+			switch {
+			case c == '-':
+				if m.dashes++; m.dashes == 2 {
+					m.pos, m.dashes = inComment, 0
+				}
+			case c == '>':
+				m.pos = inText
+			default:
+				m.pos = inTag
+			}
+		case inComment:
+			switch {
+			case c == '-':
+				m.dashes++
+			case c == '>' && m.dashes >= 2:
+				m.pos = inText
+			default:
+				m.dashes = 0
+			}
+		case inTagName:
+			switch {
+			case c == '>':
+				m.closeTag()
+			case isSpace(c) || c == '/':
+				m.pos = inTag
+			default:
+				m.tag.WriteByte(toLower(c))
+			}
+		case inTag, inAfterAttrName:
+			switch {
+			case c == '>':
+				m.closeTag()
+			case c == '=' && m.pos == inAfterAttrName:
+				m.pos = inBeforeValue
+			case isSpace(c) || c == '/':
+				if c == '/' {
+					m.pos = inTag
+				}
+			default:
+				m.startAttr(c)
+			}
+		case inAttrName:
+			switch {
+			case c == '=':
+				m.pos = inBeforeValue
+			case c == '>':
+				m.closeTag()
+			case c == '/':
+				m.pos = inTag
+			case isSpace(c):
+				m.pos = inAfterAttrName
+			default:
+				m.attr.WriteByte(toLower(c))
+			}
+		case inBeforeValue:
+			switch {
+			case isSpace(c):
+			case c == '>':
+				m.closeTag()
+			case c == '"' || c == '\'':
+				m.startValue(c)
+			default:
+				m.startValue(0)
+				m.valueByte(c)
+			}
+		case inValue:
+			switch {
+			case m.quote != 0 && c == m.quote:
+				m.pos = inTag
+			case m.quote == 0 && isSpace(c):
+				m.pos = inTag
+			case m.quote == 0 && c == '>':
+				m.closeTag()
+			default:
+				m.valueByte(c)
+			}
+		case inRawText:
+			m.rawByte(c)
+		default:
+		}
+	}
+}
+
+// closeTag moves the state past the ">" that ends a tag, into the text of a
+// <script> or <style> element it opens.
+func (m *markupState) closeTag() {
+	m.pos = inText
+	if name := m.tag.String(); !m.closing && (name == "script" || name == "style") {
+		m.pos, m.raw, m.end = inRawText, name, 0
+		m.js.reset()
+	}
+}
+
+// rawByte moves a raw text element past c, looking for its end tag.
+func (m *markupState) rawByte(c byte) {
+	if m.raw == "script" {
+		m.js.feed(c)
+	}
+	want := "</" + m.raw
+	switch {
+	case toLower(c) == want[m.end]:
+		m.end++
+	case c == '<':
+		m.end = 1
+		return
+	default:
+		m.end = 0
+		return
+	}
+	if m.end == len(want) {
+		m.pos, m.closing = inTagName, true
+		m.tag.Reset()
+		m.tag.WriteString(m.raw)
+		m.raw, m.end = "", 0
+	}
+}
+
+func (m *markupState) startAttr(c byte) {
+	m.pos = inAttrName
+	m.attr.Reset()
+	m.attr.WriteByte(toLower(c))
+}
+
+// startValue starts an attribute value inside quote, 0 for one without.
+func (m *markupState) startValue(quote byte) {
+	m.pos, m.quote, m.url = inValue, quote, urlStart
+	m.jsAttr = strings.HasPrefix(m.attr.String(), "on")
+	m.js.reset()
+	m.ent = m.ent[:0]
+}
+
+// valueByte moves an attribute value past c: a URL attribute's by how far into
+// the URL c is, and an on* attribute's by its JavaScript.
+func (m *markupState) valueByte(c byte) {
+	if m.jsAttr {
+		m.jsAttrByte(c)
+		return
+	}
+	switch {
+	case c == '?' || c == '#':
+		m.url = urlQuery
+	case m.url == urlStart:
+		m.url = urlPath
+	default:
+	}
+}
+
+// jsAttrByte moves an on* attribute's JavaScript past c, decoding character
+// references the way the browser does before it runs the script.
+func (m *markupState) jsAttrByte(c byte) {
+	if len(m.ent) == 0 {
+		if c == '&' {
+			m.ent = append(m.ent, c)
+			return
+		}
+		m.js.feed(c)
+		return
+	}
+	m.ent = append(m.ent, c)
+	switch {
+	case c == ';':
+		m.flushEnt(true)
+	case len(m.ent) < 32 && (isLetter(c) || c >= '0' && c <= '9' || c == '#'):
+	default:
+		m.flushEnt(false)
+	}
+}
+
+// flushEnt feeds the JavaScript the character reference read so far, decoded
+// when it is complete and as it was written when it is not one.
+func (m *markupState) flushEnt(complete bool) {
+	s := string(m.ent)
+	if complete {
+		s = html.UnescapeString(s)
+	}
+	m.ent = m.ent[:0]
+	for i := 0; i < len(s); i++ {
+		m.js.feed(s[i])
+	}
+}
+
+// escape escapes v, whose text is s, for where the output has got to. It
+// fails for a spot it has no escaping for.
+func (m *markupState) escape(s string, v any) (string, error) {
+	switch m.pos {
+	case inText, inOpen, inComment:
+		return html.EscapeString(s), nil
+	case inRawText:
+		if m.raw == "style" {
+			return "", fmt.Errorf("%w: a value inside <style>", ErrUnescapable)
+		}
+		m.end = 0
+		return m.js.escape(s, v)
+	case inBeforeValue:
+		// a value that starts an attribute value makes it an unquoted one
+		m.startValue(0)
+		return m.escape(s, v)
+	case inValue:
+		attr := m.attr.String()
+		switch {
+		case attr == "style":
+			return "", fmt.Errorf("%w: a value in a style attribute", ErrUnescapable)
+		case m.jsAttr:
+			if len(m.ent) > 0 {
+				m.flushEnt(false)
+			}
+			var err error
+			if s, err = m.js.escape(s, v); err != nil {
+				return "", err
+			}
+		case urlAttrs[attr]:
+			s = escapeURL(s, m.url)
+		default:
+		}
+		return escapeAttr(s, m.quote), nil
+	default:
+		// anywhere else in a tag a value could only add attributes of its own,
+		// so it is escaped into a single harmless word
+		return escapeAttr(s, 0), nil
+	}
+}
+
+// escapeMarkup escapes s, HTML already, for where the output has got to: as is
+// in text and in a quoted attribute that is neither a URL nor a script, and
+// decoded and escaped like any other value anywhere else.
+func (m *markupState) escapeMarkup(s string) (string, error) {
+	switch m.pos {
+	case inText, inOpen, inComment:
+		return s, nil
+	case inValue:
+		if attr := m.attr.String(); m.quote != 0 && !m.jsAttr && !urlAttrs[attr] && attr != "style" {
+			return s, nil
+		}
+	default:
+	}
+	plain := html.UnescapeString(s)
+	return m.escape(plain, plain)
+}
+
+// escapeAttr escapes s for an attribute value inside quote, 0 for one without.
+func escapeAttr(s string, quote byte) string {
+	s = html.EscapeString(s)
+	if quote == 0 {
+		s = unquotedEscaper.Replace(s)
+	}
+	return s
+}
+
+// unsafeURL replaces a URL whose scheme could run code, the way html/template
+// replaces it with "#ZgotmplZ".
+const unsafeURL = "#unsafe"
+
+// escapeURL escapes s for the given part of a URL. A whole URL keeps its
+// structure but loses a scheme other than http, https and mailto. A value in
+// the path is one path segment, and a value after the "?" one query value.
+func escapeURL(s string, part urlPart) string {
+	switch part {
+	case urlPath:
+		return url.PathEscape(s)
+	case urlQuery:
+		return url.QueryEscape(s)
+	default:
+	}
+	if scheme, _, ok := strings.Cut(s, ":"); ok && !strings.ContainsAny(scheme, "/?#") {
+		switch strings.ToLower(strings.TrimSpace(scheme)) {
+		case "http", "https", "mailto":
+		default:
+			return unsafeURL
+		}
+	}
+	return normalizeURL(s)
+}
+
+// normalizeURL percent-encodes the bytes that cannot appear in a URL as they
+// are, leaving the ones that give it its structure.
+func normalizeURL(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c > ' ' && c < 0x7f && c != '"' && c != '\'' && c != '<' && c != '>' && c != '`' && c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte("0123456789ABCDEF"[c>>4])
+		b.WriteByte("0123456789ABCDEF"[c&15])
+	}
+	return b.String()
+}
+
+// urlAttrs are the attributes whose value is a URL.
+var urlAttrs = map[string]bool{
+	"action":     true,
+	"background": true,
+	"cite":       true,
+	"formaction": true,
+	"href":       true,
+	"icon":       true,
+	"longdesc":   true,
+	"manifest":   true,
+	"poster":     true,
+	"src":        true,
+	"usemap":     true,
+	"xlink:href": true,
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
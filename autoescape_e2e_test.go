@@ -0,0 +1,280 @@
+package sintax
+
+import (
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions/fs"
+)
+
+func Test_E2E_AutoEscape(t *testing.T) {
+	loader := fs.MapLoader{
+		"row.tpl":  "<td>{{ name }}</td>",
+		"part.tpl": "<b>{{ name }}</b>",
+	}
+	vars := map[string]any{
+		"name":  `<a href="x">Tom & "Jerry"</a>`,
+		"quote": "it's",
+		"n":     42,
+		"items": []any{"<i>", "&"},
+		"part":  "<b>{{ name }}</b>",
+	}
+
+	testCases := []struct {
+		name     string
+		mode     AutoEscape
+		template string
+		want     string
+	}{
+		{
+			name:     "off writes values as they are",
+			mode:     EscapeNone,
+			template: "<p>{{ name }}</p>",
+			want:     `<p><a href="x">Tom & "Jerry"</a></p>`,
+		},
+		{
+			name:     "html escapes every value",
+			mode:     EscapeHTML,
+			template: "<p>{{ name }}</p>{{ for x in items }}{{ x }}{{ endfor }}",
+			want:     "<p>&lt;a href=&#34;x&#34;&gt;Tom &amp; &#34;Jerry&#34;&lt;/a&gt;</p>&lt;i&gt;&amp;",
+		},
+		{
+			name:     "a lone value is escaped too",
+			mode:     EscapeHTML,
+			template: "{{ quote }}",
+			want:     "it&#39;s",
+		},
+		{
+			name:     "xml uses the predefined entities",
+			mode:     EscapeXML,
+			template: "<Name>{{ name }}</Name><Q>{{ quote }}</Q>",
+			want:     "<Name>&lt;a href=&quot;x&quot;&gt;Tom &amp; &quot;Jerry&quot;&lt;/a&gt;</Name><Q>it&apos;s</Q>",
+		},
+		{
+			name:     "safe opts out",
+			mode:     EscapeHTML,
+			template: "{{ name | safe }}",
+			want:     `<a href="x">Tom & "Jerry"</a>`,
+		},
+		{
+			name:     "escape_html is not escaped twice",
+			mode:     EscapeHTML,
+			template: "<p>{{ quote | escape_html }}</p>",
+			want:     "<p>it&#39;s</p>",
+		},
+		{
+			name:     "a modifier after safe gives text back to escape",
+			mode:     EscapeHTML,
+			template: "{{ '<i>' | safe | upper }}",
+			want:     "&lt;I&gt;",
+		},
+		{
+			name:     "numbers pass through",
+			mode:     EscapeHTML,
+			template: "<td>{{ n }}</td>",
+			want:     "<td>42</td>",
+		},
+		{
+			name:     "a template partial is escaped once",
+			mode:     EscapeHTML,
+			template: "<div>{{ part | template }}</div>",
+			want:     "<div><b>&lt;a href=&#34;x&#34;&gt;Tom &amp; &#34;Jerry&#34;&lt;/a&gt;</b></div>",
+		},
+		{
+			name:     "an include is escaped once",
+			mode:     EscapeHTML,
+			template: `<tr>{{ include "row.tpl" with {"name": quote} }}</tr>`,
+			want:     "<tr><td>it&#39;s</td></tr>",
+		},
+		{
+			name:     "a macro call is escaped once",
+			mode:     EscapeHTML,
+			template: "{{ macro em(x) }}<em>{{ x }}</em>{{ endmacro }}{{ em(quote) }}",
+			want:     "<em>it&#39;s</em>",
+		},
+		{
+			name:     "a capture is escaped once",
+			mode:     EscapeHTML,
+			template: "{{ capture c }}<b>{{ quote }}</b>{{ endcapture }}{{ c }}",
+			want:     "<b>it&#39;s</b>",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(builtins(), WithLoader(loader), WithAutoEscape(tt.mode))
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+func Test_E2E_AutoEscape_Context(t *testing.T) {
+	vars := map[string]any{
+		"text":   `<script>alert("x")</script>`,
+		"title":  `a "b" c`,
+		"spaced": "a onclick=go()",
+		"url":    "https://example.com/a b?q=1&r=2",
+		"js":     "javascript:alert(1)",
+		"seg":    "a/b c",
+		"q":      "x&y=z #",
+		"items":  []any{"<a>", "b c"},
+		"code":   "alert(1)",
+		"id":     "1);steal(",
+		"n":      3,
+		"quoted": "');alert(1);//",
+		"interp": "${x}",
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "text",
+			template: "<p>{{ text }}</p>",
+			want:     "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>",
+		},
+		{
+			name:     "quoted attribute",
+			template: `<p title="{{ title }}">x</p>`,
+			want:     `<p title="a &#34;b&#34; c">x</p>`,
+		},
+		{
+			name:     "unquoted attribute",
+			template: "<p class={{ spaced }}>x</p>",
+			want:     "<p class=a&#32;onclick&#61;go()>x</p>",
+		},
+		{
+			name:     "whole url keeps its structure",
+			template: `<a href="{{ url }}">x</a>`,
+			want:     `<a href="https://example.com/a%20b?q=1&amp;r=2">x</a>`,
+		},
+		{
+			name:     "a scheme that runs code is dropped",
+			template: `<a href="{{ js }}">x</a><img src='{{ js | upper }}'>`,
+			want:     `<a href="#unsafe">x</a><img src='#unsafe'>`,
+		},
+		{
+			name:     "path segment",
+			template: `<a href="/users/{{ seg }}">x</a>`,
+			want:     `<a href="/users/a%2Fb%20c">x</a>`,
+		},
+		{
+			name:     "query value",
+			template: `<a href="/search?q={{ q }}&page=2">x</a>`,
+			want:     `<a href="/search?q=x%26y%3Dz+%23&page=2">x</a>`,
+		},
+		{
+			name:     "a value between attributes stays one word",
+			template: "<p {{ spaced }}>x</p>",
+			want:     "<p a&#32;onclick&#61;go()>x</p>",
+		},
+		{
+			name:     "text after a tag closes",
+			template: `<a href="/{{ seg }}">{{ seg }}</a> {{ url }}`,
+			want:     `<a href="/a%2Fb%20c">a/b c</a> https://example.com/a b?q=1&amp;r=2`,
+		},
+		{
+			name:     "a comment does not open a tag",
+			template: "<!-- <a href=\" -->{{ title }}",
+			want:     "<!-- <a href=\" -->a &#34;b&#34; c",
+		},
+		{
+			name:     "the context follows loops",
+			template: `{{ for x in items }}<a title="{{ x }}">{{ x }}</a>{{ endfor }}`,
+			want:     `<a title="&lt;a&gt;">&lt;a&gt;</a><a title="b c">b c</a>`,
+		},
+		{
+			name:     "safe opts out",
+			template: `<a href="{{ js | safe }}">x</a>`,
+			want:     `<a href="javascript:alert(1)">x</a>`,
+		},
+		{
+			name:     "script body is a javascript string",
+			template: `<script>var t = "{{ text }}";</script>{{ text }}`,
+			want:     `<script>var t = "\u003Cscript\u003Ealert(\"x\")\u003C\/script\u003E";</script>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;`,
+		},
+		{
+			name:     "event handler is a javascript string",
+			template: `<button onclick="go('{{ title }}')">x</button>`,
+			want:     `<button onclick="go('a \&#34;b\&#34; c')">x</button>`,
+		},
+		{
+			name:     "escape_html is kept once in text and a quoted attribute",
+			template: `<p title="{{ title | escape_html }}">{{ text | escape_html }}</p>`,
+			want:     `<p title="a &#34;b&#34; c">&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>`,
+		},
+		{
+			name:     "escape_html does not skip the url check",
+			template: `<a href="{{ js | escape_html }}">x</a><a href="/u/{{ seg | escape_html }}">y</a>`,
+			want:     `<a href="#unsafe">x</a><a href="/u/a%2Fb%20c">y</a>`,
+		},
+		{
+			name:     "escape_html does not skip script escaping",
+			template: `<button onclick="go({{ id | escape_html }})">x</button>`,
+			want:     `<button onclick="go(&#34;1);steal(&#34;)">x</button>`,
+		},
+		{
+			name:     "a value in script code is a literal of its own",
+			template: `<script>var x = {{ code }}, n = {{ n }};</script>`,
+			want:     `<script>var x = "alert(1)", n = 3;</script>`,
+		},
+		{
+			name:     "a value in event handler code is a literal of its own",
+			template: `<button onclick="{{ code }}">x</button><button onclick="go({{ id }})">y</button>`,
+			want:     `<button onclick="&#34;alert(1)&#34;">x</button><button onclick="go(&#34;1);steal(&#34;)">y</button>`,
+		},
+		{
+			name:     "comments and regular expressions do not open strings",
+			template: "<script>// it's\nreturn /'/.test(s) + /* it's */ {{ code }}</script>",
+			want:     "<script>// it's\nreturn /'/.test(s) + /* it's */ \"alert(1)\"</script>",
+		},
+		{
+			name:     "a quote written as a character reference opens a string",
+			template: `<button onclick="go(&#39;{{ quoted }}&#39;)">x</button>`,
+			want:     `<button onclick="go(&#39;\&#39;);alert(1);\/\/&#39;)">x</button>`,
+		},
+		{
+			name:     "a template literal",
+			template: "<script>`${a} {{ interp }}` + `${ {{ code }} }`</script>",
+			want:     "<script>`${a} \\u0024\\u007Bx}` + `${ \"alert(1)\" }`</script>",
+		},
+		{
+			name:     "end tag in another case",
+			template: `<SCRIPT type="text/javascript">x</Script><p title={{ seg }}>`,
+			want:     `<SCRIPT type="text/javascript">x</Script><p title=a/b&#32;c>`,
+		},
+	}
+
+	s := New(builtins(), WithAutoEscape(EscapeHTMLContext))
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+func Test_E2E_AutoEscape_Context_Unescapable(t *testing.T) {
+	s := New(builtins(), WithAutoEscape(EscapeHTMLContext))
+	vars := map[string]any{"color": "red"}
+
+	for _, template := range []string{
+		"<style>p { color: {{ color }} }</style>",
+		`<p style="color: {{ color }}">x</p>`,
+		"<script>var r = /{{ color }}/;</script>",
+		"<script>// {{ color }}</script>",
+		`<script>var s = "\{{ color }}";</script>`,
+	} {
+		_, err := s.RenderString(template, vars)
+		assert.ErrorIs(t, err, ErrUnescapable)
+	}
+
+	out, err := s.RenderString("<style>p { color: {{ color | safe }} }</style>{{ color }}", vars)
+	assert.NoError(t, err)
+	assert.Equal(t, "<style>p { color: red }</style>red", out)
+}
@@ -0,0 +1,206 @@
+package sintax
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/toaweme/sintax/functions/escape"
+)
+
+// jsContext is where in JavaScript the output has got to.
+type jsContext int
+
+const (
+	jsCode jsContext = iota
+	// jsString is inside a string literal, or the text of a template literal.
+	jsString
+	// jsSlash is just past a "/" in code, which may open a comment, a regular
+	// expression, or be a division.
+	jsSlash
+	jsRegexp
+	jsLineComment
+	jsBlockComment
+)
+
+// jsState follows the JavaScript in a <script> element or an on* attribute
+// value, as far as escaping needs to: whether a value lands in code or inside
+// a string literal.
+type jsState struct {
+	ctx jsContext
+	// quote is the quote the string literal was opened with, and escaped
+	// whether the byte just read was a backslash that escapes the next.
+	quote   byte
+	escaped bool
+	// last is the last byte of code read that was not a space, and word the
+	// identifier it ends, to tell a regular expression from a division.
+	// wordDone is whether a space has ended that identifier.
+	last     byte
+	word     strings.Builder
+	wordDone bool
+	// regexpOK is whether the "/" just read may open a regular expression,
+	// class whether a regular expression is inside a [...] class, and star
+	// whether a block comment has just read a "*".
+	regexpOK bool
+	class    bool
+	star     bool
+	// braces counts the "{" open in each ${...} of a template literal the
+	// code is inside, innermost last.
+	braces []int
+}
+
+// reset starts the state over, for a new script or attribute value.
+func (j *jsState) reset() {
+	*j = jsState{braces: j.braces[:0]}
+}
+
+// feed moves the state past c.
+func (j *jsState) feed(c byte) {
+	switch j.ctx {
+	case jsCode:
+		j.code(c)
+	case jsString:
+		// last is the byte just read inside the string, 0 for an escaped one,
+		// to find the "${" that opens code in a template literal
+		switch {
+		case j.escaped:
+			j.escaped, j.last = false, 0
+		case c == '\\':
+			j.escaped, j.last = true, 0
+		case c == j.quote:
+			j.ctx, j.last = jsCode, c
+		case c == '{' && j.quote == '`' && j.last == '$':
+			j.ctx, j.braces, j.last = jsCode, append(j.braces, 0), c
+		default:
+			j.last = c
+		}
+	case jsSlash:
+		switch {
+		case c == '/':
+			j.ctx = jsLineComment
+		case c == '*':
+			j.ctx, j.star = jsBlockComment, false
+		case j.regexpOK:
+			j.ctx, j.class, j.escaped = jsRegexp, false, false
+			j.feed(c)
+		default:
+			j.ctx, j.last = jsCode, '/'
+			j.code(c)
+		}
+	case jsRegexp:
+		switch {
+		case j.escaped:
+			j.escaped = false
+		case c == '\\':
+			j.escaped = true
+		case c == '[':
+			j.class = true
+		case c == ']':
+			j.class = false
+		case c == '/' && !j.class:
+			// a regular expression ends like an operand, so a "/" after it divides
+			j.ctx, j.last = jsCode, ')'
+		default:
+		}
+	case jsLineComment:
+		if c == '\n' || c == '\r' {
+			j.ctx = jsCode
+		}
+	case jsBlockComment:
+		if c == '/' && j.star {
+			j.ctx = jsCode
+		}
+		j.star = c == '*'
+	default:
+	}
+}
+
+// code moves code past c.
+func (j *jsState) code(c byte) {
+	switch {
+	case c == '"' || c == '\'' || c == '`':
+		j.ctx, j.quote, j.escaped, j.last = jsString, c, false, 0
+	case c == '/':
+		j.ctx, j.regexpOK = jsSlash, j.beforeOperand()
+	case c == '}' && len(j.braces) > 0 && j.braces[len(j.braces)-1] == 0:
+		// the end of a ${...} goes back to the template literal around it
+		j.braces = j.braces[:len(j.braces)-1]
+		j.ctx, j.quote, j.last = jsString, '`', 0
+	case isSpace(c):
+		j.wordDone = true
+	default:
+		if len(j.braces) > 0 {
+			switch c {
+			case '{':
+				j.braces[len(j.braces)-1]++
+			case '}':
+				j.braces[len(j.braces)-1]--
+			default:
+			}
+		}
+		if isJSIdentByte(c) {
+			if !isJSIdentByte(j.last) || j.wordDone {
+				j.word.Reset()
+			}
+			j.word.WriteByte(c)
+		}
+		j.last, j.wordDone = c, false
+	}
+}
+
+// beforeOperand reports whether the code read so far expects an operand next,
+// where a "/" opens a regular expression rather than dividing.
+func (j *jsState) beforeOperand() bool {
+	if j.last == 0 || strings.IndexByte("(,=:[!&|?{};+-*%<>~^", j.last) >= 0 {
+		return true
+	}
+	return isJSIdentByte(j.last) && jsKeywords[j.word.String()]
+}
+
+// jsKeywords are the keywords an operand can follow.
+var jsKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true,
+}
+
+func isJSIdentByte(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
+
+// escape escapes v, whose text is s, for where the JavaScript has got to. In a
+// string literal that is the string's body. In code it is a literal of its own,
+// v encoded as JSON. A comment, a regular expression and the spot just past a
+// backslash have no escaping, and fail.
+func (j *jsState) escape(s string, v any) (string, error) {
+	switch {
+	case j.ctx == jsString && !j.escaped:
+		s = escape.JSString(s)
+		if j.quote == '`' {
+			// nor can it open a ${...} in a template literal
+			s = templateLiteralEscaper.Replace(s)
+		}
+		return s, nil
+	case j.ctx == jsCode, j.ctx == jsSlash && !j.regexpOK:
+		return jsLiteral(s, v), nil
+	default:
+		return "", fmt.Errorf("%w: a value in a JavaScript comment, regular expression or escape", ErrUnescapable)
+	}
+}
+
+// templateLiteralEscaper escapes what opens a ${...} in a template literal.
+var templateLiteralEscaper = strings.NewReplacer("$", "\\u0024", "{", "\\u007B")
+
+// jsQuotes turns the quotes JSON leaves alone into escapes, so a literal cannot
+// end a string it is mistakenly spliced into.
+var jsQuotes = strings.NewReplacer("'", "\\u0027", "`", "\\u0060")
+
+// jsLiteral encodes v as JSON, which also escapes "<", ">" and "&", or its
+// text s as a JSON string when v has no JSON form.
+func jsLiteral(s string, v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(s)
+	}
+	return jsQuotes.Replace(string(b))
+}
@@ -28,6 +28,24 @@ func benchRender(b *testing.B, tmpl string, vars map[string]any) {
 	}
 }
 
+// Benchmark_Render_Compiled executes a template compiled once up front, the
+// steady state of a caller that holds on to a Template, so comparing it with
+// Benchmark_Render_ConditionalLoop reads the parse cost Compile hoists out.
+func Benchmark_Render_Compiled(b *testing.B) {
+	tmpl, err := New(builtins()).Compile("{{ for x in flags }}{{ if x }}1{{ else }}0{{ endif }}{{ endfor }}")
+	if err != nil {
+		b.Fatalf("setup compile failed: %v", err)
+	}
+	vars := map[string]any{"flags": benchBools(100)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := tmpl.Execute(vars); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func Benchmark_Render_Variable(b *testing.B) {
 	benchRender(b, `{{ name }}`, map[string]any{"name": "Ada"})
 }
@@ -2,6 +2,7 @@ package sintax
 
 import (
 	"maps"
+	"slices"
 
 	"github.com/toaweme/sintax/functions/boolean"
 	"github.com/toaweme/sintax/functions/collections/access"
@@ -50,8 +51,30 @@ func builtins(safeDirs ...string) Option {
 		maps.Copy(all, g)
 	}
 
+	defs := slices.Concat(
+		casing.Defs(),
+		trim.Defs(),
+		textedit.Defs(),
+		splitjoin.Defs(),
+		access.Defs(),
+		collquery.Defs(),
+		transform.Defs(),
+		serialize.Defs(),
+		parse.Defs(),
+		format.Defs(),
+		boolean.Defs(),
+		escape.Defs(),
+		pathquery.Defs(),
+		pathedit.Defs(),
+		control.Defs(),
+		fs.Defs(),
+		render.Defs(),
+	)
+
 	return WithOptions(
 		WithModifiers(all),
+		WithContextAwareModifiers(fs.ContextAwareModifiers(safeDirs)),
 		WithContextualModifiers(render.ContextualModifiers()),
+		WithModifierDefs(defs...),
 	)
 }
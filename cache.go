@@ -0,0 +1,132 @@
+package sintax
+
+import (
+	"container/list"
+	"hash/maphash"
+	"sync"
+)
+
+// templateCache is a bounded, least-recently-used set of compiled templates
+// keyed by a hash of their source, so an engine that renders the same template
+// text over and over parses it once. Entries keep their source alongside the
+// compiled template and a lookup compares it, so two sources that happen to
+// share a hash never hand back each other's tokens; the later one just evicts
+// the earlier.
+type templateCache struct {
+	mu    sync.Mutex
+	size  int
+	seed  maphash.Seed
+	order *list.List // front is the most recently used
+	items map[uint64]*list.Element
+}
+
+// newTemplateCache creates a cache holding at most size templates.
+func newTemplateCache(size int) *templateCache {
+	return &templateCache{
+		size:  size,
+		seed:  maphash.MakeSeed(),
+		order: list.New(),
+		items: make(map[uint64]*list.Element, size),
+	}
+}
+
+// get returns the compiled template for source, if one is cached, and marks it
+// as the most recently used.
+func (c *templateCache) get(source string) (*Template, bool) {
+	key := maphash.String(c.seed, source)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	tmpl, _ := el.Value.(*Template)
+	if tmpl.source != source {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return tmpl, true
+}
+
+// put stores tmpl, evicting the least recently used template once the cache is
+// full.
+func (c *templateCache) put(tmpl *Template) {
+	key := maphash.String(c.seed, tmpl.source)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value = tmpl
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(tmpl)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		evicted, _ := oldest.Value.(*Template)
+		delete(c.items, maphash.String(c.seed, evicted.source))
+	}
+}
+
+// len reports how many templates the cache holds.
+func (c *templateCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// maxLoaded bounds how many loaded templates a loadCache holds. An include can
+// compute its name, so the names a render loads are not bounded by the
+// templates' text.
+const maxLoaded = 256
+
+// loadCache holds the parsed tokens of the templates an engine has loaded by
+// name, so an include inside a loop, or a layout every page extends, is parsed
+// once rather than on every load. The loader is still asked each time, and an
+// entry is only reused while it returns the same source for the name. A modTime
+// is not enough to go by, since an edit within the clock's granularity keeps it.
+type loadCache struct {
+	mu    sync.Mutex
+	items map[string]loadedTemplate
+}
+
+type loadedTemplate struct {
+	source string
+	tokens []Token
+}
+
+func newLoadCache() *loadCache {
+	return &loadCache{items: make(map[string]loadedTemplate)}
+}
+
+// get returns the tokens cached for name, if they were parsed from the source
+// the loader just returned.
+func (c *loadCache) get(name, source string) ([]Token, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	loaded, ok := c.items[name]
+	if !ok || loaded.source != source {
+		return nil, false
+	}
+	return loaded.tokens, true
+}
+
+// put stores the tokens parsed from name's source. Once the cache is full, a
+// name it does not hold yet takes the place of an arbitrary one.
+func (c *loadCache) put(name, source string, tokens []Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.items[name]; !ok && len(c.items) >= maxLoaded {
+		for evict := range c.items {
+			delete(c.items, evict)
+			break
+		}
+	}
+	c.items[name] = loadedTemplate{source: source, tokens: tokens}
+}
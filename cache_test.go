@@ -0,0 +1,69 @@
+package sintax
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_TemplateCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newTemplateCache(2)
+	c.put(&Template{source: "a"})
+	c.put(&Template{source: "b"})
+
+	// touching "a" makes "b" the least recently used, so "c" evicts it
+	_, ok := c.get("a")
+	assert.True(t, ok, "expected a to be cached")
+	c.put(&Template{source: "c"})
+
+	_, ok = c.get("b")
+	assert.True(t, !ok, "expected b to be evicted")
+	_, ok = c.get("a")
+	assert.True(t, ok, "expected a to survive eviction")
+	_, ok = c.get("c")
+	assert.True(t, ok, "expected c to be cached")
+	assert.Equal(t, 2, c.len())
+}
+
+func Test_TemplateCache_ReplacesSameSource(t *testing.T) {
+	c := newTemplateCache(2)
+	first := &Template{source: "a"}
+	second := &Template{source: "a"}
+	c.put(first)
+	c.put(second)
+
+	got, ok := c.get("a")
+	assert.True(t, ok, "expected a to be cached")
+	assert.True(t, got == second, "expected the later put to win")
+	assert.Equal(t, 1, c.len())
+}
+
+func Test_TemplateCache_MissOnUnknownSource(t *testing.T) {
+	c := newTemplateCache(1)
+	c.put(&Template{source: "a"})
+
+	_, ok := c.get("b")
+	assert.True(t, !ok, "expected an unknown source to miss")
+}
+
+func Test_LoadCache_ChecksSource(t *testing.T) {
+	c := newLoadCache()
+	c.put("a.tpl", "ab", nil)
+
+	_, ok := c.get("a.tpl", "ab")
+	assert.True(t, ok, "expected the same source to hit")
+	// an edit that keeps the length is still an edit
+	_, ok = c.get("a.tpl", "ba")
+	assert.True(t, !ok, "expected a changed source to miss")
+	_, ok = c.get("b.tpl", "ab")
+	assert.True(t, !ok, "expected another name to miss")
+}
+
+func Test_LoadCache_Bounded(t *testing.T) {
+	c := newLoadCache()
+	for i := range maxLoaded + 10 {
+		c.put(strconv.Itoa(i), "", nil)
+	}
+	assert.Equal(t, maxLoaded, len(c.items))
+}
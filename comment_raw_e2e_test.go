@@ -0,0 +1,102 @@
+package sintax
+
+import (
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_E2E_Comments(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "inline comment renders nothing",
+			template: "Hello {{# greet the customer #}}{{ name }}!",
+			want:     "Hello ada!",
+		},
+		{
+			name:     "comment alone on a line is trimmed with its line",
+			template: "a\n  {{# explain the next line #}}\nb {{ name }}\n",
+			want:     "a\nb ada\n",
+		},
+		{
+			name:     "comment may hold tags, even unbalanced ones",
+			template: "{{# {{ if old }}{{ legacy | upper }} #}}done",
+			want:     "done",
+		},
+		{
+			name:     "multi-line comment",
+			template: "x\n{{# one\n   two\n#}}\ny",
+			want:     "x\ny",
+		},
+	}
+
+	s := New(builtins())
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, map[string]any{"name": "ada"})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+func Test_E2E_Raw(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "inline raw keeps tags literal",
+			template: "{{ name }}: {{ raw }}{{ name | upper }}{{ endraw }}",
+			want:     "ada: {{ name | upper }}",
+		},
+		{
+			name:     "raw block lines are trimmed like block tags",
+			template: "before\n{{ raw }}\n{% for x in xs %}\n  {{ x }}\n{% endfor %}\n{{ endraw }}\nafter {{ name }}",
+			want:     "before\n{% for x in xs %}\n  {{ x }}\n{% endfor %}\nafter ada",
+		},
+		{
+			name:     "raw body may be unbalanced and malformed",
+			template: "{{ raw }}{{ if }} {{ endfor }} {{ | }}{{ endraw }}",
+			want:     "{{ if }} {{ endfor }} {{ | }}",
+		},
+		{
+			name:     "raw inside a loop repeats its body",
+			template: "{{ for x in items }}{{ x }}={{ raw }}{{ x }}{{ endraw }};{{ endfor }}",
+			want:     "1={{ x }};2={{ x }};",
+		},
+		{
+			name:     "trim markers on raw tags",
+			template: "a {{- raw -}}  {{ x }}  {{- endraw -}} b",
+			want:     "a{{ x }}b",
+		},
+		{
+			name:     "comment inside raw is kept",
+			template: "{{ raw }}{{# note #}}{{ endraw }}",
+			want:     "{{# note #}}",
+		},
+	}
+
+	s := New(builtins())
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, map[string]any{"name": "ada", "items": []any{1, 2}})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+// Comments and raw blocks follow the engine's delimiters.
+func Test_E2E_CommentsAndRaw_CustomDelimiters(t *testing.T) {
+	s := New(builtins(), WithDelimiters("<%", "%>"))
+
+	out, err := s.RenderString("<%# note #%><% raw %><% name %>{{ name }}<% endraw %> <% name %>", map[string]any{"name": "ada"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<% name %>{{ name }} ada", out)
+}
@@ -0,0 +1,103 @@
+package sintax
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_E2E_Compile_ExecuteRepeatedly(t *testing.T) {
+	s := New(builtins())
+
+	tmpl, err := s.Compile("{{ for x in items }}{{ if x | gt:1 }}{{ x }};{{ endif }}{{ endfor }}")
+	assert.NoError(t, err)
+
+	for _, tt := range []struct {
+		items []any
+		want  string
+	}{
+		{items: []any{1, 2, 3}, want: "2;3;"},
+		{items: []any{5}, want: "5;"},
+		{items: []any{}, want: ""},
+	} {
+		out, err := tmpl.Execute(map[string]any{"items": tt.items})
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, out)
+	}
+}
+
+// A compiled template keeps the passthrough Render has, so a lone expression
+// still answers with its own Go type.
+func Test_E2E_Compile_KeepsPassthrough(t *testing.T) {
+	s := New(builtins())
+
+	tmpl, err := s.Compile("{{ tags | split:',' }}")
+	assert.NoError(t, err)
+
+	out, err := tmpl.Execute(map[string]any{"tags": "a,b"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, out)
+
+	str, err := tmpl.ExecuteString(map[string]any{"tags": "a,b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "[a b]", str)
+}
+
+// One Template executed from many goroutines must render each caller's vars and
+// nothing else. Run with -race to catch shared state creeping into rendering.
+func Test_E2E_Compile_ConcurrentExecute(t *testing.T) {
+	s := New(builtins())
+
+	tmpl, err := s.Compile("{{ for k, v in row }}{{ k }}={{ v | upper }} {{ endfor }}")
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			want := fmt.Sprintf("id=N%d ", i)
+			out, err := tmpl.Execute(map[string]any{"row": map[string]any{"id": fmt.Sprintf("n%d", i)}})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if out != want {
+				errs <- fmt.Errorf("got %q, want %q", out, want)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func Test_E2E_Compile_RenderReusesCache(t *testing.T) {
+	s := New(builtins(), WithCache(2))
+
+	for range 3 {
+		out, err := s.Render("Hi {{ name }}", map[string]any{"name": "Ada"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Hi Ada", out)
+	}
+	assert.Equal(t, 1, s.cache.len())
+
+	first, err := s.Compile("Hi {{ name }}")
+	assert.NoError(t, err)
+	second, err := s.Compile("Hi {{ name }}")
+	assert.NoError(t, err)
+	assert.True(t, first == second, "a cached source must compile to the same Template")
+}
+
+func Test_E2E_Compile_NoCacheByDefault(t *testing.T) {
+	s := New(builtins())
+
+	_, err := s.Render("Hi {{ name }}", map[string]any{"name": "Ada"})
+	assert.NoError(t, err)
+	assert.True(t, s.cache == nil, "the cache must stay off unless WithCache asks for it")
+}
@@ -0,0 +1,157 @@
+package sintax
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_E2E_Conditions_Operators(t *testing.T) {
+	vars := map[string]any{
+		"a":      true,
+		"b":      false,
+		"total":  150,
+		"limit":  100.0,
+		"name":   "ada",
+		"status": "active",
+		"tags":   []any{"new", "featured"},
+		"ids":    []any{1, 2, 3},
+		"meta":   map[string]any{"vip": true},
+		"empty":  []any{},
+		// named like the yes and no a modifier argument reads as booleans
+		"yes": false,
+		"no":  true,
+	}
+
+	testCases := []struct {
+		cond string
+		want bool
+	}{
+		{cond: `a and b`, want: false},
+		{cond: `a or b`, want: true},
+		{cond: `not b`, want: true},
+		{cond: `not a or b`, want: false},
+		{cond: `not (a and b)`, want: true},
+		{cond: `a and (b or total)`, want: true},
+		{cond: `b or b or a`, want: true},
+		{cond: `total > 100`, want: true},
+		{cond: `total >= 150`, want: true},
+		{cond: `total < limit`, want: false},
+		{cond: `total <= 150.0`, want: true},
+		{cond: `total == 150.0`, want: true},
+		{cond: `total != 150`, want: false},
+		{cond: `total == '150'`, want: false},
+		{cond: `status == 'active'`, want: true},
+		{cond: `status != "active"`, want: false},
+		{cond: `name < 'bob'`, want: true},
+		{cond: `'featured' in tags`, want: true},
+		{cond: `'old' in tags`, want: false},
+		{cond: `'old' not in tags`, want: true},
+		{cond: `2.0 in ids`, want: true},
+		{cond: `'vip' in meta`, want: true},
+		{cond: `'da' in name`, want: true},
+		{cond: `tags | first == 'new'`, want: true},
+		{cond: `ids | first > 0 and name | upper == 'ADA'`, want: true},
+		{cond: `not tags | first == 'new'`, want: false},
+		{cond: `a | not`, want: false},
+		{cond: `empty | first != 'x'`, want: true},
+		{cond: `a == true`, want: true},
+		{cond: `yes`, want: false},
+		{cond: `no`, want: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.cond, func(t *testing.T) {
+			s := New(builtins())
+
+			out, err := s.Render(`{{ if `+tt.cond+` }}yes{{ else }}no{{ endif }}`, vars)
+			assert.NoError(t, err)
+			want := "no"
+			if tt.want {
+				want = "yes"
+			}
+			assert.Equal(t, want, out)
+		})
+	}
+}
+
+func Test_E2E_Conditions_InElifAndTernary(t *testing.T) {
+	s := New(builtins())
+	tpl := `{{ if total >= 1000 }}large{{ elif total >= 100 and not refund }}medium{{ else }}small{{ endif }}/{{ total > 0 ? 'credit' : 'debit' }}`
+
+	out, err := s.Render(tpl, map[string]any{"total": 250, "refund": false})
+	assert.NoError(t, err)
+	assert.Equal(t, "medium/credit", out)
+
+	out, err = s.Render(tpl, map[string]any{"total": -5, "refund": false})
+	assert.NoError(t, err)
+	assert.Equal(t, "small/debit", out)
+}
+
+// The right side of and/or is never looked at once the left side decides the
+// answer, so it may name a variable that does not exist.
+func Test_E2E_Conditions_ShortCircuit(t *testing.T) {
+	s := New(builtins())
+
+	out, err := s.Render(`{{ if user and user_name == 'ada' }}hi{{ else }}anon{{ endif }}`, map[string]any{"user": false})
+	assert.NoError(t, err)
+	assert.Equal(t, "anon", out)
+
+	out, err = s.Render(`{{ if ok or missing }}yes{{ endif }}`, map[string]any{"ok": true})
+	assert.NoError(t, err)
+	assert.Equal(t, "yes", out)
+
+	_, err = s.Render(`{{ if ok and missing }}yes{{ endif }}`, map[string]any{"ok": true})
+	assert.ErrorIs(t, err, ErrVariableNotFound)
+}
+
+// A miss a default could answer compares as nil, the way it reads as false in
+// a bare condition; a variable that does not exist at all still fails.
+func Test_E2E_Conditions_MissCompares(t *testing.T) {
+	s := New(builtins())
+
+	out, err := s.Render(`{{ if items | first == 'x' }}x{{ else }}none{{ endif }}`, map[string]any{"items": []any{}})
+	assert.NoError(t, err)
+	assert.Equal(t, "none", out)
+
+	_, err = s.Render(`{{ if missing == 'x' }}x{{ endif }}`, map[string]any{})
+	assert.ErrorIs(t, err, ErrVariableNotFound)
+
+	paths := New(builtins(), WithPathAccess())
+	out, err = paths.Render(`{{ if user.nope != 'x' }}yes{{ endif }}`, map[string]any{"user": map[string]any{"name": "ada"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "yes", out)
+}
+
+// Keywords are whole words between operands, so names that merely start with
+// one, and modifiers spelled like one, are left alone.
+func Test_E2E_Conditions_KeywordLookalikes(t *testing.T) {
+	s := New(builtins())
+
+	out, err := s.Render(`{{ if order and index and android }}ok{{ endif }}{{ if nothing | not }}!{{ endif }}`, map[string]any{
+		"order": 1, "index": 1, "android": true, "nothing": false,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ok!", out)
+}
+
+func Test_E2E_Conditions_Errors(t *testing.T) {
+	s := New(builtins())
+
+	_, err := s.Render(`{{ if a < b < c }}x{{ endif }}`, map[string]any{})
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("got %v, want a *SyntaxError for a chained comparison", err)
+	}
+	assert.Equal(t, 13, syntaxErr.Column)
+
+	_, err = s.Render(`{{ if a and }}x{{ endif }}`, map[string]any{})
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("got %v, want a *SyntaxError for a dangling and", err)
+	}
+
+	// ordering a string against a number has no answer
+	_, err = s.Render(`{{ if name > 3 }}x{{ endif }}`, map[string]any{"name": "ada"})
+	assert.Error(t, err)
+}
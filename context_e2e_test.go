@@ -0,0 +1,152 @@
+package sintax
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_E2E_RenderContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, tmpl := range []string{"Hello {{ name }}", "{{ name }}"} {
+		_, err := New(builtins()).RenderContext(ctx, tmpl, map[string]any{"name": "ada"})
+		assert.ErrorIs(t, err, ErrCanceled)
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
+
+func Test_E2E_RenderContext_DeadlinePassed(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err := New(builtins()).RenderContext(ctx, "{{ for x in items }}{{ x }}{{ endfor }}", map[string]any{
+		"items": []any{1, 2, 3},
+	})
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// A loop checks its context before every iteration, so a cancel part way through
+// stops it at the next pass rather than at the end of the data.
+func Test_E2E_RenderContext_StopsLoopMidway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	s := New(builtins(), WithContextAwareModifiers(map[string]ContextAwareModifier{
+		"tick": func(_ context.Context, value any, _ []any) (any, error) {
+			calls++
+			if calls == 3 {
+				cancel()
+			}
+			return value, nil
+		},
+	}))
+
+	var sb strings.Builder
+	err := s.RenderToContext(ctx, &sb, "{{ for x in items }}{{ for y in items }}{{ y | tick }}{{ endfor }}{{ endfor }}", map[string]any{
+		"items": []any{1, 2, 3, 4, 5},
+	})
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, "123", sb.String())
+}
+
+type ctxKey struct{}
+
+func Test_E2E_RenderContext_ReachesModifiers(t *testing.T) {
+	s := New(WithContextAwareModifiers(map[string]ContextAwareModifier{
+		"tenant": func(ctx context.Context, _ any, _ []any) (any, error) {
+			return ctx.Value(ctxKey{}), nil
+		},
+	}))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "acme")
+	out, err := s.RenderContext(ctx, "{{ name | tenant }}", map[string]any{"name": "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", out)
+
+	// outside RenderContext the modifier still gets a usable context
+	out, err = s.Render("{{ name | tenant }}", map[string]any{"name": "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, nil, out)
+}
+
+func Test_E2E_RenderContext_FileModifier(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "greeting.txt"), []byte("Hello there"), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	out, err := New(builtins(dir)).RenderContext(ctx, `{{ "greeting.txt" | file }}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello there", out)
+}
+
+// A modifier that gives up on its context fails the render as a cancellation,
+// even though the engine's own checks did not see it first, and a default
+// further down the pipeline does not paper over it.
+func Test_E2E_RenderContext_ModifierGivesUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := New(builtins(), WithContextAwareModifiers(map[string]ContextAwareModifier{
+		"slow": func(ctx context.Context, _ any, _ []any) (any, error) {
+			cancel()
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}))
+
+	_, err := s.RenderContext(ctx, "{{ x | slow | default:'fallback' }}", map[string]any{"x": 1})
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	var modErr *ModifierError
+	assert.True(t, errors.As(err, &modErr), "expected a *ModifierError, got %v", err)
+	assert.Equal(t, "slow", modErr.Modifier)
+}
+
+// A name belongs to whichever option registered it last, whatever kind of
+// modifier each one registered.
+func Test_E2E_Modifiers_LaterKindWins(t *testing.T) {
+	aware := WithContextAwareModifiers(map[string]ContextAwareModifier{
+		"pick": func(context.Context, any, []any) (any, error) { return "aware", nil },
+	})
+	global := WithModifiers(map[string]GlobalModifier{
+		"pick": func(any, []any) (any, error) { return "global", nil },
+	})
+
+	out, err := New(aware, global).Render("{{ x | pick }}", map[string]any{"x": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "global", out)
+
+	out, err = New(global, aware).Render("{{ x | pick }}", map[string]any{"x": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "aware", out)
+}
+
+// A nested template renders under the same context as its parent.
+func Test_E2E_RenderContext_ReachesNestedTemplates(t *testing.T) {
+	s := New(builtins(), WithContextAwareModifiers(map[string]ContextAwareModifier{
+		"tenant": func(ctx context.Context, _ any, _ []any) (any, error) {
+			return ctx.Value(ctxKey{}), nil
+		},
+	}))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "acme")
+	out, err := s.RenderContext(ctx, "<{{ partial | template }}>", map[string]any{"partial": "{{ partial | tenant }}"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<acme>", out)
+}
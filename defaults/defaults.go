@@ -2,11 +2,12 @@
 // Pass All() to sintax.New for the whole battery. Importing this package links
 // every built-in modifier; consumers that want a smaller binary should compose
 // only the modifier groups they use instead (each group under functions/*
-// exposes a Modifiers() constructor).
+// exposes a Modifiers() constructor, and a Defs() describing it).
 package defaults
 
 import (
 	"maps"
+	"slices"
 
 	"github.com/toaweme/sintax"
 	"github.com/toaweme/sintax/functions"
@@ -33,14 +34,20 @@ import (
 // one or more safeDirs to enable the `file` modifier against that allowlist;
 // with no dirs, file reads stay disabled.
 func New(safeDirs ...string) map[string]functions.GlobalModifier {
+	return NewWith(functions.Fields{}, safeDirs...)
+}
+
+// NewWith is New with struct members resolved through fields by the modifiers
+// that read records (key, pluck, find, filter and sum).
+func NewWith(fields functions.Fields, safeDirs ...string) map[string]functions.GlobalModifier {
 	groups := []map[string]functions.GlobalModifier{
 		casing.Modifiers(),
 		trim.Modifiers(),
 		textedit.Modifiers(),
 		splitjoin.Modifiers(),
-		access.Modifiers(),
-		collquery.Modifiers(),
-		transform.Modifiers(),
+		access.ModifiersWith(fields),
+		collquery.ModifiersWith(fields),
+		transform.ModifiersWith(fields),
 		serialize.Modifiers(),
 		parse.Modifiers(),
 		format.Modifiers(),
@@ -66,9 +73,42 @@ func Contextual() map[string]functions.ContextualModifier {
 	return render.ContextualModifiers()
 }
 
+// ContextAware returns the built-in modifiers that honor the render's context.
+// Its `file` stops reading once a RenderContext deadline passes. All registers
+// it after New, so it takes the name over from New's plain `file`.
+func ContextAware(safeDirs ...string) map[string]functions.ContextAwareModifier {
+	return fs.ContextAwareModifiers(safeDirs)
+}
+
+// Defs describes every built-in modifier, global, contextual and context-aware
+// alike, for sintax.WithModifierDefs. All registers them.
+func Defs() []functions.ModifierDef {
+	return slices.Concat(
+		casing.Defs(),
+		trim.Defs(),
+		textedit.Defs(),
+		splitjoin.Defs(),
+		access.Defs(),
+		collquery.Defs(),
+		transform.Defs(),
+		serialize.Defs(),
+		parse.Defs(),
+		format.Defs(),
+		boolean.Defs(),
+		escape.Defs(),
+		pathquery.Defs(),
+		pathedit.Defs(),
+		control.Defs(),
+		fs.Defs(),
+		render.Defs(),
+	)
+}
+
 // All bundles every built-in modifier, global and contextual alike, into a
 // single option for sintax.New. Pass one or more safeDirs to enable the `file`
-// modifier against that allowlist; with none, file reads stay disabled.
+// modifier against that allowlist; with none, file reads stay disabled. The same
+// allowlist becomes the engine's loader, so an extends or import finds templates
+// where `file` does.
 //
 // Options merge in order, so layering your own on top replaces a built-in of
 // the same name:
@@ -77,6 +117,48 @@ func Contextual() map[string]functions.ContextualModifier {
 func All(safeDirs ...string) sintax.Option {
 	return sintax.WithOptions(
 		sintax.WithModifiers(New(safeDirs...)),
+		sintax.WithContextAwareModifiers(ContextAware(safeDirs...)),
 		sintax.WithContextualModifiers(Contextual()),
+		sintax.WithModifierDefs(Defs()...),
+		dirLoader(safeDirs),
 	)
 }
+
+// AllWith is All with struct members resolved through fields, by the modifiers
+// and by the engine alike, so a DTO reads the same in `{{ user | key:'email' }}`
+// as in a loop over it or a path with sintax.WithPathAccess:
+//
+//	s := sintax.New(defaults.AllWith(functions.Fields{Tag: "json"}))
+func AllWith(fields functions.Fields, safeDirs ...string) sintax.Option {
+	return sintax.WithOptions(
+		sintax.WithModifiers(NewWith(fields, safeDirs...)),
+		sintax.WithContextAwareModifiers(ContextAware(safeDirs...)),
+		sintax.WithContextualModifiers(Contextual()),
+		sintax.WithModifierDefs(Defs()...),
+		sintax.WithFields(fields),
+		dirLoader(safeDirs),
+	)
+}
+
+// WithLoader points the `file` modifier and the engine's template references at
+// loader, in place of the safe dirs All was given, so partials and layouts can
+// come from an embed.FS, a map or a chain of stores:
+//
+//	s := sintax.New(defaults.All(), defaults.WithLoader(fs.FSLoader(templates)))
+func WithLoader(loader functions.Loader) sintax.Option {
+	return sintax.WithOptions(
+		sintax.WithModifiers(fs.ModifiersFrom(loader)),
+		sintax.WithContextAwareModifiers(fs.ContextAwareModifiersFrom(loader)),
+		sintax.WithModifierDefs(fs.Defs()...),
+		sintax.WithLoader(loader),
+	)
+}
+
+// dirLoader sets the engine's loader to the safeDirs allowlist. With none it
+// leaves the loader alone, so it does not replace one set by an earlier option.
+func dirLoader(safeDirs []string) sintax.Option {
+	if len(safeDirs) == 0 {
+		return sintax.WithOptions()
+	}
+	return sintax.WithLoader(fs.DirLoader(safeDirs...))
+}
@@ -2,11 +2,14 @@ package defaults_test
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/toaweme/sintax"
 	"github.com/toaweme/sintax/defaults"
 	"github.com/toaweme/sintax/functions"
+	"github.com/toaweme/sintax/functions/fs"
 )
 
 func Test_Defaults_New_RendersFullBattery(t *testing.T) {
@@ -85,3 +88,106 @@ func Test_Defaults_New_OmitsContextualModifiers(t *testing.T) {
 		t.Fatalf("got %v, want ErrFunctionNotFound", err)
 	}
 }
+
+type invoice struct {
+	Number string  `json:"number"`
+	Total  float64 `json:"total"`
+}
+
+// AllWith must reach both the modifiers and the engine, so the tag names work
+// in a pipeline and in a path alike.
+func Test_Defaults_AllWith_ConfiguresFields(t *testing.T) {
+	s := sintax.New(defaults.AllWith(functions.Fields{Tag: "json"}), sintax.WithPathAccess())
+
+	out, err := s.Render(`{{ invoices | pluck:'number' | join:',' }} {{ invoices[1].total }} {{ invoices | sum:'total' }}`, map[string]any{
+		"invoices": []invoice{{Number: "A1", Total: 2}, {Number: "A2", Total: 3}},
+	})
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if out != "A1,A2 3 5" {
+		t.Fatalf("got %q, want %q", out, "A1,A2 3 5")
+	}
+}
+
+// The safe dirs All is given become the engine's loader too, so a layout is
+// found where `file` reads.
+func Test_Defaults_All_LoadsTemplatesFromSafeDirs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "base.tpl"), []byte("[{{ block b }}{{ endblock }}]"), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	out, err := sintax.New(defaults.All(dir)).Render(`{{ extends "base.tpl" }}{{ block b }}{{ "base.tpl" | file | length }}{{ endblock }}`, nil)
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if out != "[29]" {
+		t.Fatalf("got %q, want %q", out, "[29]")
+	}
+}
+
+// WithLoader moves `file` and the template tags onto one loader together.
+func Test_Defaults_WithLoader(t *testing.T) {
+	loader := fs.MapLoader{
+		"base.tpl":  "[{{ block b }}{{ endblock }}]",
+		"greet.txt": "hi",
+	}
+
+	s := sintax.New(defaults.All(), defaults.WithLoader(loader))
+	out, err := s.Render(`{{ extends "base.tpl" }}{{ block b }}{{ "greet.txt" | file }}{{ endblock }}`, nil)
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if out != "[hi]" {
+		t.Fatalf("got %q, want %q", out, "[hi]")
+	}
+}
+
+// Every built-in modifier is described, its description agrees with the param
+// counts the modifier itself reports, and its examples are calls it accepts.
+func Test_Defaults_Defs(t *testing.T) {
+	s := sintax.New(defaults.All())
+	mods := defaults.New()
+
+	defs := s.Modifiers()
+	if len(defs) != len(defaults.Defs()) {
+		t.Fatalf("engine lists %d modifiers, defaults describes %d", len(defs), len(defaults.Defs()))
+	}
+	for _, def := range defs {
+		if def.Group == "" || def.Summary == "" || def.Returns == "" || len(def.Examples) == 0 {
+			t.Errorf("modifier %q is registered without a full definition: %+v", def.Name, def)
+			continue
+		}
+		if as, ok := functions.ArityOf(mods[string(def.Name)]); ok {
+			for n := range 5 {
+				if as.Accepts(n) != def.Arity().Accepts(n) {
+					t.Errorf("modifier %q takes %s, its definition says %s", def.Name, as, def.Arity())
+					break
+				}
+			}
+		}
+		for _, example := range def.Examples {
+			if err := s.Validate(example); err != nil {
+				t.Errorf("example %q of %q: %v", example, def.Name, err)
+			}
+		}
+	}
+
+	// only default answers a miss
+	for _, def := range defs {
+		if def.AnswersMiss != (def.Name == "default") {
+			t.Errorf("modifier %q: AnswersMiss = %v", def.Name, def.AnswersMiss)
+		}
+	}
+}
+
+// WithLoader replaces `file`, and its definition with it.
+func Test_Defaults_WithLoader_KeepsDefs(t *testing.T) {
+	s := sintax.New(defaults.All(), defaults.WithLoader(fs.MapLoader{}))
+	for _, def := range s.Modifiers() {
+		if def.Name == fs.ModifierNameFile && def.Summary == "" {
+			t.Fatal("file lost its definition")
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package sintax
+
+import (
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_E2E_Delimiters(t *testing.T) {
+	vars := map[string]any{
+		"name":    "web",
+		"ports":   []any{80, 443},
+		"tls":     true,
+		"partial": "port [[ p ]]",
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "literal standard tags pass through",
+			template: "name: [[ name ]]\nimage: ${{ secrets.IMAGE }}",
+			want:     "name: web\nimage: ${{ secrets.IMAGE }}",
+		},
+		{
+			name:     "pipelines and conditions",
+			template: "[[ name | upper ]] [[ tls ? 'https' : 'http' ]] [[ if tls and name == 'web' ]]ok[[ endif ]]",
+			want:     "WEB https ok",
+		},
+		{
+			name:     "block lines auto-trim",
+			template: "ports:\n[[ for p in ports ]]\n  - [[ p ]]\n[[ endfor ]]\ndone",
+			want:     "ports:\n  - 80\n  - 443\ndone",
+		},
+		{
+			name:     "trim markers",
+			template: "a   [[- name -]]   b",
+			want:     "awebb",
+		},
+		{
+			name:     "nested template uses the same delimiters",
+			template: "[[ for p in ports ]][[ partial | template ]];[[ endfor ]]",
+			want:     "port 80;port 443;",
+		},
+	}
+
+	s := New(builtins(), WithDelimiters("[[", "]]"))
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+// A rejected pair fails every render rather than quietly falling back to the
+// standard delimiters and rendering the wrong tags.
+func Test_E2E_Delimiters_Rejected(t *testing.T) {
+	s := New(builtins(), WithDelimiters("%%", "%%"))
+
+	_, err := s.Render("%% name %%", map[string]any{"name": "x"})
+	assert.ErrorIs(t, err, ErrInvalidDelimiters)
+
+	_, err = s.Compile("{{ name }}")
+	assert.ErrorIs(t, err, ErrInvalidDelimiters)
+
+	_, err = NewTokenRenderer(WithDelimiters("", "]]")).Render(nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidDelimiters)
+}
@@ -189,3 +189,25 @@ func Test_Render_ModifierError_RaisedDespiteDefault(t *testing.T) {
 		t.Errorf("got modifier %q, want %q", modErr.Modifier, "upper")
 	}
 }
+
+// A template that cannot parse fails before rendering with a *SyntaxError that
+// survives the engine's wrapping, so a caller reaches the position with errors.As.
+func Test_Render_SyntaxError_ReachesTheCaller(t *testing.T) {
+	s := New(builtins())
+
+	_, err := s.Render("Total:\n{{ for tx in txs }}{{ tx | key:'amount' }}\n", map[string]any{})
+	if !errors.Is(err, ErrInvalidSyntax) {
+		t.Fatalf("got %v, want ErrInvalidSyntax", err)
+	}
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("got %v, want a *SyntaxError in the chain", err)
+	}
+	if syntaxErr.Line != 2 || syntaxErr.Column != 1 {
+		t.Errorf("got %d:%d, want the unclosed for at 2:1", syntaxErr.Line, syntaxErr.Column)
+	}
+	if syntaxErr.Snippet != "{{ for tx in txs }}{{ tx | key:'amount' }}" {
+		t.Errorf("got snippet %q, want the line holding the for", syntaxErr.Snippet)
+	}
+}
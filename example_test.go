@@ -77,6 +77,29 @@ func ExampleNew_returnsValue() {
 	// Output: 3 tags, first is "go"
 }
 
+// ExampleTemplate_Execute compiles a template once and executes it against
+// several variable sets, parsing the template text only the first time.
+func ExampleTemplate_Execute() {
+	engine := sintax.New(defaults.All())
+
+	tmpl, err := engine.Compile(`{{ name | title }} owes {{ amount | decimal:2 }}`)
+	if err != nil {
+		panic(err)
+	}
+	for _, vars := range []map[string]any{
+		{"name": "ada-lovelace", "amount": 12.5},
+		{"name": "alan-turing", "amount": 3},
+	} {
+		out, err := tmpl.Execute(vars)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(out)
+	}
+	// Output: Ada Lovelace owes 12.50
+	// Alan Turing owes 3.00
+}
+
 // ExampleWithModifiers registers a custom modifier alongside the defaults, so a
 // template can call it by name like any built-in. Options merge in order, so a
 // modifier registered here under a built-in's name would replace it.
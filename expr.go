@@ -0,0 +1,709 @@
+package sintax
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/toaweme/sintax/functions"
+)
+
+// exprNode is one node of a parsed expression, the language if and elif
+// conditions and inline conditionals `{{ cond ? a : b }}` are written in.
+// Leaves are literals and variable or modifier pipeline terms, joined by
+// comparisons and the boolean operators. The tree is built once at parse time
+// and cached on the token, so rendering only walks it.
+type exprNode interface {
+	eval(r *TokenRenderer, vars map[string]any) (any, error)
+}
+
+// literalNode is a value written directly in the expression: a quoted string, a
+// number, a boolean, or an empty collection literal.
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(*TokenRenderer, map[string]any) (any, error) {
+	// hand out a fresh empty collection every time, so a caller that appends to
+	// the result of `{{ x ? items : [] }}` cannot change what the next render sees.
+	switch n.value.(type) {
+	case []any:
+		return []any{}, nil
+	case map[string]any:
+		return map[string]any{}, nil
+	default:
+	}
+	return n.value, nil
+}
+
+// termNode is a variable or modifier pipeline, rendered through renderVariable
+// exactly as the same text would render in its own tag.
+type termNode struct {
+	token Token
+}
+
+func (n *termNode) eval(r *TokenRenderer, vars map[string]any) (any, error) {
+	return r.renderVariable(n.token, vars)
+}
+
+// mapNode is a map literal, `{"tx": tx, "n": 1}`. Each value is an expression
+// of its own, evaluated strictly the way a macro argument is, and the map is
+// built afresh on every evaluation.
+type mapNode struct {
+	entries []mapEntry
+}
+
+// mapEntry is one key of a map literal and the expression of its value.
+type mapEntry struct {
+	key   string
+	value exprNode
+}
+
+func (n *mapNode) eval(r *TokenRenderer, vars map[string]any) (any, error) {
+	m := make(map[string]any, len(n.entries))
+	for _, e := range n.entries {
+		v, err := e.value.eval(r, vars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate key %s of map: %w", e.key, err)
+		}
+		m[e.key] = v
+	}
+	return m, nil
+}
+
+// ternaryNode is an inline conditional, `cond ? then : els`. The condition is a
+// question about the data and answers a miss on its own, the way an if does, so
+// a missing value reads as false. The chosen branch is a value and is rendered
+// strictly, so a miss there fails the render the way it would in its own tag.
+// Only the chosen branch is evaluated.
+type ternaryNode struct {
+	cond, then, els exprNode
+}
+
+func (n *ternaryNode) eval(r *TokenRenderer, vars map[string]any) (any, error) {
+	ok, err := r.evalTruth(n.cond, vars)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return n.then.eval(r, vars)
+	}
+	return n.els.eval(r, vars)
+}
+
+// notNode is `not x`, the negated truthiness of its operand.
+type notNode struct {
+	x exprNode
+}
+
+func (n *notNode) eval(r *TokenRenderer, vars map[string]any) (any, error) {
+	ok, err := r.evalTruth(n.x, vars)
+	if err != nil {
+		return nil, err
+	}
+	return !ok, nil
+}
+
+// logicNode is `left and right` or `left or right`. Both operands are read for
+// their truthiness and the result is a bool. Evaluation short-circuits, so the
+// right operand is never looked at once the left one decides the answer, which
+// lets `user and user | key:'name'` guard a lookup that would otherwise fail.
+type logicNode struct {
+	and         bool
+	left, right exprNode
+}
+
+func (n *logicNode) eval(r *TokenRenderer, vars map[string]any) (any, error) {
+	ok, err := r.evalTruth(n.left, vars)
+	if err != nil {
+		return nil, err
+	}
+	if ok != n.and {
+		// true or ..., false and ...
+		return ok, nil
+	}
+	return r.evalTruth(n.right, vars)
+}
+
+// compareNode is a comparison between two operands. Both are read like a
+// condition: a miss a default could answer, such as `first` of an empty list or
+// a missing segment under path access, compares as nil, while a variable that
+// does not exist at all fails the render, as it does in `{{ if x }}`. Equality
+// follows functions.ValuesEqual, ordering functions.CompareValues, and `in`
+// functions.Contains.
+type compareNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *compareNode) eval(r *TokenRenderer, vars map[string]any) (any, error) {
+	a, err := r.evalLenient(n.left, vars)
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.evalLenient(n.right, vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return functions.ValuesEqual(a, b), nil
+	case "!=":
+		return !functions.ValuesEqual(a, b), nil
+	case "in", "not in":
+		found, err := functions.Contains(b, a)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %q: %w", n.op, err)
+		}
+		return found == (n.op == "in"), nil
+	default:
+	}
+	order, err := functions.CompareValues(a, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %q: %w", n.op, err)
+	}
+	switch n.op {
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	case ">":
+		return order > 0, nil
+	default:
+		return order >= 0, nil
+	}
+}
+
+// evalLenient evaluates node in a position that answers a miss on its own, a
+// condition or a for iterable, where a miss evaluates to nil rather than failing
+// the render. See evalExpr.
+func (r *TokenRenderer) evalLenient(node exprNode, vars map[string]any) (any, error) {
+	value, err := node.eval(r, vars)
+	if err != nil {
+		if errors.Is(err, functions.ErrAllowsDefaultFunc) {
+			return nil, nil //nolint:nilnil // deliberate, absent data is nil here, which reads as false and iterates nothing
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// evalTruth evaluates node as a condition and returns its truthiness via
+// functions.ConditionIsTrue.
+func (r *TokenRenderer) evalTruth(node exprNode, vars map[string]any) (bool, error) {
+	value, err := r.evalLenient(node, vars)
+	if err != nil {
+		return false, err
+	}
+	return functions.ConditionIsTrue(value), nil
+}
+
+// exprItemKind identifies a lexical item of an expression.
+type exprItemKind int
+
+const (
+	itemOperand exprItemKind = iota
+	itemQuestion
+	itemColon
+	itemLParen
+	itemRParen
+	itemOperator
+	itemKeyword
+)
+
+// exprOperators are the comparison operators, longest first so `<=` is not read
+// as `<` followed by `=`.
+var exprOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// exprKeywords are the word operators. They are only keywords as whole words
+// standing between operands, so a variable such as `index` or `order` is left
+// alone, and so is a modifier or argument spelled like one, as in
+// `active | not`.
+var exprKeywords = []string{"and", "or", "not", "in"}
+
+// keywordAt returns the word operator starting at s[i], or "" when there is
+// none.
+func keywordAt(s string, i int) string {
+	if i > 0 && !isSpaceByte(s[i-1]) && s[i-1] != '(' && s[i-1] != ')' {
+		return ""
+	}
+	prev := strings.TrimRight(s[:i], " \t\r\n")
+	if prev != "" && strings.ContainsRune("|:,", rune(prev[len(prev)-1])) {
+		return ""
+	}
+	for _, kw := range exprKeywords {
+		end := i + len(kw)
+		if !strings.HasPrefix(s[i:], kw) {
+			continue
+		}
+		if end == len(s) || isSpaceByte(s[end]) || s[end] == '(' {
+			return kw
+		}
+	}
+	return ""
+}
+
+// operatorAt returns the comparison operator starting at s[i], or "" when there
+// is none.
+func operatorAt(s string, i int) string {
+	for _, op := range exprOperators {
+		if strings.HasPrefix(s[i:], op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// exprItem is one lexical item of an expression. An operand is kept as the raw
+// text of a literal, variable, or modifier pipeline, so a pipeline inside an
+// expression is parsed by the same code as one in its own tag.
+type exprItem struct {
+	kind exprItemKind
+	text string
+	at   int
+}
+
+// lexExpr cuts an expression into operands and the operators and punctuation
+// between them. Quoted strings are skipped whole, and brackets and the
+// parentheses of a call nest inside their operand. A `:` only separates ternary branches when a `?` is
+// waiting for it and whitespace precedes it, which is what tells it apart from
+// the `:` of a modifier call such as `key:'name'`.
+func lexExpr(s string) ([]exprItem, *parseError) {
+	var items []exprItem
+	operandStart := -1
+	depth, depthAt := 0, 0
+	quote, quoteAt := byte(0), 0
+	pendingQuestions := 0
+
+	flush := func(end int) {
+		if operandStart < 0 {
+			return
+		}
+		items = append(items, exprItem{kind: itemOperand, text: strings.TrimSpace(s[operandStart:end]), at: operandStart})
+		operandStart = -1
+	}
+	startOperand := func(i int) {
+		if operandStart < 0 {
+			operandStart = i
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			startOperand(i)
+			quote, quoteAt = c, i
+		case depth > 0:
+			switch c {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				depth--
+			default:
+			}
+		case c == '(':
+			if operandStart >= 0 && isIdentByte(s[i-1]) {
+				// a call such as range(1, 5): its parentheses belong to the operand
+				depth, depthAt = 1, i
+				continue
+			}
+			flush(i)
+			items = append(items, exprItem{kind: itemLParen, text: "(", at: i})
+		case c == '[' || c == '{':
+			startOperand(i)
+			depth, depthAt = 1, i
+		case c == ')':
+			flush(i)
+			items = append(items, exprItem{kind: itemRParen, text: ")", at: i})
+		case c == ']' || c == '}':
+			return nil, errAt(i, "unexpected %q", string(c))
+		case c == '?':
+			flush(i)
+			items = append(items, exprItem{kind: itemQuestion, text: "?", at: i})
+			pendingQuestions++
+		case c == ':' && pendingQuestions > 0 && i > 0 && isSpaceByte(s[i-1]):
+			flush(i)
+			items = append(items, exprItem{kind: itemColon, text: ":", at: i})
+			pendingQuestions--
+		case isSpaceByte(c):
+		default:
+			if op := operatorAt(s, i); op != "" {
+				flush(i)
+				items = append(items, exprItem{kind: itemOperator, text: op, at: i})
+				i += len(op) - 1
+				continue
+			}
+			if kw := keywordAt(s, i); kw != "" {
+				flush(i)
+				items = append(items, exprItem{kind: itemKeyword, text: kw, at: i})
+				i += len(kw) - 1
+				continue
+			}
+			startOperand(i)
+		}
+	}
+	if quote != 0 {
+		return nil, errAt(quoteAt, "unterminated quoted string")
+	}
+	if depth > 0 {
+		return nil, errAt(depthAt, "unclosed %q", string(s[depthAt]))
+	}
+	flush(len(s))
+	return items, nil
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// exprParser is a recursive descent parser over the items lexExpr produced.
+type exprParser struct {
+	sp    *StringParser
+	items []exprItem
+	pos   int
+	end   int
+}
+
+// parseExpr parses s into an expression tree. Errors carry offsets relative to
+// s, for Parse to turn into a positioned *SyntaxError.
+func (p *StringParser) parseExpr(s string) (exprNode, *parseError) {
+	items, err := lexExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	ep := &exprParser{sp: p, items: items, end: len(s)}
+	node, err := ep.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if item, ok := ep.peek(); ok {
+		return nil, errAt(item.at, "unexpected %q", item.text)
+	}
+	return node, nil
+}
+
+func (ep *exprParser) peek() (exprItem, bool) {
+	if ep.pos >= len(ep.items) {
+		return exprItem{}, false
+	}
+	return ep.items[ep.pos], true
+}
+
+// accept consumes the next item if it is of kind.
+func (ep *exprParser) accept(kind exprItemKind) bool {
+	if item, ok := ep.peek(); ok && item.kind == kind {
+		ep.pos++
+		return true
+	}
+	return false
+}
+
+// parseTernary parses `cond ? then : els`, the loosest binding form. Both
+// branches are themselves full expressions, so conditionals nest, and an
+// unparenthesized chain such as `a ? x : b ? y : z` groups to the right.
+func (ep *exprParser) parseTernary() (exprNode, *parseError) {
+	cond, err := ep.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !ep.accept(itemQuestion) {
+		return cond, nil
+	}
+	then, err := ep.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if !ep.accept(itemColon) {
+		return nil, ep.errHere("inline conditional is missing its \" : \" branch")
+	}
+	els, err := ep.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryNode{cond: cond, then: then, els: els}, nil
+}
+
+// acceptKeyword consumes the next item if it is the word operator kw.
+func (ep *exprParser) acceptKeyword(kw string) bool {
+	if item, ok := ep.peek(); ok && item.kind == itemKeyword && item.text == kw {
+		ep.pos++
+		return true
+	}
+	return false
+}
+
+// parseOr parses `a or b or ...`. or binds looser than and.
+func (ep *exprParser) parseOr() (exprNode, *parseError) {
+	left, err := ep.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for ep.acceptKeyword("or") {
+		right, err := ep.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd parses `a and b and ...`.
+func (ep *exprParser) parseAnd() (exprNode, *parseError) {
+	left, err := ep.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for ep.acceptKeyword("and") {
+		right, err := ep.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseNot parses `not x`, which binds looser than a comparison, so
+// `not a == b` negates the comparison.
+func (ep *exprParser) parseNot() (exprNode, *parseError) {
+	if ep.acceptKeyword("not") {
+		x, err := ep.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return ep.parseComparison()
+}
+
+// parseComparison parses a single comparison, or a lone operand. Comparisons
+// do not chain, since `a < b < c` reads as a range test it would not perform.
+func (ep *exprParser) parseComparison() (exprNode, *parseError) {
+	left, err := ep.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := ep.comparisonOp()
+	if !ok {
+		return left, nil
+	}
+	right, err := ep.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ep.peekComparisonOp(); ok {
+		return nil, ep.errHere("comparisons cannot be chained, join them with and")
+	}
+	return &compareNode{op: op, left: left, right: right}, nil
+}
+
+// comparisonOp consumes the comparison operator at the current item, if any.
+func (ep *exprParser) comparisonOp() (string, bool) {
+	op, ok := ep.peekComparisonOp()
+	if ok {
+		ep.pos += len(strings.Fields(op))
+	}
+	return op, ok
+}
+
+// peekComparisonOp reports the comparison operator at the current item, `not
+// in` spanning two items.
+func (ep *exprParser) peekComparisonOp() (string, bool) {
+	item, ok := ep.peek()
+	if !ok {
+		return "", false
+	}
+	switch {
+	case item.kind == itemOperator:
+		return item.text, true
+	case item.kind == itemKeyword && item.text == "in":
+		return "in", true
+	case item.kind == itemKeyword && item.text == "not":
+		if ep.pos+1 < len(ep.items) && ep.items[ep.pos+1].kind == itemKeyword && ep.items[ep.pos+1].text == "in" {
+			return "not in", true
+		}
+	default:
+	}
+	return "", false
+}
+
+// parsePrimary parses a parenthesized expression or a single operand.
+func (ep *exprParser) parsePrimary() (exprNode, *parseError) {
+	item, ok := ep.peek()
+	if !ok {
+		return nil, errAt(ep.end, "expression ends where a value is expected")
+	}
+	switch item.kind {
+	case itemLParen:
+		ep.pos++
+		node, err := ep.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		if !ep.accept(itemRParen) {
+			return nil, ep.errHere("missing %q", ")")
+		}
+		return node, nil
+	case itemOperand:
+		ep.pos++
+		return ep.sp.parseTerm(item.text, item.at)
+	default:
+	}
+	return nil, errAt(item.at, "unexpected %q where a value is expected", item.text)
+}
+
+// errHere reports an error at the next item, or at the end of the expression
+// when there is none.
+func (ep *exprParser) errHere(format string, args ...any) *parseError {
+	if item, ok := ep.peek(); ok {
+		return errAt(item.at, format, args...)
+	}
+	return errAt(ep.end, format, args...)
+}
+
+// parseTerm parses one operand: a literal, a map, a macro call, or a variable or
+// modifier pipeline built into the same token its own tag would produce.
+func (p *StringParser) parseTerm(text string, at int) (exprNode, *parseError) {
+	if value, ok := parseLiteral(text); ok {
+		return &literalNode{value: value}, nil
+	}
+	if strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}") {
+		return p.parseMap(text, at)
+	}
+	switch p.detectTokenType(text) {
+	case VariableToken:
+		return &termNode{token: p.createToken(VariableToken, text)}, nil
+	case FilteredVariableToken:
+		if err := checkPipeline(text); err != nil {
+			return nil, err.shift(at)
+		}
+		return &termNode{token: p.createToken(FilteredVariableToken, text)}, nil
+	case CallToken:
+		return p.parseCall(text, at)
+	default:
+	}
+	return nil, errAt(at, "%q is not a value, variable, or modifier pipeline", text)
+}
+
+// parseMap parses a map literal. Keys are quoted strings or bare names, each
+// followed by a colon and the expression of its value. at is where text starts
+// in the expression, for errors to point inside the map.
+func (p *StringParser) parseMap(text string, at int) (exprNode, *parseError) {
+	innerAt := at + 1
+	parts, err := splitArgs(text[1 : len(text)-1])
+	if err != nil {
+		return nil, err.shift(innerAt)
+	}
+	node := &mapNode{}
+	seen := map[string]bool{}
+	for _, part := range parts {
+		partAt := innerAt + part.at
+		key, valueAt, ok := cutMapKey(part.text)
+		if !ok {
+			return nil, errAt(partAt, "malformed map entry %q, expected \"key: value\"", part.text)
+		}
+		if seen[key] {
+			return nil, errAt(partAt, "key %s is given twice", key)
+		}
+		seen[key] = true
+		raw := part.text[valueAt:]
+		valueText := strings.TrimSpace(raw)
+		if valueText == "" {
+			return nil, errAt(partAt+len(part.text), "key %s is missing a value", key)
+		}
+		value, err := p.parseExpr(valueText)
+		if err != nil {
+			return nil, err.shift(partAt + valueAt + len(raw) - len(strings.TrimLeft(raw, " \t\r\n")))
+		}
+		node.entries = append(node.entries, mapEntry{key: key, value: value})
+	}
+	return node, nil
+}
+
+// cutMapKey splits a map entry at the colon after its key, returning the key
+// and the offset its value starts at.
+func cutMapKey(s string) (key string, valueAt int, ok bool) {
+	end := 0
+	if s[0] == '"' || s[0] == '\'' {
+		if end = closingQuote(s); end < 0 {
+			return "", 0, false
+		}
+		key, end = unquote(s[:end+1], s[:1]), end+1
+	} else {
+		for end < len(s) && isIdentByte(s[end]) {
+			end++
+		}
+		if key = s[:end]; !identRe.MatchString(key) {
+			return "", 0, false
+		}
+	}
+	rest := strings.TrimLeft(s[end:], " \t\r\n")
+	if !strings.HasPrefix(rest, ":") {
+		return "", 0, false
+	}
+	return key, len(s) - len(rest) + 1, true
+}
+
+// parseLiteral reads text as a literal value, reporting false when it is not
+// one. The forms are those a modifier argument accepts, a quoted string, a
+// number, a boolean and the empty collection literals, except that a boolean is
+// only true or false. A modifier argument also reads yes and no as booleans, but
+// in an expression those are variable names, as they were in a condition before
+// conditions had a grammar.
+func parseLiteral(text string) (any, bool) {
+	if isQuotedWith(text, `"`) || isQuotedWith(text, `'`) {
+		// a quoted head followed by a pipeline is a term, not a literal
+		if parts, err := splitPipeline(text); err != nil || len(parts) > 1 {
+			return nil, false
+		}
+		return unquote(text, text[:1]), true
+	}
+	switch text {
+	case emptyArrayLiteral:
+		return []any{}, true
+	case emptyObjectLiteral:
+		return map[string]any{}, true
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	default:
+	}
+	// only text shaped like a number is tried as one, since ParseFloat would also
+	// read a variable named inf or nan as a float
+	if c := text[0]; c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.' {
+		if n, ok := isInt(text); ok {
+			return n, true
+		}
+		if f, ok := isFloat(text); ok {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// exprOf returns the expression an IfToken, ElifToken, SetToken, CallToken, or
+// ShorthandIfToken carries, preferring the tree cached at parse time and parsing the raw text for
+// tokens that lack it.
+func (r *TokenRenderer) exprOf(token Token) (exprNode, error) {
+	if bt, ok := token.(BaseToken); ok && bt.parsedExpr != nil {
+		return bt.parsedExpr, nil
+	}
+	node, err := r.parser.parseExpr(strings.TrimSpace(token.Raw()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %s", token.Raw(), err.msg)
+	}
+	return node, nil
+}
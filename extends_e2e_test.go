@@ -0,0 +1,165 @@
+package sintax
+
+import (
+	iofs "io/fs"
+	"strings"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions/fs"
+)
+
+func Test_E2E_Extends(t *testing.T) {
+	loader := fs.MapLoader{
+		"base.tpl": "<h1>{{ title | default:'Document' }}</h1>\n" +
+			"{{ block body }}\n" +
+			"default body\n" +
+			"{{ endblock }}\n" +
+			"<footer>{{ block footer }}(c) {{ company }}{{ endblock }}</footer>",
+		"invoice.tpl": "{{ extends \"base.tpl\" }}\n" +
+			"{{ set title = 'Invoice' }}\n" +
+			"{{ block body }}\n" +
+			"Invoice {{ number }}\n" +
+			"{{ endblock }}",
+		"reminder.tpl": "{{ extends \"invoice.tpl\" }}\n" +
+			"{{ block body }}\n" +
+			"REMINDER\n" +
+			"{{ super() }}" +
+			"{{ endblock }}\n" +
+			"{{ block footer }}{{ super() }}, legal{{ endblock }}",
+		"rows.tpl": "{{ for x in items }}{{ block row }}[{{ x }}]{{ endblock }}{{ endfor }}",
+		"rows_child.tpl": "{{ extends \"rows.tpl\" }}" +
+			"{{ block row }}{{ set y = x | upper }}<{{ y }}{{ y_seen | default:'' }}>{{ set y_seen = '!' }}{{ endblock }}",
+		"macros_base.tpl": "{{ macro em(x) }}*{{ x }}*{{ endmacro }}{{ block body }}{{ em('base') }}{{ endblock }}",
+		"macros_child.tpl": "{{ extends \"macros_base.tpl\" }}" +
+			"{{ macro em(x) }}_{{ x }}_{{ endmacro }}" +
+			"{{ block body }}{{ em('child') }} {{ super() }}{{ endblock }}",
+		"set_base.tpl":      "{{ set title = 'Base' }}<title>{{ title }}</title>",
+		"fallback_base.tpl": "{{ set title = title | default:'Base' }}<title>{{ title }}</title>",
+		"set_child.tpl":     "{{ extends \"set_base.tpl\" }}{{ set title = 'Child' }}",
+		"nested_base.tpl":   "{{ block page }}[{{ block inner }}base inner{{ endblock }}]{{ endblock }}",
+		"nested_child.tpl":  "{{ extends \"nested_base.tpl\" }}{{ block inner }}child inner{{ endblock }}",
+	}
+	vars := map[string]any{"company": "ACME", "number": 42, "items": []any{"a", "b"}}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "a template without extends renders its blocks in place",
+			template: "a{{ block b }}-{{ company }}-{{ endblock }}z",
+			want:     "a-ACME-z",
+		},
+		{
+			name:     "a child overrides a block and sets a value for the layout",
+			template: "{{ extends \"invoice.tpl\" }}",
+			want:     "<h1>Invoice</h1>\nInvoice 42\n<footer>(c) ACME</footer>",
+		},
+		{
+			name:     "super renders the overridden block up the chain",
+			template: "{{ extends \"reminder.tpl\" }}",
+			want:     "<h1>Invoice</h1>\nREMINDER\nInvoice 42\n<footer>(c) ACME, legal</footer>",
+		},
+		{
+			name:     "an override renders in the scope its block sits in",
+			template: "{{ extends \"rows_child.tpl\" }}",
+			want:     "<A><B>",
+		},
+		{
+			name:     "each block calls the macros of its own template",
+			template: "{{ extends \"macros_child.tpl\" }}",
+			want:     "_child_ *base*",
+		},
+		{
+			name:     "a set higher up the chain has the last word",
+			template: "{{ extends \"set_child.tpl\" }}{{ set title = 'Grandchild' }}",
+			want:     "<title>Base</title>",
+		},
+		{
+			name:     "a layout that sets a fallback keeps the child's value",
+			template: "{{ extends \"fallback_base.tpl\" }}{{ set title = 'Child' }}",
+			want:     "<title>Child</title>",
+		},
+		{
+			name:     "a parent's set overrides its child's",
+			template: "{{ extends \"invoice.tpl\" }}{{ set title = 'Reminder' }}",
+			want:     "<h1>Invoice</h1>\nInvoice 42\n<footer>(c) ACME</footer>",
+		},
+		{
+			name:     "a nested block is overridden on its own",
+			template: "{{ extends \"nested_child.tpl\" }}",
+			want:     "[child inner]",
+		},
+	}
+
+	s := New(builtins(), WithLoader(loader))
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+
+			var sb strings.Builder
+			assert.NoError(t, s.RenderTo(&sb, tt.template, vars))
+			assert.Equal(t, tt.want, sb.String())
+		})
+	}
+}
+
+func Test_E2E_Extends_Errors(t *testing.T) {
+	loader := fs.MapLoader{
+		"a.tpl":      "{{ extends \"b.tpl\" }}",
+		"b.tpl":      "{{ extends \"a.tpl\" }}",
+		"self.tpl":   "{{ extends \"self.tpl\" }}{{ block x }}{{ endblock }}",
+		"base.tpl":   "{{ block body }}{{ super() }}{{ endblock }}",
+		"broken.tpl": "{{ block body }}",
+	}
+	s := New(builtins(), WithLoader(loader))
+
+	_, err := s.Render(`{{ extends "a.tpl" }}`, nil)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+
+	_, err = s.Render(`{{ extends "self.tpl" }}`, nil)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+
+	_, err = s.Render(`{{ extends "missing.tpl" }}`, nil)
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+	assert.True(t, strings.Contains(err.Error(), `failed to extend "missing.tpl"`), "unexpected error %v", err)
+
+	_, err = s.Render(`{{ extends "broken.tpl" }}`, nil)
+	assert.ErrorIs(t, err, ErrInvalidSyntax)
+
+	_, err = s.Render(`{{ extends "base.tpl" }}{{ block body }}x{{ endblock }}`, nil)
+	assert.NoError(t, err)
+
+	_, err = s.Render(`{{ extends "base.tpl" }}`, nil)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "block body has no parent block for super() to render"), "unexpected error %v", err)
+
+	_, err = s.Render(`{{ super() }}`, nil)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "super() called outside a block"), "unexpected error %v", err)
+
+	_, err = New(builtins()).Render(`{{ extends "base.tpl" }}`, nil)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "no loader configured"), "unexpected error %v", err)
+}
+
+// The cycle guard honors WithMaxDepth, so a legitimately deep chain can be
+// allowed for.
+func Test_E2E_Extends_DeepChain(t *testing.T) {
+	loader := fs.MapLoader{"l0.tpl": "{{ block b }}0{{ endblock }}"}
+	for _, n := range []string{"1", "2", "3"} {
+		prev := map[string]string{"1": "l0", "2": "l1", "3": "l2"}[n]
+		loader["l"+n+".tpl"] = "{{ extends \"" + prev + ".tpl\" }}{{ block b }}{{ super() }}" + n + "{{ endblock }}"
+	}
+
+	out, err := New(builtins(), WithLoader(loader)).Render(`{{ extends "l3.tpl" }}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123", out)
+
+	_, err = New(builtins(), WithLoader(loader), WithMaxDepth(3)).Render(`{{ extends "l3.tpl" }}`, nil)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+}
@@ -9,3 +9,22 @@ type GlobalModifier = functions.GlobalModifier
 // ContextualModifier is a modifier that needs live render state, the current
 // variables and a re-entrant renderer, rather than only its piped value.
 type ContextualModifier = functions.ContextualModifier
+
+// ContextAwareModifier is a global modifier that also receives the context the
+// render runs under, so it can honor RenderContext's cancellation and deadline.
+type ContextAwareModifier = functions.ContextAwareModifier
+
+// ModifierDef describes a modifier for tooling: its name, params, result and
+// examples. See WithModifierDefs.
+type ModifierDef = functions.ModifierDef
+
+// ParamDef describes one param of a ModifierDef.
+type ParamDef = functions.ParamDef
+
+// Loader finds a template's source by name, for the tags that refer to another
+// template. See WithLoader.
+type Loader = functions.Loader
+
+// ContextLoader is a Loader that can give up once the render's context is done.
+// The engine loads through LoadContext when its loader offers it.
+type ContextLoader = functions.ContextLoader
@@ -0,0 +1,108 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Arity is a range of param counts a modifier accepts, from Min to Max. Max is
+// -1 when there is no upper bound, as for a variadic modifier.
+type Arity struct {
+	Min, Max int
+}
+
+// Accepts reports whether a call passing n params fits a.
+func (a Arity) Accepts(n int) bool {
+	return n >= a.Min && (a.Max < 0 || n <= a.Max)
+}
+
+func (a Arity) String() string {
+	switch {
+	case a.Max < 0:
+		return fmt.Sprintf("%d or more", a.Min)
+	case a.Min == a.Max:
+		return fmt.Sprint(a.Min)
+	default:
+		return fmt.Sprintf("%d to %d", a.Min, a.Max)
+	}
+}
+
+// Arities are the arities of a modifier that takes several forms, as an
+// Overload does. A call fits when it fits any of them.
+type Arities []Arity
+
+// Accepts reports whether a call passing n params fits any of as.
+func (as Arities) Accepts(n int) bool {
+	for _, a := range as {
+		if a.Accepts(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (as Arities) String() string {
+	parts := make([]string, len(as))
+	for i, a := range as {
+		parts[i] = a.String()
+	}
+	return strings.Join(parts, " or ")
+}
+
+// arityProbe is the value ArityOf pipes into a modifier to ask for its arities.
+// The Wrap family and Overload recognize it and answer on it instead of
+// running, so asking costs them nothing and has no effect.
+type arityProbe struct {
+	arities Arities
+	known   bool
+}
+
+// answer records as on the probe, as the reply of the modifier it was piped into.
+func (p *arityProbe) answer(as ...Arity) (any, error) {
+	p.arities, p.known = as, true
+	return nil, nil
+}
+
+// adapters holds the code of every modifier that answers an arity probe, so
+// ArityOf only ever pipes the probe into one of them. A modifier written by
+// hand is never run to ask, since there is no telling what it would do.
+var adapters sync.Map
+
+// answering registers fn, a closure of the Wrap family, as one that answers an
+// arity probe. Every closure built by the same line of code shares its code, so
+// registering costs one entry per adapter, not per modifier.
+func answering[F any](fn F) F {
+	adapters.LoadOrStore(reflect.ValueOf(fn).Pointer(), struct{}{})
+	return fn
+}
+
+func answers(fn any) bool {
+	_, ok := adapters.Load(reflect.ValueOf(fn).Pointer())
+	return ok
+}
+
+// ArityOf reports the param counts fn accepts. A modifier built with Wrap,
+// WrapOne, WrapTwo, WrapVariadic or an Overload of them knows its arities, and
+// so does one decorated with AsText. For any other, ok is false.
+func ArityOf(fn GlobalModifier) (Arities, bool) {
+	if fn == nil || !answers(fn) {
+		return nil, false
+	}
+	probe := &arityProbe{}
+	_, _ = fn(probe, nil)
+	return probe.arities, probe.known
+}
+
+// ContextArityOf is ArityOf for a context-aware modifier, which knows its
+// arities when it is built with WrapContext.
+func ContextArityOf(fn ContextAwareModifier) (Arities, bool) {
+	if fn == nil || !answers(fn) {
+		return nil, false
+	}
+	probe := &arityProbe{}
+	_, _ = fn(context.Background(), probe, nil)
+	return probe.arities, probe.known
+}
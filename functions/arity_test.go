@@ -0,0 +1,80 @@
+package functions
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func Test_ArityOf(t *testing.T) {
+	id := func(s string) (string, error) { return s, nil }
+	pad := func(s string, n int) (string, error) { return s, nil }
+	between := func(s string, a, b string) (string, error) { return s, nil }
+	join := func(s []any, parts ...string) (string, error) { return "", nil }
+
+	tests := []struct {
+		name string
+		fn   GlobalModifier
+		want Arities
+	}{
+		{"Wrap", Wrap(id), Arities{{0, 0}}},
+		{"WrapOne", WrapOne(pad), Arities{{1, 1}}},
+		{"WrapTwo", WrapTwo(between), Arities{{2, 2}}},
+		{"WrapVariadic", WrapVariadic(join), Arities{{0, -1}}},
+		{"Overload", Overload(WrapOne(pad), Wrap(id), WrapOne(pad)), Arities{{1, 1}, {0, 0}}},
+		{"AsText", AsText(WrapOne(pad)), Arities{{1, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ArityOf(tt.fn)
+			if !ok || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ArityOf = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+
+	aware, ok := ContextArityOf(WrapContext(func(_ context.Context, s string) (string, error) { return s, nil }))
+	if !ok || !reflect.DeepEqual(aware, Arities{{0, 0}}) {
+		t.Fatalf("ContextArityOf = %v, %v", aware, ok)
+	}
+}
+
+// A modifier written by hand is never run to ask, and neither is an adapter
+// that holds one.
+func Test_ArityOf_Unknown(t *testing.T) {
+	called := false
+	plain := GlobalModifier(func(any, []any) (any, error) {
+		called = true
+		return nil, nil
+	})
+
+	for _, fn := range []GlobalModifier{plain, AsText(plain), Overload(Wrap(func(s string) (string, error) { return s, nil }), plain), nil} {
+		if as, ok := ArityOf(fn); ok {
+			t.Fatalf("expected no arities, got %v", as)
+		}
+	}
+	if _, ok := ContextArityOf(func(context.Context, any, []any) (any, error) {
+		called = true
+		return nil, nil
+	}); ok {
+		t.Fatal("expected no arities for a hand-written context-aware modifier")
+	}
+	if called {
+		t.Fatal("a hand-written modifier was run to ask for its arities")
+	}
+}
+
+func Test_Arities_Accepts(t *testing.T) {
+	as := Arities{{0, 0}, {2, -1}}
+	for n, want := range []bool{true, false, true, true} {
+		if got := as.Accepts(n); got != want {
+			t.Errorf("Accepts(%d) = %v, want %v", n, got, want)
+		}
+	}
+	if got := as.String(); got != "0 or 2 or more" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Arity{1, 3}).String(); got != "1 to 3" {
+		t.Errorf("String() = %q", got)
+	}
+}
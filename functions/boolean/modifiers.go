@@ -27,3 +27,41 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameEq):  eqModifier,
 	}
 }
+
+// Defs describes the boolean comparison modifiers, in the order Modifiers
+// lists them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameNot,
+			Group:    functions.GroupBoolean,
+			Summary:  "Inverts the truthiness of the value.",
+			Returns:  "bool",
+			Examples: []string{"{{ is_active | not }}"},
+		},
+		{
+			Name:     ModifierNameGt,
+			Group:    functions.GroupBoolean,
+			Summary:  "Reports whether a number is greater than the threshold.",
+			Params:   []functions.ParamDef{{Name: "threshold", Type: "number"}},
+			Returns:  "bool",
+			Examples: []string{"{{ items_in_cart | gt:0 }}"},
+		},
+		{
+			Name:     ModifierNameGte,
+			Group:    functions.GroupBoolean,
+			Summary:  "Reports whether a number is greater than or equal to the threshold.",
+			Params:   []functions.ParamDef{{Name: "threshold", Type: "number"}},
+			Returns:  "bool",
+			Examples: []string{"{{ qty | gte:1 }}"},
+		},
+		{
+			Name:     ModifierNameEq,
+			Group:    functions.GroupBoolean,
+			Summary:  "Reports whether the value equals the given one.",
+			Params:   []functions.ParamDef{{Name: "other", Type: "any"}},
+			Returns:  "bool",
+			Examples: []string{"{{ status | eq:'active' }}"},
+		},
+	}
+}
@@ -261,7 +261,7 @@ func ExampleKey_notAMap() {
 	fmt.Println(render(`{{ name | key:'first' | default:'unknown' }}`, map[string]any{
 		"name": "Alice",
 	}))
-	// Output: error: failed to render template: failed to render variable token 'name': modifier "key": function failed to apply: key expected a map or slice, got string: invalid value type
+	// Output: error: failed to render template: failed to render variable token 'name': modifier "key": function failed to apply: key expected a map, struct or slice, got string: invalid value type
 }
 
 // ExampleFindSlice returns the first map in a slice whose key field equals the
@@ -1,6 +1,7 @@
 package access
 
 import (
+	"errors"
 	"reflect"
 
 	"github.com/toaweme/sintax/functions"
@@ -9,22 +10,49 @@ import (
 // ModifierNameFind is the template name for the Find modifier.
 const ModifierNameFind functions.ModifierName = "find"
 
-// FindSlice returns the first map in a slice whose named field equals the wanted
-// value, scanning in order and returning the whole matching map. Matching is exact on
-// value and type, so a field holding the integer 42 is not matched by the string
-// "42". When nothing matches it returns a non-fatal ErrAllowsDefaultFunc error so
-// the default modifier can supply a fallback.
+// FindSlice returns the first map or struct in a slice whose named field equals
+// the wanted value, scanning in order and returning the whole matching element.
+// Matching is exact on value and type, so a field holding the integer 42 is not
+// matched by the string "42". When nothing matches it returns a non-fatal
+// ErrAllowsDefaultFunc error so the default modifier can supply a fallback.
 func FindSlice(v []any, key string, keyValue any) (any, error) {
+	return findSlice(functions.Fields{}, v, key, keyValue)
+}
+
+func findSlice(fields functions.Fields, v []any, key string, keyValue any) (any, error) {
 	for _, elem := range v {
 		if m, ok := elem.(map[string]any); ok {
 			if val, ok := m[key]; ok && reflect.DeepEqual(val, keyValue) {
 				return m, nil
 			}
+			continue
+		}
+		if !isStruct(elem) {
+			continue
+		}
+		val, err := fields.Field(reflect.ValueOf(elem), key)
+		if err != nil {
+			if errors.Is(err, functions.ErrAllowsDefaultFunc) {
+				continue
+			}
+			return nil, err
+		}
+		if reflect.DeepEqual(val, keyValue) {
+			return elem, nil
 		}
 	}
 	return nil, functions.Miss("key %q with value %v not found in slice", key, keyValue)
 }
 
+// isStruct reports whether v is a struct or a non-nil pointer to one.
+func isStruct(v any) bool {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return rv.Kind() == reflect.Struct
+}
+
 // FindMap returns the map itself when its named field equals the wanted value,
 // and otherwise a non-fatal ErrAllowsDefaultFunc error. It is the single-map form of
 // find.
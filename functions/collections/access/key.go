@@ -12,10 +12,12 @@ import (
 // ModifierNameKey is the template name for the Key modifier.
 const ModifierNameKey functions.ModifierName = "key"
 
-// Key reads one value out of a map by key or out of a slice by index. Pass a
-// string to look up a map key, and use dot notation in that string to walk into
-// nested maps (for example 'database.host'). Pass a number to index into a
-// slice.
+// Key reads one value out of a map or struct by key or out of a slice by index.
+// Pass a string to look up a map key or struct field, and use dot notation in
+// that string to walk into nested maps and structs (for example
+// 'database.host'). Pass a number to index into a slice. Struct fields resolve
+// by their Go names here; KeyWith resolves them through a functions.Fields, for
+// tag names and methods.
 //
 // A lookup that finds nothing is a miss rather than a failure. A missing key, a
 // path that runs out partway, an out-of-range index, and a nil value all report
@@ -26,8 +28,20 @@ const ModifierNameKey functions.ModifierName = "key"
 //
 // Being handed something that cannot be looked up at all is a different thing
 // and stays terminal. No key parameter, a non-string key, or a value that is
-// neither map nor slice means the template is wrong, and no default rescues it.
+// neither map, struct nor slice means the template is wrong, and no default
+// rescues it.
 func Key(value any, params []any) (any, error) {
+	return key(functions.Fields{}, value, params)
+}
+
+// KeyWith returns the key modifier with struct members resolved through fields.
+func KeyWith(fields functions.Fields) functions.GlobalModifier {
+	return func(value any, params []any) (any, error) {
+		return key(fields, value, params)
+	}
+}
+
+func key(fields functions.Fields, value any, params []any) (any, error) {
 	if len(params) == 0 {
 		return nil, fmt.Errorf("key requires a key parameter: %w", functions.ErrMissingParam)
 	}
@@ -39,21 +53,25 @@ func Key(value any, params []any) (any, error) {
 	rv := reflect.ValueOf(value)
 
 	switch rv.Type().Kind() {
-	case reflect.Map:
-		return handleMap(rv, params)
+	case reflect.Map, reflect.Struct:
+		return handlePath(fields, rv, params)
 	case reflect.Slice, reflect.Array:
 		return handleSlice(rv, params)
 	case reflect.Pointer:
 		if rv.IsNil() {
 			return nil, functions.Miss("key found no value to look in")
 		}
-		return Key(rv.Elem().Interface(), params)
+		if rv.Elem().Kind() == reflect.Struct {
+			// keep the pointer, so methods with pointer receivers stay reachable
+			return handlePath(fields, rv, params)
+		}
+		return key(fields, rv.Elem().Interface(), params)
 	default:
-		return nil, fmt.Errorf("key expected a map or slice, got %T: %w", value, functions.ErrInvalidValueType)
+		return nil, fmt.Errorf("key expected a map, struct or slice, got %T: %w", value, functions.ErrInvalidValueType)
 	}
 }
 
-func handleMap(rv reflect.Value, params []any) (any, error) {
+func handlePath(fields functions.Fields, rv reflect.Value, params []any) (any, error) {
 	parts, err := keyParts(params)
 	if err != nil {
 		return nil, err
@@ -61,14 +79,34 @@ func handleMap(rv reflect.Value, params []any) (any, error) {
 
 	current := rv
 	for i, part := range parts {
-		// Dereference pointers and unwrap interfaces
+		// Dereference pointers and unwrap interfaces, remembering the last pointer
+		// for a struct's pointer receiver methods
+		holder := current
 		for current.Kind() == reflect.Pointer || current.Kind() == reflect.Interface {
 			if current.IsNil() {
 				return nil, functions.Miss("key path stops at %q, which holds nothing", part)
 			}
+			if current.Kind() == reflect.Pointer {
+				holder = current
+			}
 			current = current.Elem()
 		}
 
+		if current.Kind() == reflect.Struct {
+			if holder.Kind() != reflect.Pointer {
+				holder = current
+			}
+			field, err := fields.Field(holder, part)
+			if err != nil {
+				return nil, err
+			}
+			if i == len(parts)-1 {
+				return field, nil
+			}
+			current = reflect.ValueOf(field)
+			continue
+		}
+
 		// the path runs out rather than the template being wrong. this data simply
 		// does not nest that deep, which is the same shape of absence as a key that
 		// is not there.
@@ -76,8 +114,8 @@ func handleMap(rv reflect.Value, params []any) (any, error) {
 			return nil, functions.Miss("key path segment %q is not a map, cannot look deeper", part)
 		}
 
-		keyValue, err := convertToMapKeyType(part, current.Type().Key())
-		if err != nil {
+		keyValue, ok := functions.MapKey(part, current.Type().Key())
+		if !ok {
 			return nil, functions.Miss("key %q cannot exist in a map keyed by %v", part, current.Type().Key())
 		}
 
@@ -110,33 +148,6 @@ func handleSlice(rv reflect.Value, params []any) (any, error) {
 	return rv.Index(index).Interface(), nil
 }
 
-func convertToMapKeyType(key string, keyType reflect.Type) (reflect.Value, error) {
-	switch keyType.Kind() {
-	case reflect.String:
-		return reflect.ValueOf(key), nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		intVal, err := strconv.ParseInt(key, 10, 64)
-		if err != nil {
-			return reflect.Value{}, fmt.Errorf("cannot convert %q to int: %w", key, err)
-		}
-		return reflect.ValueOf(intVal).Convert(keyType), nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		uintVal, err := strconv.ParseUint(key, 10, 64)
-		if err != nil {
-			return reflect.Value{}, fmt.Errorf("cannot convert %q to uint: %w", key, err)
-		}
-		return reflect.ValueOf(uintVal).Convert(keyType), nil
-	case reflect.Float32, reflect.Float64:
-		floatVal, err := strconv.ParseFloat(key, 64)
-		if err != nil {
-			return reflect.Value{}, fmt.Errorf("cannot convert %q to float: %w", key, err)
-		}
-		return reflect.ValueOf(floatVal).Convert(keyType), nil
-	default:
-		return reflect.Value{}, fmt.Errorf("unsupported map key type: %v", keyType.Kind())
-	}
-}
-
 // convertToInt is the lenient index coercion for slice access: it reuses the
 // strict functions.ValueInt for the numeric kinds, then adds the leniency an
 // array index wants but Wrap's int slot must not have - truncating a fractional
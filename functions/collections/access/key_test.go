@@ -57,6 +57,12 @@ func Test_Key(t *testing.T) {
 			params:   []any{"2"},
 			expected: "two",
 		},
+		{
+			name:     "float keyed map",
+			value:    map[float64]any{2: "two"},
+			params:   []any{"2"},
+			expected: "two",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -38,3 +38,68 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameFind):  findModifier,
 	}
 }
+
+// ModifiersWith returns the same modifiers as Modifiers, with key, pluck and
+// find resolving struct members through fields.
+func ModifiersWith(fields functions.Fields) map[string]functions.GlobalModifier {
+	mods := Modifiers()
+	mods[string(ModifierNameKey)] = KeyWith(fields)
+	mods[string(ModifierNamePluck)] = functions.WrapOne(func(value []any, field string) ([]any, error) {
+		return pluck(fields, value, field)
+	})
+	mods[string(ModifierNameFind)] = functions.Overload(
+		functions.WrapTwo(func(v []any, key string, keyValue any) (any, error) {
+			return findSlice(fields, v, key, keyValue)
+		}),
+		functions.WrapTwo(FindMap),
+	)
+	return mods
+}
+
+// Defs describes the element and field access modifiers, in the order
+// Modifiers lists them. ModifiersWith registers the same names and shapes.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameFirst,
+			Group:    functions.GroupCollections,
+			Summary:  "Returns the first character of a string or the first element of a list.",
+			Returns:  "any",
+			Examples: []string{"{{ items | first }}"},
+		},
+		{
+			Name:     ModifierNameLast,
+			Group:    functions.GroupCollections,
+			Summary:  "Returns the last character of a string or the last element of a list.",
+			Returns:  "any",
+			Examples: []string{"{{ items | last }}"},
+		},
+		{
+			Name:     ModifierNameKey,
+			Group:    functions.GroupCollections,
+			Summary:  "Reads a value out of a map or struct by dotted key path, or out of a list by index.",
+			Params:   []functions.ParamDef{{Name: "path", Type: "any"}},
+			Returns:  "any",
+			Examples: []string{"{{ user | key:'name' }}", "{{ config | key:'database.host' }}"},
+		},
+		{
+			Name:     ModifierNamePluck,
+			Group:    functions.GroupCollections,
+			Summary:  "Takes one field from each element of a list of records.",
+			Params:   []functions.ParamDef{{Name: "field", Type: "string"}},
+			Returns:  "[]any",
+			Examples: []string{"{{ users | pluck:'id' }}"},
+		},
+		{
+			Name:    ModifierNameFind,
+			Group:   functions.GroupCollections,
+			Summary: "Returns the first element of a list or map whose field equals the given value.",
+			Params: []functions.ParamDef{
+				{Name: "field", Type: "string"},
+				{Name: "value", Type: "any"},
+			},
+			Returns:  "any",
+			Examples: []string{"{{ users | find:'id',42 }}"},
+		},
+	}
+}
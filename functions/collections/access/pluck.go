@@ -1,6 +1,7 @@
 package access
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 
@@ -10,17 +11,22 @@ import (
 // ModifierNamePluck is the template name for the Pluck modifier.
 const ModifierNamePluck functions.ModifierName = "pluck"
 
-// Pluck reads one named field from every element of a slice of maps and returns
-// the collected values as a slice, in order. The result length always matches
+// Pluck reads one named field from every element of a slice of maps or structs
+// and returns the collected values as a slice, in order. Struct fields resolve
+// by their Go names, or through the functions.Fields given to ModifiersWith. The result length always matches
 // the input length, so a field that is absent from an element is never skipped
 // or padded over. An empty slice yields an empty slice.
 //
 // A field missing from an element, or an element holding nothing to read the
 // field from, is a miss, so `| pluck:'key' | default:[]` falls back to an empty
-// slice rather than failing. An element that is not a map at all is a terminal
-// error, since plucking a field from a number is a template that cannot mean
-// anything.
+// slice rather than failing. An element that is neither map nor struct is a
+// terminal error, since plucking a field from a number is a template that cannot
+// mean anything.
 func Pluck(value []any, field string) ([]any, error) {
+	return pluck(functions.Fields{}, value, field)
+}
+
+func pluck(fields functions.Fields, value []any, field string) ([]any, error) {
 	out := make([]any, 0, len(value))
 	for i, elem := range value {
 		ev := reflect.ValueOf(elem)
@@ -33,8 +39,19 @@ func Pluck(value []any, field string) ([]any, error) {
 		if elem == nil {
 			return nil, functions.Miss("pluck found nothing at element %d to read %q from", i, field)
 		}
+		if ev.Kind() == reflect.Struct {
+			v, err := fields.Field(reflect.ValueOf(elem), field)
+			if err != nil {
+				if errors.Is(err, functions.ErrAllowsDefaultFunc) {
+					return nil, functions.Miss("pluck found no field %q in element %d", field, i)
+				}
+				return nil, fmt.Errorf("pluck failed to read %q from element %d: %w", field, i, err)
+			}
+			out = append(out, v)
+			continue
+		}
 		if ev.Kind() != reflect.Map {
-			return nil, fmt.Errorf("pluck expected a map at element %d, got %T: %w", i, elem, functions.ErrInvalidValueType)
+			return nil, fmt.Errorf("pluck expected a map or struct at element %d, got %T: %w", i, elem, functions.ErrInvalidValueType)
 		}
 		var found bool
 		for _, k := range ev.MapKeys() {
@@ -0,0 +1,91 @@
+package access
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions"
+)
+
+type account struct {
+	IBAN    string `json:"iban"`
+	Balance int    `json:"balance"`
+	Owner   *owner `json:"owner"`
+	note    string
+}
+
+type owner struct {
+	Name string `json:"name"`
+}
+
+func (a account) Label() string { return "acct:" + a.IBAN }
+
+func accounts() []any {
+	return []any{
+		account{IBAN: "LT01", Balance: 10, Owner: &owner{Name: "Ada"}, note: "n"},
+		&account{IBAN: "LT02", Balance: 20},
+	}
+}
+
+func Test_Key_Struct(t *testing.T) {
+	acc := accounts()[0]
+
+	out, err := Key(acc, []any{"IBAN"})
+	assert.NoError(t, err)
+	assert.Equal(t, "LT01", out)
+
+	out, err = Key(&acc, []any{"Owner.Name"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Ada", out)
+
+	_, err = Key(acc, []any{"note"})
+	assert.ErrorIs(t, err, functions.ErrAllowsDefaultFunc)
+
+	// the zero-value key only knows Go names
+	_, err = Key(acc, []any{"iban"})
+	assert.ErrorIs(t, err, functions.ErrAllowsDefaultFunc)
+
+	mods := ModifiersWith(functions.Fields{Tag: "json", Methods: true})
+	out, err = mods["key"](acc, []any{"owner.name"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Ada", out)
+
+	out, err = mods["key"](acc, []any{"Label"})
+	assert.NoError(t, err)
+	assert.Equal(t, "acct:LT01", out)
+
+	// a nil pointer along the path is a miss, not a panic
+	_, err = mods["key"](accounts()[1], []any{"owner.name"})
+	assert.ErrorIs(t, err, functions.ErrAllowsDefaultFunc)
+}
+
+func Test_Pluck_Struct(t *testing.T) {
+	out, err := Pluck(accounts(), "IBAN")
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"LT01", "LT02"}, out)
+
+	pluck := ModifiersWith(functions.Fields{Tag: "json"})["pluck"]
+	balances, err := pluck(accounts(), []any{"balance"})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{10, 20}, balances)
+
+	_, err = pluck(accounts(), []any{"note"})
+	if !errors.Is(err, functions.ErrAllowsDefaultFunc) {
+		t.Errorf("got %v, want an unexported field to be a miss", err)
+	}
+}
+
+func Test_Find_Struct(t *testing.T) {
+	out, err := FindSlice(accounts(), "IBAN", "LT02")
+	assert.NoError(t, err)
+	assert.Equal(t, accounts()[1].(*account).IBAN, out.(*account).IBAN)
+
+	find := ModifiersWith(functions.Fields{Tag: "json"})["find"]
+	out, err = find(accounts(), []any{"balance", 10})
+	assert.NoError(t, err)
+	assert.Equal(t, "LT01", out.(account).IBAN)
+
+	_, err = find(accounts(), []any{"balance", 99})
+	assert.ErrorIs(t, err, functions.ErrAllowsDefaultFunc)
+}
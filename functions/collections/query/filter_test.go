@@ -164,3 +164,24 @@ func Test_Filter_Errors(t *testing.T) {
 		assert.ErrorIs(t, err, functions.ErrInvalidParamType)
 	})
 }
+
+type filterItem struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+func Test_Filter_Struct(t *testing.T) {
+	items := []any{
+		filterItem{Name: "Coffee", Status: "active"},
+		&filterItem{Name: "Tea", Status: "sold-out"},
+	}
+
+	out, err := filterModifier(items, []any{"Status", "active"})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{items[0]}, out)
+
+	filter := ModifiersWith(functions.Fields{Tag: "json"})["filter"]
+	out, err = filter(items, []any{"status", "sold-out"})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{items[1]}, out)
+}
@@ -1,6 +1,9 @@
 package query
 
-import "github.com/toaweme/sintax/functions"
+import (
+	"github.com/toaweme/sintax/functions"
+	"github.com/toaweme/sintax/functions/collections/access"
+)
 
 // Each modifier is a named, composed GlobalModifier so it can be referenced
 // directly (in tests, or by a consumer wanting one modifier) without building
@@ -21,3 +24,51 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameIs):     isModifier,
 	}
 }
+
+// ModifiersWith returns the same modifiers as Modifiers, with filter resolving
+// struct fields through fields.
+func ModifiersWith(fields functions.Fields) map[string]functions.GlobalModifier {
+	lookup := access.KeyWith(fields)
+	mods := Modifiers()
+	mods[string(ModifierNameFilter)] = functions.WrapTwo(func(value []any, key string, search any) ([]any, error) {
+		return filter(lookup, value, key, search)
+	})
+	return mods
+}
+
+// Defs describes the collection query modifiers, in the order Modifiers lists
+// them. ModifiersWith registers the same names and shapes.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:    ModifierNameFilter,
+			Group:   functions.GroupCollections,
+			Summary: "Keeps the elements of a list whose field equals the given value.",
+			Params: []functions.ParamDef{
+				{Name: "field", Type: "string"},
+				{Name: "value", Type: "any"},
+			},
+			Returns:  "[]any",
+			Examples: []string{"{{ items | filter:'status','active' }}"},
+		},
+		{
+			Name:    ModifierNameHas,
+			Group:   functions.GroupCollections,
+			Summary: "Reports whether a list holds a value, or a map or list of records a key with one of the given values.",
+			Params: []functions.ParamDef{
+				{Name: "value", Type: "any"},
+				{Name: "values", Type: "any", Variadic: true},
+			},
+			Returns:  "bool",
+			Examples: []string{"{{ tags | has:'featured' }}", "{{ users | has:'role','admin','owner' }}"},
+		},
+		{
+			Name:     ModifierNameIs,
+			Group:    functions.GroupCollections,
+			Summary:  "Reports whether the value equals any of the given candidates.",
+			Params:   []functions.ParamDef{{Name: "candidates", Type: "any", Variadic: true}},
+			Returns:  "bool",
+			Examples: []string{"{{ status | is:'active','trial' }}"},
+		},
+	}
+}
@@ -33,3 +33,62 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameFlatten): flattenModifier,
 	}
 }
+
+// ModifiersWith returns the same modifiers as Modifiers, with sum resolving
+// struct fields through fields.
+func ModifiersWith(fields functions.Fields) map[string]functions.GlobalModifier {
+	mods := Modifiers()
+	mods[string(ModifierNameSum)] = functions.Overload(
+		functions.WrapOne(func(v []any, field string) (float64, error) {
+			return sumField(fields, v, field)
+		}),
+		functions.Wrap(SumElements),
+	)
+	return mods
+}
+
+// Defs describes the collection transform modifiers, in the order Modifiers
+// lists them. ModifiersWith registers the same names and shapes.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameMap,
+			Group:    functions.GroupCollections,
+			Summary:  "Keys a list of maps by the string value of the given field.",
+			Params:   []functions.ParamDef{{Name: "field", Type: "string"}},
+			Returns:  "map[string]map[string]any",
+			Examples: []string{"{{ users | map:'id' }}"},
+		},
+		{
+			Name:     ModifierNameMerge,
+			Group:    functions.GroupCollections,
+			Summary:  "Keys a list of maps by the string value of the given field, as map does.",
+			Params:   []functions.ParamDef{{Name: "field", Type: "string"}},
+			Returns:  "map[string]map[string]any",
+			Examples: []string{"{{ users | merge:'id' }}"},
+		},
+		{
+			Name:     ModifierNameSort,
+			Group:    functions.GroupCollections,
+			Summary:  "Sorts a copy of a list, 'asc' or 'desc', ascending when no direction is given.",
+			Params:   []functions.ParamDef{{Name: "direction", Type: "string", Optional: true}},
+			Returns:  "[]any",
+			Examples: []string{"{{ names | sort }}", "{{ scores | sort:'desc' }}"},
+		},
+		{
+			Name:     ModifierNameSum,
+			Group:    functions.GroupCollections,
+			Summary:  "Adds up the numbers of a list, or the given field of each of its records.",
+			Params:   []functions.ParamDef{{Name: "field", Type: "string", Optional: true}},
+			Returns:  "float64",
+			Examples: []string{"{{ amounts | sum }}", "{{ lines | sum:'total' }}"},
+		},
+		{
+			Name:     ModifierNameFlatten,
+			Group:    functions.GroupCollections,
+			Summary:  "Flattens a list of lists by one level.",
+			Returns:  "[]any",
+			Examples: []string{"{{ groups | pluck:'items' | flatten }}"},
+		},
+	}
+}
@@ -1,6 +1,7 @@
 package transform
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 
@@ -24,15 +25,19 @@ func SumElements(v []any) (float64, error) {
 	return total, nil
 }
 
-// SumField totals the named field across a slice of maps, the way you sum one
-// column of a list of records. A field absent from a record is a miss, so
+// SumField totals the named field across a slice of maps or structs, the way you
+// sum one column of a list of records. A field absent from a record is a miss, so
 // `| sum:'amount' | default:0` falls back rather than failing, while a
 // non-numeric value in the column is a terminal error, since silently treating
 // "abc" as zero would understate a total that someone is going to act on.
 func SumField(v []any, field string) (float64, error) {
+	return sumField(functions.Fields{}, v, field)
+}
+
+func sumField(fields functions.Fields, v []any, field string) (float64, error) {
 	var total float64
 	for i, elem := range v {
-		n, err := numberFromField(elem, field)
+		n, err := numberFromField(fields, elem, field)
 		if err != nil {
 			return 0, fmt.Errorf("failed to sum element %d: %w", i, err)
 		}
@@ -47,7 +52,7 @@ func SumField(v []any, field string) (float64, error) {
 // an identity to fall back on, so a nil record can be counted as contributing
 // nothing without inventing anything, while pluck would have to fabricate an
 // element to keep its result aligned with its input.
-func numberFromField(elem any, field string) (float64, error) {
+func numberFromField(fields functions.Fields, elem any, field string) (float64, error) {
 	rv := reflect.ValueOf(elem)
 	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
 		if rv.IsNil() {
@@ -58,8 +63,18 @@ func numberFromField(elem any, field string) (float64, error) {
 	if elem == nil {
 		return 0, nil
 	}
+	if rv.Kind() == reflect.Struct {
+		v, err := fields.Field(reflect.ValueOf(elem), field)
+		if err != nil {
+			if errors.Is(err, functions.ErrAllowsDefaultFunc) {
+				return 0, functions.Miss("sum found no field %q to total", field)
+			}
+			return 0, err
+		}
+		return functions.ParseNumber(v)
+	}
 	if rv.Kind() != reflect.Map {
-		return 0, fmt.Errorf("sum expected a map to read field %q from, got %T: %w", field, elem, functions.ErrInvalidValueType)
+		return 0, fmt.Errorf("sum expected a map or struct to read field %q from, got %T: %w", field, elem, functions.ErrInvalidValueType)
 	}
 	for _, k := range rv.MapKeys() {
 		if fmt.Sprint(k.Interface()) == field {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions"
 )
 
 func Test_Sum(t *testing.T) {
@@ -115,3 +116,26 @@ func Test_Sum_FieldOnNonMap(t *testing.T) {
 	_, err := sum([]any{1, 2}, []any{"price"})
 	assert.Error(t, err)
 }
+
+type sumLine struct {
+	Amount float64 `json:"amount"`
+	Qty    int
+}
+
+// Test_Sum_StructField proves sum reads a field from struct records, by Go name
+// or through the configured tag.
+func Test_Sum_StructField(t *testing.T) {
+	lines := []sumLine{{Amount: 1.5, Qty: 2}, {Amount: 2.5, Qty: 3}}
+
+	out, err := sumModifier(lines, []any{"Qty"})
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, out)
+
+	sum := ModifiersWith(functions.Fields{Tag: "json"})["sum"]
+	out, err = sum(lines, []any{"amount"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4.0, out)
+
+	_, err = sum(lines, []any{"missing"})
+	assert.ErrorIs(t, err, functions.ErrAllowsDefaultFunc)
+}
@@ -0,0 +1,126 @@
+package functions
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ValuesEqual compares two values for equality, treating the numeric kinds as
+// interchangeable so an int field matches a float search of the same value.
+// Strings and bools compare by value across named types, any other pair of the
+// same type compares deeply, and nil equals only nil. A number and its string
+// form are never equal, so 5 does not equal "5".
+func ValuesEqual(a, b any) bool {
+	if a == nil && b == nil {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+	va := reflect.ValueOf(a)
+	vb := reflect.ValueOf(b)
+	if va.Type() == vb.Type() {
+		return reflect.DeepEqual(a, b)
+	}
+	if isNumeric(va) && isNumeric(vb) {
+		return convertToFloat64(va) == convertToFloat64(vb)
+	}
+	if va.Kind() == reflect.String && vb.Kind() == reflect.String {
+		return va.String() == vb.String()
+	}
+	if va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool {
+		return va.Bool() == vb.Bool()
+	}
+	return false
+}
+
+// CompareValues orders two values, returning -1, 0 or 1 as a sorts before,
+// level with, or after b. Numbers compare by value across the int and float
+// kinds, and nil counts as zero against a number, matching the gt and gte
+// modifiers. Strings compare byte-wise. Any other pair has no order and reports
+// ErrInvalidValueType.
+func CompareValues(a, b any) (int, error) {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.String && vb.Kind() == reflect.String {
+		return strings.Compare(va.String(), vb.String()), nil
+	}
+	if (a == nil || isNumeric(va)) && (b == nil || isNumeric(vb)) {
+		x, y := 0.0, 0.0
+		if a != nil {
+			x = convertToFloat64(va)
+		}
+		if b != nil {
+			y = convertToFloat64(vb)
+		}
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: cannot order %T against %T", ErrInvalidValueType, a, b)
+}
+
+// Contains reports whether needle is in haystack. A slice or array contains its
+// elements, a map contains its keys, and a string contains its substrings, with
+// elements and keys matched by ValuesEqual. Anything else, nil included,
+// contains nothing.
+func Contains(haystack, needle any) (bool, error) {
+	if haystack == nil {
+		return false, nil
+	}
+	v := reflect.ValueOf(haystack)
+	switch v.Kind() {
+	case reflect.String:
+		s, ok := needle.(string)
+		if !ok {
+			return false, fmt.Errorf("%w: cannot look for %T in a string", ErrInvalidValueType, needle)
+		}
+		return strings.Contains(v.String(), s), nil
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if ValuesEqual(v.Index(i).Interface(), needle) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if ValuesEqual(iter.Key().Interface(), needle) {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+	}
+	return false, fmt.Errorf("%w: cannot look inside %T", ErrInvalidValueType, haystack)
+}
+
+func isNumeric(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func convertToFloat64(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	default:
+		return 0
+	}
+}
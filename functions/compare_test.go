@@ -0,0 +1,90 @@
+package functions
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_ValuesEqual(t *testing.T) {
+	type label string
+	tests := []struct {
+		name string
+		a, b any
+		want bool
+	}{
+		{"nil and nil", nil, nil, true},
+		{"nil and zero", nil, 0, false},
+		{"int and float", 5, 5.0, true},
+		{"uint and int", uint8(3), 3, true},
+		{"number and its string", 5, "5", false},
+		{"named string", label("x"), "x", true},
+		{"bools", true, true, true},
+		{"slices", []any{1, "a"}, []any{1, "a"}, true},
+		{"different slices", []any{1}, []any{2}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValuesEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("ValuesEqual(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_CompareValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    any
+		want    int
+		wantErr error
+	}{
+		{"int below float", 1, 1.5, -1, nil},
+		{"equal numbers", 2, 2.0, 0, nil},
+		{"nil counts as zero", nil, 1, -1, nil},
+		{"nil and nil", nil, nil, 0, nil},
+		{"strings", "b", "a", 1, nil},
+		{"string and number", "a", 1, 0, ErrInvalidValueType},
+		{"bools have no order", true, false, 0, ErrInvalidValueType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CompareValues(tt.a, tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CompareValues(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Contains(t *testing.T) {
+	tests := []struct {
+		name     string
+		haystack any
+		needle   any
+		want     bool
+		wantErr  error
+	}{
+		{"slice element", []any{1, 2}, 2.0, true, nil},
+		{"missing element", []string{"a"}, "b", false, nil},
+		{"map key", map[string]int{"a": 1}, "a", true, nil},
+		{"map value is not a key", map[string]int{"a": 1}, 1, false, nil},
+		{"substring", "banking", "bank", true, nil},
+		{"nil contains nothing", nil, "a", false, nil},
+		{"number in a string", "123", 1, false, ErrInvalidValueType},
+		{"not a collection", 5, 5, false, ErrInvalidValueType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Contains(tt.haystack, tt.needle)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Contains(%v, %v) = %v, want %v", tt.haystack, tt.needle, got, tt.want)
+			}
+		})
+	}
+}
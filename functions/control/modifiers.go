@@ -11,3 +11,19 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameDefault): defaultModifier,
 	}
 }
+
+// Defs describes the value-resolution control modifiers, in the order
+// Modifiers lists them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:        ModifierNameDefault,
+			Group:       functions.GroupUtilities,
+			Summary:     "Returns the fallback when the value is nil, an empty string or a miss.",
+			Params:      []functions.ParamDef{{Name: "fallback", Type: "any"}},
+			Returns:     "any",
+			Examples:    []string{"{{ name | default:'anonymous' }}", "{{ user | key:'nick' | default:'-' }}"},
+			AnswersMiss: true,
+		},
+	}
+}
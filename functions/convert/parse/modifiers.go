@@ -27,3 +27,30 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameFromYAML): fromYAMLModifier,
 	}
 }
+
+// Defs describes the parsing modifiers, in the order Modifiers lists them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameFromJSON,
+			Group:    functions.GroupConvert,
+			Summary:  "Parses a JSON object string into a map.",
+			Returns:  "map[string]any",
+			Examples: []string{"{{ body | from_json | key:'id' }}"},
+		},
+		{
+			Name:     ModifierNameFromCSV,
+			Group:    functions.GroupConvert,
+			Summary:  "Parses a CSV string into a list of rows keyed by the header row.",
+			Returns:  "[]map[string]any",
+			Examples: []string{"{{ body | from_csv }}"},
+		},
+		{
+			Name:     ModifierNameFromYAML,
+			Group:    functions.GroupConvert,
+			Summary:  "Parses a YAML document into a map. Ships as a stub until you inject a codec.",
+			Returns:  "map[string]any",
+			Examples: []string{"{{ body | from_yaml }}"},
+		},
+	}
+}
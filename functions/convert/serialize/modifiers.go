@@ -23,3 +23,32 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameMarkdown): markdownModifier,
 	}
 }
+
+// Defs describes the serialization modifiers, in the order Modifiers lists
+// them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameJSON,
+			Group:    functions.GroupConvert,
+			Summary:  "Serializes the value to JSON, indented when the mode is 'pretty'.",
+			Params:   []functions.ParamDef{{Name: "mode", Type: "string", Optional: true}},
+			Returns:  "string",
+			Examples: []string{"{{ user | json }}", "{{ user | json:'pretty' }}"},
+		},
+		{
+			Name:     ModifierNameYAML,
+			Group:    functions.GroupConvert,
+			Summary:  "Serializes the value to YAML. Ships as a stub until you inject a codec.",
+			Returns:  "string",
+			Examples: []string{"{{ config | yaml }}"},
+		},
+		{
+			Name:     ModifierNameMarkdown,
+			Group:    functions.GroupConvert,
+			Summary:  "Converts an HTML string to Markdown. Ships as a stub until you inject a converter.",
+			Returns:  "string",
+			Examples: []string{"{{ html_content | markdown }}"},
+		},
+	}
+}
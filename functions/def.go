@@ -0,0 +1,67 @@
+package functions
+
+// The groups the built-in modifiers are listed under, in the README and in a
+// ModifierDef.
+const (
+	GroupText        = "Text"
+	GroupCollections = "Collections"
+	GroupBoolean     = "Boolean"
+	GroupConvert     = "Convert"
+	GroupUtilities   = "Utilities"
+	GroupFileSystem  = "File System"
+	GroupMoney       = "Money"
+	GroupEscape      = "Escape"
+)
+
+// ModifierDef describes a modifier for tooling rather than for rendering: what
+// it is called, what it takes and returns, and how a template uses it. Each
+// modifier group hands out its definitions with Defs, next to its Modifiers map,
+// so autocomplete, generated docs and call validation all read from one source.
+type ModifierDef struct {
+	// Name is the template name, such as "trim_prefix".
+	Name ModifierName
+	// Group is the family the modifier is listed under, such as GroupText.
+	Group string
+	// Summary is a one-sentence description of what the modifier does.
+	Summary string
+	// Params are the params a call passes after the name, in order.
+	Params []ParamDef
+	// Returns is the type of the result, such as "string" or "[]any".
+	Returns string
+	// Examples are tags that call the modifier, such as "{{ name | upper }}".
+	Examples []string
+	// AnswersMiss reports whether the modifier catches a miss from earlier in
+	// the pipeline, as default does, so a variable it follows may be absent.
+	AnswersMiss bool
+}
+
+// ParamDef describes one param of a modifier.
+type ParamDef struct {
+	// Name is what the param is called in documentation, such as "separator".
+	Name string
+	// Type is the type the param takes, such as "string" or "int". "any" means
+	// any value.
+	Type string
+	// Optional reports whether a call may leave the param out. Every param
+	// after an optional one is optional too.
+	Optional bool
+	// Variadic reports whether the param soaks up the rest of the call's params.
+	// Only the last param can be variadic, and it is optional unless the
+	// modifier says otherwise with a required param before it.
+	Variadic bool
+}
+
+// Arity reports the param counts d accepts, derived from its Params.
+func (d ModifierDef) Arity() Arity {
+	a := Arity{Max: len(d.Params)}
+	for _, p := range d.Params {
+		if p.Variadic {
+			a.Max = -1
+			continue
+		}
+		if !p.Optional {
+			a.Min++
+		}
+	}
+	return a
+}
@@ -0,0 +1,24 @@
+package functions
+
+import "testing"
+
+func Test_ModifierDef_Arity(t *testing.T) {
+	tests := []struct {
+		name   string
+		params []ParamDef
+		want   Arity
+	}{
+		{"none", nil, Arity{0, 0}},
+		{"required", []ParamDef{{Name: "a"}, {Name: "b"}}, Arity{2, 2}},
+		{"optional", []ParamDef{{Name: "a"}, {Name: "b", Optional: true}}, Arity{1, 2}},
+		{"variadic", []ParamDef{{Name: "a", Variadic: true}}, Arity{0, -1}},
+		{"required then variadic", []ParamDef{{Name: "a"}, {Name: "b", Variadic: true}}, Arity{1, -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (ModifierDef{Params: tt.params}).Arity(); got != tt.want {
+				t.Fatalf("Arity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@
 package functions
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -28,6 +29,13 @@ type GlobalModifier func(value any, params []any) (any, error)
 // snapshot. Copy out anything that must outlive the call.
 type ContextualModifier func(render func(template string, vars map[string]any) (any, error), vars map[string]any, value any, params []any) (any, error)
 
+// ContextAwareModifier is a global modifier that also receives the context the
+// render runs under, so one that waits on the outside world (a file, a network
+// lookup) can give up when the caller cancels or the deadline passes. Outside
+// RenderContext it is handed context.Background(). Like a GlobalModifier it sees
+// only its piped value and params, never the render's variables.
+type ContextAwareModifier func(ctx context.Context, value any, params []any) (any, error)
+
 // Miss reports that the data a modifier went looking for was not there, which
 // is the condition the default modifier exists to catch. A lookup that matched
 // no element, a collection with no element to take, and a file that is not in
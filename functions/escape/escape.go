@@ -13,6 +13,11 @@
 // convert changes a value's representation, while escape leaves the text as
 // text and only defuses the characters the destination would otherwise
 // interpret. See each modifier for its safety boundary.
+//
+// An engine can also escape every value on its own, see sintax.WithAutoEscape.
+// escape_html is registered with functions.AsMarkup, so such an engine does not
+// escape its result twice, and safe is how a template writes a value out
+// unescaped.
 package escape
 
 import (
@@ -33,6 +38,8 @@ const (
 	ModifierNameURL functions.ModifierName = "escape_url"
 	// ModifierNameJS is the template name for the JS modifier.
 	ModifierNameJS functions.ModifierName = "escape_js"
+	// ModifierNameSafe is the template name for the Safe modifier.
+	ModifierNameSafe functions.ModifierName = "safe"
 )
 
 // HTML escapes a value for an HTML text node or a quoted attribute value. It is
@@ -70,7 +77,24 @@ func JS(value any) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return escapeJS(str), nil
+	return JSString(str), nil
+}
+
+// Safe marks a value as already fit for the output it lands in, so an engine
+// with auto-escaping on writes it as is. It is the one way out of
+// auto-escaping, and the author vouches for the value: safe on a value that
+// holds user input reopens the injection auto-escaping closes. It changes
+// nothing when auto-escaping is off. Scalar values are coerced to their string
+// form first.
+func Safe(value any) (functions.SafeString, error) {
+	if s, ok := value.(functions.SafeString); ok {
+		return s, nil
+	}
+	str, err := stringify(value)
+	if err != nil {
+		return "", err
+	}
+	return functions.SafeString(str), nil
 }
 
 // stringify coerces a scalar value to the string form the escapers will encode.
@@ -84,6 +108,8 @@ func stringify(v any) (string, error) {
 		return "", nil
 	case string:
 		return s, nil
+	case functions.SafeString:
+		return string(s), nil
 	case bool:
 		return strconv.FormatBool(s), nil
 	case int, int8, int16, int32, int64,
@@ -95,15 +121,16 @@ func stringify(v any) (string, error) {
 	}
 }
 
-// escapeJS neutralizes the characters that would let a value break out of a
+// JSString neutralizes the characters that would let a value break out of a
 // JavaScript string literal or the surrounding <script> element. It escapes
 // quotes, backslashes, and forward slashes. It escapes the characters that
 // could open or close a script or comment tag (< > &), backticks, every control
 // character (bytes 0 to 31), and the delete character (127). It also escapes
 // U+2028 and U+2029, two invisible line separators that end a string literal in
 // older JavaScript engines. The result is meant to sit inside an existing
-// quoted string, so it does not add the surrounding quotes itself.
-func escapeJS(s string) string {
+// quoted string, so it does not add the surrounding quotes itself. It is what
+// JS escapes with, and what an auto-escaping engine uses inside a script.
+func JSString(s string) string {
 	var b strings.Builder
 	b.Grow(len(s))
 	for _, r := range s {
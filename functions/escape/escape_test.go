@@ -283,3 +283,23 @@ func Test_Modifiers(t *testing.T) {
 		return out.(string)
 	}())
 }
+
+func Test_Safe(t *testing.T) {
+	out, err := Safe("<b>x</b>")
+	assert.NoError(t, err)
+	assert.Equal(t, functions.SafeString("<b>x</b>"), out)
+
+	out, err = Safe(functions.SafeString("<i>"))
+	assert.NoError(t, err)
+	assert.Equal(t, functions.SafeString("<i>"), out)
+
+	out, err = Safe(42)
+	assert.NoError(t, err)
+	assert.Equal(t, functions.SafeString("42"), out)
+
+	_, err = Safe([]int{1})
+	assert.ErrorIs(t, err, functions.ErrInvalidValueType)
+
+	_, err = Modifiers()[string(ModifierNameSafe)]("x", []any{"extra"})
+	assert.ErrorIs(t, err, functions.ErrInvalidParamType)
+}
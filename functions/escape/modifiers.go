@@ -3,9 +3,10 @@ package escape
 import "github.com/toaweme/sintax/functions"
 
 var (
-	escapeHTMLModifier = functions.Wrap(HTML)
+	escapeHTMLModifier = functions.AsMarkup(functions.Wrap(HTML))
 	escapeURLModifier  = functions.Wrap(URL)
 	escapeJSModifier   = functions.Wrap(JS)
+	safeModifier       = functions.Wrap(Safe)
 )
 
 // Modifiers returns the context-escaping modifiers keyed by their template names.
@@ -14,5 +15,41 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameHTML): escapeHTMLModifier,
 		string(ModifierNameURL):  escapeURLModifier,
 		string(ModifierNameJS):   escapeJSModifier,
+		string(ModifierNameSafe): safeModifier,
+	}
+}
+
+// Defs describes the context-escaping modifiers, in the order Modifiers lists
+// them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameHTML,
+			Group:    functions.GroupEscape,
+			Summary:  "Escapes a value for HTML text or a quoted attribute, which auto-escaping then leaves alone.",
+			Returns:  "string",
+			Examples: []string{"{{ comment | escape_html }}"},
+		},
+		{
+			Name:     ModifierNameURL,
+			Group:    functions.GroupEscape,
+			Summary:  "Escapes a value for a query-string value.",
+			Returns:  "string",
+			Examples: []string{"/search?q={{ query | escape_url }}"},
+		},
+		{
+			Name:     ModifierNameJS,
+			Group:    functions.GroupEscape,
+			Summary:  "Escapes a value for a quoted JavaScript string literal.",
+			Returns:  "string",
+			Examples: []string{"var name = '{{ name | escape_js }}';"},
+		},
+		{
+			Name:     ModifierNameSafe,
+			Group:    functions.GroupEscape,
+			Summary:  "Marks a value as already escaped, so auto-escaping writes it as is.",
+			Returns:  "string",
+			Examples: []string{"{{ trusted_html | safe }}"},
+		},
 	}
 }
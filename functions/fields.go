@@ -0,0 +1,199 @@
+package functions
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Fields decides which members of a struct a template can reach, and under what
+// names. The zero value exposes exported fields under their Go names, promoted
+// fields of embedded structs included, and an exported embedded struct under
+// its type name too, so `o.City` and `o.Address.City` read the same field, as
+// in Go. Unexported fields are never visible, whatever the configuration.
+//
+// The engine takes one through sintax.WithFields for variable paths and loops,
+// and the collection groups take one through their ModifiersWith constructors
+// for key, pluck, filter, find and sum, so a DTO reads the same way everywhere.
+type Fields struct {
+	// Tag names a struct tag, such as "json" or "sintax", whose name renames a
+	// field the way encoding/json reads it: the part before the first comma, with
+	// "-" hiding the field. A field without the tag keeps its Go name.
+	Tag string
+	// Methods also exposes exported methods that take no arguments and return a
+	// value, or a value and an error, called when they are looked up. Fields win
+	// over methods of the same name.
+	Methods bool
+}
+
+// structField is one visible member of a struct type.
+type structField struct {
+	name  string
+	index []int
+}
+
+// structLayout is the visible fields of a struct type under one tag, in
+// declaration order with promoted fields following the struct that embeds them.
+// byName also holds the untagged embedded structs, which are members a path can
+// name but are listed by their fields rather than themselves.
+type structLayout struct {
+	fields []structField
+	byName map[string][]int
+}
+
+type layoutKey struct {
+	t   reflect.Type
+	tag string
+}
+
+// layouts caches structLayout per type and tag, since working out promotion and
+// tag names walks the whole type and the answer never changes.
+var layouts sync.Map
+
+func (f Fields) layout(t reflect.Type) *structLayout {
+	key := layoutKey{t: t, tag: f.Tag}
+	if cached, ok := layouts.Load(key); ok {
+		return cached.(*structLayout)
+	}
+
+	l := &structLayout{byName: make(map[string][]int)}
+	depth := make(map[string]int)
+	listed := make(map[string]int)
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() {
+			continue
+		}
+		name := sf.Name
+		tagged := false
+		if f.Tag != "" {
+			if tag, ok := sf.Tag.Lookup(f.Tag); ok {
+				tagName, _, _ := strings.Cut(tag, ",")
+				if tagName == "-" {
+					continue
+				}
+				if tagName != "" {
+					name, tagged = tagName, true
+				}
+			}
+		}
+		// the shallower field wins a name clash, the way Go promotes fields
+		if d, seen := depth[name]; seen && d <= len(sf.Index) {
+			continue
+		}
+		depth[name] = len(sf.Index)
+		l.byName[name] = sf.Index
+		if i, ok := listed[name]; ok {
+			l.fields[i].index = sf.Index
+			continue
+		}
+		// an untagged embedded struct is listed by its fields, which
+		// VisibleFields lists after it, rather than by itself
+		if sf.Anonymous && !tagged && isStructType(sf.Type) {
+			continue
+		}
+		listed[name] = len(l.fields)
+		l.fields = append(l.fields, structField{name: name, index: sf.Index})
+	}
+
+	cached, _ := layouts.LoadOrStore(key, l)
+	return cached.(*structLayout)
+}
+
+func isStructType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// Field reads the member called name from a struct. v is the struct itself or
+// a pointer to it, and a pointer is what makes methods with pointer receivers
+// reachable. It returns a Miss when there is no such visible member, or when a
+// promoted field sits behind a nil embedded pointer. An error returned by a
+// method is returned as it is.
+func (f Fields) Field(v reflect.Value, name string) (any, error) {
+	sv := v
+	for sv.Kind() == reflect.Pointer || sv.Kind() == reflect.Interface {
+		if sv.IsNil() {
+			return nil, Miss("field %q has nothing to look in", name)
+		}
+		sv = sv.Elem()
+	}
+	if sv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: expected a struct, got %v", ErrInvalidValueType, sv.Kind())
+	}
+
+	l := f.layout(sv.Type())
+	if index, ok := l.byName[name]; ok {
+		fv, err := sv.FieldByIndexErr(index)
+		if err != nil {
+			return nil, Miss("field %q sits behind a nil pointer", name)
+		}
+		return fv.Interface(), nil
+	}
+	if f.Methods {
+		if out, ok, err := callMethod(v, sv, name); ok {
+			return out, err
+		}
+	}
+	return nil, Miss("field %q not found", name)
+}
+
+// callMethod calls the exported zero-argument method called name, looking on
+// the pointer first so pointer receivers are found. It reports false when there
+// is no method of a usable shape.
+func callMethod(v, sv reflect.Value, name string) (any, bool, error) {
+	m := reflect.Value{}
+	if v.Kind() == reflect.Pointer {
+		m = v.MethodByName(name)
+	}
+	if !m.IsValid() {
+		m = sv.MethodByName(name)
+	}
+	if !m.IsValid() {
+		return nil, false, nil
+	}
+	mt := m.Type()
+	errorType := reflect.TypeFor[error]()
+	switch {
+	case mt.NumIn() != 0:
+		return nil, false, nil
+	case mt.NumOut() == 1:
+		return m.Call(nil)[0].Interface(), true, nil
+	case mt.NumOut() == 2 && mt.Out(1) == errorType:
+		out := m.Call(nil)
+		if err, _ := out[1].Interface().(error); err != nil {
+			return nil, true, fmt.Errorf("method %s failed: %w", name, err)
+		}
+		return out[0].Interface(), true, nil
+	default:
+	}
+	return nil, false, nil
+}
+
+// Each calls fn with the name and value of every visible field of a struct, in
+// declaration order. Methods are not included, since calling every method to
+// list a struct would run code nobody asked for. A promoted field behind a nil
+// embedded pointer is skipped.
+func (f Fields) Each(v reflect.Value, fn func(name string, value any) error) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: expected a struct, got %v", ErrInvalidValueType, v.Kind())
+	}
+	for _, field := range f.layout(v.Type()).fields {
+		fv, err := v.FieldByIndexErr(field.index)
+		if err != nil {
+			continue
+		}
+		if err := fn(field.name, fv.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package functions
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fieldsBase struct {
+	ID      int    `json:"id"`
+	Created string `json:"created_at"`
+}
+
+type fieldsAccount struct {
+	fieldsBase
+	*fieldsOwner
+	IBAN     string `json:"iban" sintax:"account_number"`
+	Balance  float64
+	Internal string `json:"-"`
+	secret   string
+}
+
+type fieldsOwner struct {
+	Name string `json:"name"`
+}
+
+type FieldsAddress struct {
+	City string
+}
+
+type fieldsOrder struct {
+	FieldsAddress
+	Number int
+}
+
+func (a fieldsAccount) Masked() string { return "****" + a.IBAN[len(a.IBAN)-2:] }
+
+func (a *fieldsAccount) Pointer() string { return "ptr" }
+
+func (a fieldsAccount) Failing() (string, error) { return "", errors.New("boom") }
+
+func (a fieldsAccount) Takes(int) string { return "no" }
+
+func newFieldsAccount() *fieldsAccount {
+	return &fieldsAccount{
+		fieldsBase:  fieldsBase{ID: 7, Created: "today"},
+		fieldsOwner: &fieldsOwner{Name: "Ada"},
+		IBAN:        "LT1234",
+		Balance:     9.5,
+		Internal:    "x",
+		secret:      "s",
+	}
+}
+
+func Test_Fields_Field(t *testing.T) {
+	acc := newFieldsAccount()
+	tests := []struct {
+		name    string
+		fields  Fields
+		value   any
+		field   string
+		want    any
+		miss    bool
+		wantErr bool
+	}{
+		{name: "go name", value: acc, field: "IBAN", want: "LT1234"},
+		{name: "struct value", value: *acc, field: "Balance", want: 9.5},
+		{name: "promoted field", value: acc, field: "ID", want: 7},
+		{name: "promoted through pointer", value: acc, field: "Name", want: "Ada"},
+		{name: "unexported is invisible", value: acc, field: "secret", miss: true},
+		{name: "embedded struct itself is not a member", value: acc, field: "fieldsBase", miss: true},
+		{name: "json tag renames", fields: Fields{Tag: "json"}, value: acc, field: "iban", want: "LT1234"},
+		{name: "json tag hides the go name", fields: Fields{Tag: "json"}, value: acc, field: "IBAN", miss: true},
+		{name: "untagged keeps its go name", fields: Fields{Tag: "json"}, value: acc, field: "Balance", want: 9.5},
+		{name: "dash hides", fields: Fields{Tag: "json"}, value: acc, field: "Internal", miss: true},
+		{name: "promoted tag", fields: Fields{Tag: "json"}, value: acc, field: "created_at", want: "today"},
+		{name: "other tag", fields: Fields{Tag: "sintax"}, value: acc, field: "account_number", want: "LT1234"},
+		{name: "methods off", value: acc, field: "Masked", miss: true},
+		{name: "value method", fields: Fields{Methods: true}, value: acc, field: "Masked", want: "****34"},
+		{name: "pointer method", fields: Fields{Methods: true}, value: acc, field: "Pointer", want: "ptr"},
+		{name: "pointer method on a value", fields: Fields{Methods: true}, value: *acc, field: "Pointer", miss: true},
+		{name: "method error", fields: Fields{Methods: true}, value: acc, field: "Failing", wantErr: true},
+		{name: "method with args", fields: Fields{Methods: true}, value: acc, field: "Takes", miss: true},
+		{name: "nil embedded pointer", value: &fieldsAccount{}, field: "Name", miss: true},
+		{name: "exported embedded struct by its type name", value: fieldsOrder{FieldsAddress: FieldsAddress{City: "Vilnius"}}, field: "FieldsAddress", want: FieldsAddress{City: "Vilnius"}},
+		{name: "exported embedded struct promotes too", value: fieldsOrder{FieldsAddress: FieldsAddress{City: "Vilnius"}}, field: "City", want: "Vilnius"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fields.Field(reflect.ValueOf(tt.value), tt.field)
+			switch {
+			case tt.miss:
+				if !errors.Is(err, ErrAllowsDefaultFunc) {
+					t.Fatalf("got %v, %v, want a miss", got, err)
+				}
+			case tt.wantErr:
+				if err == nil || errors.Is(err, ErrAllowsDefaultFunc) {
+					t.Fatalf("got %v, want a terminal error", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func Test_Fields_Each(t *testing.T) {
+	var names []string
+	err := Fields{Tag: "json"}.Each(reflect.ValueOf(newFieldsAccount()), func(name string, _ any) error {
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"id", "created_at", "name", "iban", "Balance"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	// an exported embedded struct is listed by its fields alone
+	names = nil
+	err = Fields{}.Each(reflect.ValueOf(fieldsOrder{}), func(name string, _ any) error {
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"City", "Number"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
@@ -41,3 +41,52 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameCurrency):    currencyModifier,
 	}
 }
+
+// Defs describes the value-formatting modifiers, in the order Modifiers lists
+// them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameFormat,
+			Group:    functions.GroupUtilities,
+			Summary:  "Formats a time with a date layout, 'Y-m-d H:i:s' when none is given. A string passes through.",
+			Params:   []functions.ParamDef{{Name: "layout", Type: "string", Optional: true}},
+			Returns:  "string",
+			Examples: []string{"{{ created_at | format:'Y-m-d' }}"},
+		},
+		{
+			Name:     ModifierNameLength,
+			Group:    functions.GroupUtilities,
+			Summary:  "Counts the characters of a string, the bytes of a byte slice, or the elements of a list or map.",
+			Returns:  "int",
+			Examples: []string{"{{ name | length }}"},
+		},
+		{
+			Name:     ModifierNameLineNumbers,
+			Group:    functions.GroupUtilities,
+			Summary:  "Prefixes each line with its line number, counting from 1 or from the given start.",
+			Params:   []functions.ParamDef{{Name: "start", Type: "int", Optional: true}},
+			Returns:  "string",
+			Examples: []string{"{{ note | line_numbers }}", "{{ snippet | line_numbers:6 }}"},
+		},
+		{
+			Name:     ModifierNameDecimal,
+			Group:    functions.GroupUtilities,
+			Summary:  "Formats a number with a fixed number of decimal places, two when none is given.",
+			Params:   []functions.ParamDef{{Name: "places", Type: "int", Optional: true}},
+			Returns:  "string",
+			Examples: []string{"{{ amount | decimal:2 }}"},
+		},
+		{
+			Name:    ModifierNameCurrency,
+			Group:   functions.GroupMoney,
+			Summary: "Converts a number between currency units by the ratio of two unit multipliers.",
+			Params: []functions.ParamDef{
+				{Name: "from", Type: "int"},
+				{Name: "to", Type: "int"},
+			},
+			Returns:  "int",
+			Examples: []string{"{{ price | currency:1,100 }}"},
+		},
+	}
+}
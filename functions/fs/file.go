@@ -1,11 +1,12 @@
-// Package fs provides a modifier that reads file contents from an allowlist.
+// Package fs provides a modifier that reads file contents from an allowlist,
+// and the loaders it and the engine find templates through.
 package fs
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
+	iofs "io/fs"
 
 	"github.com/toaweme/sintax/functions"
 )
@@ -18,63 +19,43 @@ const ModifierNameFile functions.ModifierName = "file"
 // template modifier as a partial). Unlike the plain global modifiers, `file` is
 // a security boundary. The application that wires it in supplies an allowlist of
 // safe directories that controls exactly which directories a template may read
-// from, and an empty allowlist disables file reads entirely.
-//
-// The path is resolved against each safe dir in order and the first dir that
-// contains a readable file wins. A path may only point inside a safe dir.
-// Anything that escapes via ".." is dropped, so a template author cannot read
-// siblings or parents of a safe dir. Absolute paths are not an escape hatch
-// either. They are joined onto the safe dir (so "/etc/passwd" against safe dir
-// "tpl" resolves to "tpl/etc/passwd"), never to the real root. When no safe dir
-// yields the file it returns a not-found error that deliberately does not reveal
-// whether the file existed outside the allowlist.
+// from, and an empty allowlist disables file reads entirely. It reads through
+// DirLoader, which documents how a path is resolved and sandboxed, and reports a
+// file no safe dir yields as a miss, so a default can stand in for it.
 func File(safeDirs []string) func(path string) (string, error) {
-	return func(path string) (string, error) {
-		paths, err := resolveSafePaths(path, safeDirs)
-		if err != nil {
-			return "", err
-		}
-
-		for _, full := range paths {
-			data, err := os.ReadFile(full)
-			if err != nil {
-				if os.IsNotExist(err) {
-					continue
-				}
-				return "", fmt.Errorf("failed to read file %q: %w", path, err)
-			}
-			return string(data), nil
-		}
-
-		return "", functions.Miss("failed to read file %q: %w", path, os.ErrNotExist)
-	}
+	return FileFrom(DirLoader(safeDirs...))
 }
 
-// resolveSafePaths validates path against the configured safe dirs, returning
-// the cleaned candidate paths to read (one per safe dir the path stays inside).
-// It performs no I/O. Paths that escape their safe dir via ".." are dropped, and
-// if none remain it reports a miss carrying os.ErrNotExist so a traversal
-// attempt is indistinguishable from a genuine miss.
-func resolveSafePaths(path string, safeDirs []string) (paths []string, err error) {
-	// an empty allowlist is the application declining to wire file reads at all,
-	// not a file that happens to be absent, so a default must not paper over it.
-	if len(safeDirs) == 0 {
-		return nil, fmt.Errorf("failed to read file %q: no safe directories configured", path)
+// FileFrom builds the `file` modifier over any Loader, so a template can read
+// from an embed.FS, a map or a chain of stores just as from a directory. The
+// loader is the boundary, so it alone decides what a path may reach.
+func FileFrom(loader functions.Loader) func(path string) (string, error) {
+	read := FileContextFrom(loader)
+	return func(path string) (string, error) {
+		return read(context.Background(), path)
 	}
+}
 
-	for _, dir := range safeDirs {
-		cleanDir := filepath.Clean(dir)
-		full := filepath.Clean(filepath.Join(cleanDir, path))
+// FileContext builds the context-aware `file` modifier. It reads exactly as
+// File does, but gives up once ctx is canceled or its deadline passes, checking
+// before each candidate path and between the chunks of a read, so a render
+// under RenderContext is not held hostage by a slow disk or a huge file.
+func FileContext(safeDirs []string) func(ctx context.Context, path string) (string, error) {
+	return FileContextFrom(DirLoader(safeDirs...))
+}
 
-		// reject anything that escapes the safe dir via ".."
-		if full != cleanDir && !strings.HasPrefix(full, cleanDir+string(os.PathSeparator)) {
-			continue
+// FileContextFrom is FileContext over any Loader. A loader that implements
+// functions.ContextLoader receives ctx. Any other is only checked against ctx
+// before it loads.
+func FileContextFrom(loader functions.Loader) func(ctx context.Context, path string) (string, error) {
+	return func(ctx context.Context, path string) (string, error) {
+		source, _, err := loadContext(ctx, loader, path)
+		if errors.Is(err, iofs.ErrNotExist) {
+			return "", functions.Miss("failed to read file %q: %w", path, iofs.ErrNotExist)
 		}
-		paths = append(paths, full)
-	}
-
-	if len(paths) == 0 {
-		return nil, functions.Miss("failed to read file %q: %w", path, os.ErrNotExist)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file %q: %w", path, err)
+		}
+		return source, nil
 	}
-	return paths, nil
 }
@@ -1,8 +1,11 @@
 package fs
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/toaweme/sintax/assert"
@@ -203,3 +206,33 @@ func Test_File_Modifier_ParamsAreRejected(t *testing.T) {
 	_, err := file("greeting.txt", []any{"extra"})
 	assert.ErrorIs(t, err, functions.ErrInvalidParamType)
 }
+
+func Test_FileContext_StopsWhenCanceled(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "greeting.txt"), []byte("hello"), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := FileContext([]string{dir})(ctx, "greeting.txt")
+	assert.ErrorIs(t, err, context.Canceled)
+	// a canceled read is not a miss, so a default must not paper over it
+	assert.True(t, !errors.Is(err, functions.ErrAllowsDefaultFunc), "canceled read reported as a miss")
+}
+
+// A file larger than one chunk reads back whole, since the chunked read is what
+// lets FileContext check its context part way through.
+func Test_FileContext_ReadsAcrossChunks(t *testing.T) {
+	dir := t.TempDir()
+	want := strings.Repeat("0123456789", readChunk/10+7)
+	if err := os.WriteFile(filepath.Join(dir, "big.txt"), []byte(want), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	file := ContextAwareModifiers([]string{dir})[string(ModifierNameFile)]
+	out, err := file(context.Background(), "big.txt", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, want, out)
+}
@@ -0,0 +1,209 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/toaweme/sintax/functions"
+)
+
+// DirLoader returns a Loader that reads from an allowlist of safe directories,
+// the sandbox the `file` modifier has always read through. A name is resolved
+// against each safe dir in order and the first dir that contains a readable
+// file wins. Anything that escapes a safe dir via ".." is dropped, and absolute
+// names are joined onto the safe dir (so "/etc/passwd" against safe dir "tpl"
+// resolves to "tpl/etc/passwd"), never to the real root. A miss deliberately
+// does not reveal whether the file existed outside the allowlist. With no safe
+// dirs every load fails, and not with a miss, since an empty allowlist is the
+// application declining to wire reads at all.
+//
+// The loader also implements functions.ContextLoader, checking its context
+// before each candidate path and between the chunks of a read.
+func DirLoader(safeDirs ...string) functions.ContextLoader {
+	return dirLoader(safeDirs)
+}
+
+type dirLoader []string
+
+func (l dirLoader) Load(name string) (string, time.Time, error) {
+	return l.LoadContext(context.Background(), name)
+}
+
+func (l dirLoader) LoadContext(ctx context.Context, name string) (string, time.Time, error) {
+	paths, err := resolveSafePaths(name, l)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	for _, full := range paths {
+		data, modTime, err := readFile(ctx, full)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", time.Time{}, err
+		}
+		return string(data), modTime, nil
+	}
+
+	return "", time.Time{}, notExist(name)
+}
+
+// readChunk is how much of a file readFile takes between checks of its context.
+const readChunk = 32 << 10
+
+// readFile is os.ReadFile with a cancellation check before every chunk. It also
+// reports the file's modification time.
+func readFile(ctx context.Context, name string) ([]byte, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, time.Time{}, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer f.Close()
+
+	var modTime time.Time
+	if info, err := f.Stat(); err == nil {
+		modTime = info.ModTime()
+	}
+
+	var buf bytes.Buffer
+	chunk := make([]byte, readChunk)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, time.Time{}, err
+		}
+		n, err := f.Read(chunk)
+		buf.Write(chunk[:n])
+		if errors.Is(err, io.EOF) {
+			return buf.Bytes(), modTime, nil
+		}
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+}
+
+// resolveSafePaths validates name against the configured safe dirs, returning
+// the cleaned candidate paths to read (one per safe dir the name stays inside).
+// It performs no I/O. Paths that escape their safe dir via ".." are dropped, and
+// if none remain it reports os.ErrNotExist so a traversal attempt is
+// indistinguishable from a genuine miss.
+func resolveSafePaths(name string, safeDirs []string) (paths []string, err error) {
+	if len(safeDirs) == 0 {
+		return nil, errors.New("no safe directories configured")
+	}
+
+	for _, dir := range safeDirs {
+		cleanDir := filepath.Clean(dir)
+		full := filepath.Clean(filepath.Join(cleanDir, name))
+
+		// reject anything that escapes the safe dir via ".."
+		if full != cleanDir && !strings.HasPrefix(full, cleanDir+string(os.PathSeparator)) {
+			continue
+		}
+		paths = append(paths, full)
+	}
+
+	if len(paths) == 0 {
+		return nil, notExist(name)
+	}
+	return paths, nil
+}
+
+// FSLoader returns a Loader that reads from fsys, such as an embed.FS. Names
+// are slash-separated and rooted at fsys, with a leading "/" ignored. As with
+// DirLoader, a name that escapes the root via ".." is a miss.
+func FSLoader(fsys iofs.FS) functions.Loader {
+	return fsLoader{fsys: fsys}
+}
+
+type fsLoader struct {
+	fsys iofs.FS
+}
+
+func (l fsLoader) Load(name string) (string, time.Time, error) {
+	clean := path.Clean(strings.TrimLeft(name, "/"))
+	if clean == ".." || strings.HasPrefix(clean, "../") || !iofs.ValidPath(clean) {
+		return "", time.Time{}, notExist(name)
+	}
+
+	data, err := iofs.ReadFile(l.fsys, clean)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var modTime time.Time
+	if info, err := iofs.Stat(l.fsys, clean); err == nil {
+		modTime = info.ModTime()
+	}
+	return string(data), modTime, nil
+}
+
+// MapLoader is a Loader over a map of names to sources, for tests and for
+// templates an application builds or stores itself. Names are matched exactly
+// and the modification time is always zero.
+type MapLoader map[string]string
+
+func (l MapLoader) Load(name string) (string, time.Time, error) {
+	source, ok := l[name]
+	if !ok {
+		return "", time.Time{}, notExist(name)
+	}
+	return source, time.Time{}, nil
+}
+
+// ChainLoader returns a Loader that tries loaders in order and returns the first
+// source found, so an application can let a directory of overrides shadow the
+// templates it embeds. A miss moves on to the next loader. Any other error stops
+// the chain, so a broken store is never masked by a fallback. When every loader
+// misses, so does the chain.
+//
+// The chain implements functions.ContextLoader, passing its context to the
+// loaders that take one.
+func ChainLoader(loaders ...functions.Loader) functions.ContextLoader {
+	return chainLoader(loaders)
+}
+
+type chainLoader []functions.Loader
+
+func (l chainLoader) Load(name string) (string, time.Time, error) {
+	return l.LoadContext(context.Background(), name)
+}
+
+func (l chainLoader) LoadContext(ctx context.Context, name string) (string, time.Time, error) {
+	for _, loader := range l {
+		source, modTime, err := loadContext(ctx, loader, name)
+		if errors.Is(err, iofs.ErrNotExist) {
+			continue
+		}
+		return source, modTime, err
+	}
+	return "", time.Time{}, notExist(name)
+}
+
+// loadContext loads name through loader, under ctx when the loader takes one.
+func loadContext(ctx context.Context, loader functions.Loader, name string) (string, time.Time, error) {
+	if cl, ok := loader.(functions.ContextLoader); ok {
+		return cl.LoadContext(ctx, name)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", time.Time{}, err
+	}
+	return loader.Load(name)
+}
+
+// notExist is the miss a loader reports for name, shaped like the one opening a
+// missing file reports.
+func notExist(name string) error {
+	return &iofs.PathError{Op: "open", Path: name, Err: iofs.ErrNotExist}
+}
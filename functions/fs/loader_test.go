@@ -0,0 +1,140 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions"
+)
+
+func Test_DirLoader_ReportsModTime(t *testing.T) {
+	dir := t.TempDir()
+	full := filepath.Join(dir, "page.tpl")
+	if err := os.WriteFile(full, []byte("page"), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	when := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(full, when, when); err != nil {
+		t.Fatalf("failed to set mod time: %v", err)
+	}
+
+	source, modTime, err := DirLoader(dir).Load("page.tpl")
+	assert.NoError(t, err)
+	assert.Equal(t, "page", source)
+	assert.True(t, modTime.Equal(when), "got mod time %v, want %v", modTime, when)
+
+	_, _, err = DirLoader(dir).Load("../page.tpl")
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+}
+
+func Test_FSLoader(t *testing.T) {
+	when := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"layouts/base.tpl": {Data: []byte("base"), ModTime: when},
+	}
+
+	testCases := []struct {
+		name    string
+		path    string
+		want    string
+		missing bool
+	}{
+		{name: "nested name", path: "layouts/base.tpl", want: "base"},
+		{name: "leading slash is ignored", path: "/layouts/base.tpl", want: "base"},
+		{name: "traversal that stays inside is kept", path: "layouts/../layouts/base.tpl", want: "base"},
+		{name: "missing name", path: "layouts/nope.tpl", missing: true},
+		{name: "escape is a miss", path: "../layouts/base.tpl", missing: true},
+	}
+
+	loader := FSLoader(fsys)
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			source, modTime, err := loader.Load(tt.path)
+			if tt.missing {
+				assert.ErrorIs(t, err, iofs.ErrNotExist)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, source)
+			assert.True(t, modTime.Equal(when), "got mod time %v, want %v", modTime, when)
+		})
+	}
+}
+
+func Test_MapLoader(t *testing.T) {
+	loader := MapLoader{"a.tpl": "A"}
+
+	source, modTime, err := loader.Load("a.tpl")
+	assert.NoError(t, err)
+	assert.Equal(t, "A", source)
+	assert.True(t, modTime.IsZero(), "got mod time %v, want zero", modTime)
+
+	_, _, err = loader.Load("b.tpl")
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+}
+
+// failingLoader fails every load with err.
+type failingLoader struct{ err error }
+
+func (l failingLoader) Load(string) (string, time.Time, error) {
+	return "", time.Time{}, l.err
+}
+
+func Test_ChainLoader(t *testing.T) {
+	overrides := MapLoader{"a.tpl": "override"}
+	embedded := MapLoader{"a.tpl": "embedded", "b.tpl": "embedded b"}
+
+	loader := ChainLoader(overrides, embedded)
+
+	source, _, err := loader.Load("a.tpl")
+	assert.NoError(t, err)
+	assert.Equal(t, "override", source)
+
+	source, _, err = loader.Load("b.tpl")
+	assert.NoError(t, err)
+	assert.Equal(t, "embedded b", source)
+
+	_, _, err = loader.Load("c.tpl")
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+
+	// a broken loader stops the chain rather than falling back past it
+	broken := errors.New("store is down")
+	_, _, err = ChainLoader(failingLoader{err: broken}, embedded).Load("a.tpl")
+	assert.ErrorIs(t, err, broken)
+
+	_, _, err = ChainLoader().Load("a.tpl")
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+}
+
+func Test_ChainLoader_StopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := ChainLoader(MapLoader{"a.tpl": "A"}).LoadContext(ctx, "a.tpl")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_FileFrom_ReadsThroughLoader(t *testing.T) {
+	read := FileFrom(MapLoader{"greeting.txt": "hello from a map"})
+
+	out, err := read("greeting.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from a map", out)
+
+	// a name the loader lacks is a miss a default can stand in for
+	_, err = read("nope.txt")
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+	assert.ErrorIs(t, err, functions.ErrAllowsDefaultFunc)
+
+	// any other failure is not
+	_, err = FileFrom(failingLoader{err: errors.New("store is down")})("greeting.txt")
+	assert.Error(t, err)
+	assert.True(t, !errors.Is(err, functions.ErrAllowsDefaultFunc), "failed load reported as a miss")
+}
@@ -12,3 +12,43 @@ func Modifiers(safeDirs []string) map[string]functions.GlobalModifier {
 		string(ModifierNameFile): functions.Wrap(File(safeDirs)),
 	}
 }
+
+// ContextAwareModifiers returns the filesystem modifiers that honor the render's
+// context, keyed by their template names. Its `file` reads exactly as the one
+// Modifiers returns but stops once a RenderContext deadline passes. Register it
+// after Modifiers, so it takes the name over.
+func ContextAwareModifiers(safeDirs []string) map[string]functions.ContextAwareModifier {
+	return map[string]functions.ContextAwareModifier{
+		string(ModifierNameFile): functions.WrapContext(FileContext(safeDirs)),
+	}
+}
+
+// ModifiersFrom is Modifiers with `file` reading through loader instead of a
+// directory allowlist.
+func ModifiersFrom(loader functions.Loader) map[string]functions.GlobalModifier {
+	return map[string]functions.GlobalModifier{
+		string(ModifierNameFile): functions.Wrap(FileFrom(loader)),
+	}
+}
+
+// ContextAwareModifiersFrom is ContextAwareModifiers with `file` reading through
+// loader instead of a directory allowlist.
+func ContextAwareModifiersFrom(loader functions.Loader) map[string]functions.ContextAwareModifier {
+	return map[string]functions.ContextAwareModifier{
+		string(ModifierNameFile): functions.WrapContext(FileContextFrom(loader)),
+	}
+}
+
+// Defs describes the filesystem modifiers. Every constructor above registers
+// the same `file`, so they share one description.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameFile,
+			Group:    functions.GroupFileSystem,
+			Summary:  "Reads a file's contents as a string, from the allowlisted directories or the loader it was built over.",
+			Returns:  "string",
+			Examples: []string{`{{ "greeting.tpl" | file }}`, `{{ "partial.tpl" | file | template }}`},
+		},
+	}
+}
@@ -0,0 +1,30 @@
+package functions
+
+import (
+	"context"
+	"time"
+)
+
+// Loader finds a template's source by name, for the tags that refer to another
+// template, such as a from-import reading macros out of a shared file, and for
+// the `file` modifier. The fs modifier package implements it over a directory
+// allowlist, an io/fs.FS, a map and a chain of other loaders. It lives here, in
+// the shared base package, so a modifier package can take one without importing
+// the engine.
+//
+// modTime is when the source last changed, or the zero time when the loader
+// cannot tell. The engine reuses the template it parsed from a name while the
+// loader returns the same source for it, whatever the modTime. A name the loader does not have is reported with an error
+// wrapping fs.ErrNotExist, so a caller can tell a missing template from one that
+// failed to load.
+type Loader interface {
+	Load(name string) (source string, modTime time.Time, err error)
+}
+
+// ContextLoader is a Loader that can give up on a load once ctx is done, so a
+// slow store does not outlast a render's deadline. The engine and the `file`
+// modifier use LoadContext when a loader offers it.
+type ContextLoader interface {
+	Loader
+	LoadContext(ctx context.Context, name string) (source string, modTime time.Time, err error)
+}
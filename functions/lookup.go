@@ -0,0 +1,129 @@
+package functions
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Lookup reads exactly one level out of value: a map entry by key, a slice or
+// array element by index, or an exported struct field by its Go name, looking
+// through pointers and interfaces on the way. It is the single step a variable path such
+// as `user.address.city` or `items[0]` takes per segment.
+//
+// A string key is taken whole and never split on dots, so a map key that itself
+// contains a dot stays reachable. A string key on a slice is read as an index.
+//
+// Every way of finding nothing is a Miss: a missing key or field, an
+// out-of-range index, a nil along the way, and a value that cannot be looked
+// into at all, which in a path means the data does not nest that deep.
+func Lookup(value any, key any) (any, error) {
+	return Fields{}.Lookup(value, key)
+}
+
+// Lookup is the package Lookup with struct members resolved through f.
+func (f Fields) Lookup(value any, key any) (any, error) {
+	rv := reflect.ValueOf(value)
+	// holder is the last pointer on the way in, kept so a struct's pointer
+	// receiver methods stay reachable
+	holder := rv
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, Miss("%s has nothing to look in", describeKey(key))
+		}
+		if rv.Kind() == reflect.Pointer {
+			holder = rv
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, Miss("%s has nothing to look in", describeKey(key))
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		keyValue, ok := MapKey(key, rv.Type().Key())
+		if !ok {
+			return nil, Miss("%s cannot exist in a map keyed by %v", describeKey(key), rv.Type().Key())
+		}
+		found := rv.MapIndex(keyValue)
+		if !found.IsValid() {
+			return nil, Miss("%s not found", describeKey(key))
+		}
+		return found.Interface(), nil
+	case reflect.Slice, reflect.Array:
+		index, ok := ValueInt(key)
+		if s, isString := key.(string); isString {
+			n, err := strconv.Atoi(s)
+			index, ok = n, err == nil
+		}
+		if !ok {
+			return nil, Miss("%s is not an index into a slice", describeKey(key))
+		}
+		if index < 0 || index >= rv.Len() {
+			return nil, Miss("index %d is outside the slice's %d element(s)", index, rv.Len())
+		}
+		return rv.Index(index).Interface(), nil
+	case reflect.Struct:
+		name, ok := key.(string)
+		if !ok {
+			return nil, Miss("%s is not a field name", describeKey(key))
+		}
+		if holder.Kind() != reflect.Pointer {
+			holder = rv
+		}
+		return f.Field(holder, name)
+	default:
+	}
+	return nil, Miss("%s cannot be looked up in a %T", describeKey(key), value)
+}
+
+// MapKey converts key to a map's key type, reporting false when no key of that
+// type could match it. Keys parse from their string form, so the segment "2" in
+// a path, or the key modifier's '2', reaches the entry 2 of a map keyed by int,
+// and "1.5" the entry 1.5 of one keyed by float64. Every lookup by key goes
+// through it, so a path and the key modifier reach the same entries.
+func MapKey(key any, keyType reflect.Type) (reflect.Value, bool) {
+	kv := reflect.ValueOf(key)
+	if kv.IsValid() && kv.Type().AssignableTo(keyType) {
+		return kv, true
+	}
+	s := fmt.Sprint(key)
+	switch keyType.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(s).Convert(keyType), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || reflect.Zero(keyType).OverflowInt(n) {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(n).Convert(keyType), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || reflect.Zero(keyType).OverflowUint(n) {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(n).Convert(keyType), true
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || reflect.Zero(keyType).OverflowFloat(n) {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(n).Convert(keyType), true
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(b).Convert(keyType), true
+	default:
+	}
+	return reflect.Value{}, false
+}
+
+func describeKey(key any) string {
+	if s, ok := key.(string); ok {
+		return fmt.Sprintf("key %q", s)
+	}
+	return fmt.Sprintf("index %v", key)
+}
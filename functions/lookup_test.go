@@ -0,0 +1,62 @@
+package functions
+
+import (
+	"errors"
+	"testing"
+)
+
+type keyName string
+
+func Test_Lookup(t *testing.T) {
+	type inner struct{ City string }
+	type outer struct {
+		*inner
+		Name    string
+		private string
+	}
+	name := "x"
+
+	tests := []struct {
+		name  string
+		value any
+		key   any
+		want  any
+		miss  bool
+	}{
+		{name: "map key", value: map[string]any{"a": 1}, key: "a", want: 1},
+		{name: "dotted key taken whole", value: map[string]any{"a.b": 2}, key: "a.b", want: 2},
+		{name: "missing key", value: map[string]any{}, key: "a", miss: true},
+		{name: "int keyed map", value: map[int]string{2: "two"}, key: "2", want: "two"},
+		{name: "key that cannot exist", value: map[int]string{}, key: "x", miss: true},
+		{name: "float keyed map", value: map[float64]string{1.5: "one and a half"}, key: "1.5", want: "one and a half"},
+		{name: "float key from a number", value: map[float32]string{2: "two"}, key: 2, want: "two"},
+		{name: "named string keyed map", value: map[keyName]int{"a": 1}, key: "a", want: 1},
+		{name: "slice index", value: []string{"a", "b"}, key: 1, want: "b"},
+		{name: "slice index as string", value: []string{"a", "b"}, key: "0", want: "a"},
+		{name: "index out of range", value: []string{"a"}, key: 3, miss: true},
+		{name: "struct field", value: outer{Name: "n"}, key: "Name", want: "n"},
+		{name: "pointer to struct", value: &outer{Name: "p"}, key: "Name", want: "p"},
+		{name: "unexported field", value: outer{private: "s"}, key: "private", miss: true},
+		{name: "promoted field", value: outer{inner: &inner{City: "V"}}, key: "City", want: "V"},
+		{name: "promoted through nil pointer", value: outer{}, key: "City", miss: true},
+		{name: "nil", value: nil, key: "a", miss: true},
+		{name: "scalar", value: &name, key: "a", miss: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Lookup(tt.value, tt.key)
+			if tt.miss {
+				if !errors.Is(err, ErrAllowsDefaultFunc) {
+					t.Fatalf("got %v, %v, want a miss", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
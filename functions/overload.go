@@ -3,6 +3,7 @@ package functions
 import (
 	"errors"
 	"fmt"
+	"slices"
 )
 
 // Overload binds one modifier name to several typed clauses, for the modifiers
@@ -24,7 +25,24 @@ import (
 // mismatch in preference to a value mismatch, so the caller sees the most
 // informative error rather than the last one to occur.
 func Overload(clauses ...GlobalModifier) GlobalModifier {
-	return func(value any, params []any) (any, error) {
+	return answering(func(value any, params []any) (any, error) {
+		if probe, ok := value.(*arityProbe); ok {
+			// the overload accepts what any clause accepts, and knows it only
+			// when every clause does
+			var arities Arities
+			for _, clause := range clauses {
+				as, ok := ArityOf(clause)
+				if !ok {
+					return nil, nil
+				}
+				for _, a := range as {
+					if !slices.Contains(arities, a) {
+						arities = append(arities, a)
+					}
+				}
+			}
+			return probe.answer(arities...)
+		}
 		// track value-shape and param rejections separately. A param rejection
 		// means a clause accepted the value but its params did not fit, which is
 		// more informative than a bare value mismatch, so it wins when both occur
@@ -59,5 +77,5 @@ func Overload(clauses ...GlobalModifier) GlobalModifier {
 			reject = ErrInvalidValueType
 		}
 		return nil, fmt.Errorf("no overload accepts a %T value with %d param(s): %w", value, len(params), reject)
-	}
+	})
 }
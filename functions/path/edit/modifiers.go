@@ -15,3 +15,25 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameFilenameTrimExt):    extTrimModifier,
 	}
 }
+
+// Defs describes the path extension-rewriting modifiers, in the order
+// Modifiers lists them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameFilenamePrependExt,
+			Group:    functions.GroupFileSystem,
+			Summary:  "Inserts an extension before the path's existing one.",
+			Params:   []functions.ParamDef{{Name: "ext", Type: "string"}},
+			Returns:  "string",
+			Examples: []string{"{{ file_path | ext_prepend:'min' }}"},
+		},
+		{
+			Name:     ModifierNameFilenameTrimExt,
+			Group:    functions.GroupFileSystem,
+			Summary:  "Returns the path without its extension.",
+			Returns:  "string",
+			Examples: []string{"{{ file_path | ext_trim }}"},
+		},
+	}
+}
@@ -18,3 +18,38 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameFilenameExtDot): extDotModifier,
 	}
 }
+
+// Defs describes the path-reading modifiers, in the order Modifiers lists
+// them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameDirname,
+			Group:    functions.GroupFileSystem,
+			Summary:  "Returns the directory portion of a path.",
+			Returns:  "string",
+			Examples: []string{"{{ file_path | dirname }}"},
+		},
+		{
+			Name:     ModifierNameFilename,
+			Group:    functions.GroupFileSystem,
+			Summary:  "Returns the last element of a path, extension included.",
+			Returns:  "string",
+			Examples: []string{"{{ file_path | filename }}"},
+		},
+		{
+			Name:     ModifierNameFilenameExt,
+			Group:    functions.GroupFileSystem,
+			Summary:  "Returns the path's extension without the leading dot.",
+			Returns:  "string",
+			Examples: []string{"{{ file_path | ext }}"},
+		},
+		{
+			Name:     ModifierNameFilenameExtDot,
+			Group:    functions.GroupFileSystem,
+			Summary:  "Returns the path's extension with the leading dot.",
+			Returns:  "string",
+			Examples: []string{"{{ file_path | ext_dot }}"},
+		},
+	}
+}
@@ -10,3 +10,18 @@ func ContextualModifiers() map[string]functions.ContextualModifier {
 		string(ModifierNameTemplate): Template,
 	}
 }
+
+// Defs describes the render-state-aware modifiers, in the order
+// ContextualModifiers lists them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameTemplate,
+			Group:    functions.GroupText,
+			Summary:  "Renders a string value as a nested template, against the given map alone when one is passed.",
+			Params:   []functions.ParamDef{{Name: "vars", Type: "map[string]any", Optional: true}},
+			Returns:  "any",
+			Examples: []string{`{{ "partial.tpl" | file | template }}`, "{{ body | template:scope }}"},
+		},
+	}
+}
@@ -1,6 +1,46 @@
 package functions
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+)
+
+// SafeString is text already fit for the markup it lands in, which an engine
+// with auto-escaping on writes out as is. The safe modifier makes one from a
+// value the template author vouches for, and the engine makes one of what a
+// macro, a capture block or a nested template renders, since that output was
+// escaped as it was written, and of what a modifier decorated with AsMarkup
+// returns. Modifiers that take a string take a SafeString too, and give back a
+// plain string, which is escaped again.
+type SafeString string
+
+// AsMarkup decorates a modifier whose string result is already markup, such as
+// escape_html, so an engine with auto-escaping on takes the result as a
+// SafeString rather than escaping it a second time. The modifier itself is run
+// unchanged and still returns a plain string, so with auto-escaping off, or
+// called directly, nothing about its result differs. An engine that escapes
+// for context keeps the result as is only where HTML escaping is enough, in
+// text and quoted attributes, and escapes it again for a URL or a script. Like AsText it composes
+// with Wrap and Overload, and ArityOf sees through it.
+func AsMarkup(mod GlobalModifier) GlobalModifier {
+	return answering(func(value any, params []any) (any, error) {
+		if probe, ok := value.(*arityProbe); ok {
+			if as, ok := ArityOf(mod); ok {
+				return probe.answer(as...)
+			}
+			return nil, nil
+		}
+		return mod(value, params)
+	})
+}
+
+// markupCode is the code every modifier AsMarkup returns shares.
+var markupCode = reflect.ValueOf(AsMarkup(nil)).Pointer()
+
+// IsMarkup reports whether fn was decorated with AsMarkup.
+func IsMarkup(fn GlobalModifier) bool {
+	return fn != nil && reflect.ValueOf(fn).Pointer() == markupCode
+}
 
 // AsText decorates a modifier so a scalar value - a number or a bool - is handed
 // to it as its default string form, for text-first modifiers where 42 should
@@ -11,7 +51,14 @@ import "fmt"
 // and runs before the wrapped modifier, it composes with Wrap, Overload, and any
 // other GlobalModifier, and it leaves value-type dispatch strict.
 func AsText(mod GlobalModifier) GlobalModifier {
-	return func(value any, params []any) (any, error) {
+	return answering(func(value any, params []any) (any, error) {
+		if probe, ok := value.(*arityProbe); ok {
+			// the decorated modifier decides, if it knows
+			if as, ok := ArityOf(mod); ok {
+				return probe.answer(as...)
+			}
+			return nil, nil
+		}
 		// a string is already the form the modifier wants, and reassigning it
 		// would box an identical string into a fresh any, allocating to replace
 		// a value with itself. strings are the common path here, so skip it.
@@ -22,7 +69,7 @@ func AsText(mod GlobalModifier) GlobalModifier {
 			value = s
 		}
 		return mod(value, params)
-	}
+	})
 }
 
 // Stringish returns the default string form of a scalar primitive (a string,
@@ -26,3 +26,46 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameModelTitle): titleModelModifier,
 	}
 }
+
+// Defs describes the case-transformation and slug modifiers, in the order
+// Modifiers lists them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameToLower,
+			Group:    functions.GroupText,
+			Summary:  "Converts a string to lowercase.",
+			Returns:  "string",
+			Examples: []string{"{{ email | lower }}"},
+		},
+		{
+			Name:     ModifierNameToUpper,
+			Group:    functions.GroupText,
+			Summary:  "Converts a string to uppercase.",
+			Returns:  "string",
+			Examples: []string{"{{ name | upper }}"},
+		},
+		{
+			Name:     ModifierNameSlug,
+			Group:    functions.GroupText,
+			Summary:  "Converts a string to a URL-friendly slug.",
+			Returns:  "string",
+			Examples: []string{"{{ title | slug }}"},
+		},
+		{
+			Name:     ModifierNameTitle,
+			Group:    functions.GroupText,
+			Summary:  "Converts a hyphen-separated slug into a title, uppercasing known and given acronyms.",
+			Params:   []functions.ParamDef{{Name: "acronyms", Type: "string", Variadic: true}},
+			Returns:  "string",
+			Examples: []string{"{{ slug | title }}", "{{ slug | title:'SDK' }}"},
+		},
+		{
+			Name:     ModifierNameModelTitle,
+			Group:    functions.GroupText,
+			Summary:  "Formats an AI model identifier into a human-readable title.",
+			Returns:  "string",
+			Examples: []string{"{{ model_id | title_model }}"},
+		},
+	}
+}
@@ -32,3 +32,63 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameWrap):           wrapModifier,
 	}
 }
+
+// Defs describes the string-editing modifiers, in the order Modifiers lists
+// them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameShorten,
+			Group:    functions.GroupText,
+			Summary:  "Truncates a string to the given maximum character length.",
+			Params:   []functions.ParamDef{{Name: "length", Type: "int"}},
+			Returns:  "string",
+			Examples: []string{"{{ description | shorten:30 }}"},
+		},
+		{
+			Name:     ModifierNameConcat,
+			Group:    functions.GroupText,
+			Summary:  "Appends one or more strings to the value.",
+			Params:   []functions.ParamDef{{Name: "parts", Type: "string", Variadic: true}},
+			Returns:  "string",
+			Examples: []string{"{{ greeting | concat:'!' }}", "{{ first | concat:' ',last }}"},
+		},
+		{
+			Name:    ModifierNameReplace,
+			Group:   functions.GroupText,
+			Summary: "Replaces every occurrence of a substring.",
+			Params: []functions.ParamDef{
+				{Name: "old", Type: "string"},
+				{Name: "new", Type: "string"},
+			},
+			Returns:  "string",
+			Examples: []string{"{{ greeting | replace:'world','everyone' }}"},
+		},
+		{
+			Name:    ModifierNameReplacePattern,
+			Group:   functions.GroupText,
+			Summary: "Replaces every match of a regular expression.",
+			Params: []functions.ParamDef{
+				{Name: "pattern", Type: "string"},
+				{Name: "replacement", Type: "string"},
+			},
+			Returns:  "string",
+			Examples: []string{`{{ text | replace_pattern:'\s+',' ' }}`},
+		},
+		{
+			Name:     ModifierNameReverse,
+			Group:    functions.GroupText,
+			Summary:  "Reverses the characters of a string.",
+			Returns:  "string",
+			Examples: []string{"{{ name | reverse }}"},
+		},
+		{
+			Name:     ModifierNameWrap,
+			Group:    functions.GroupCollections,
+			Summary:  "Wraps the value in a map under the given key.",
+			Params:   []functions.ParamDef{{Name: "key", Type: "string"}},
+			Returns:  "map[string]any",
+			Examples: []string{"{{ name | wrap:'user' | json }}"},
+		},
+	}
+}
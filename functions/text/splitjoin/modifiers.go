@@ -28,3 +28,33 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameSplit): splitModifier,
 	}
 }
+
+// Defs describes the split and join modifiers, in the order Modifiers lists
+// them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameLines,
+			Group:    functions.GroupText,
+			Summary:  "Splits a string into its lines.",
+			Returns:  "[]string",
+			Examples: []string{"{{ note | lines }}"},
+		},
+		{
+			Name:     ModifierNameJoin,
+			Group:    functions.GroupText,
+			Summary:  "Joins a list of strings with a separator, a newline when none is given.",
+			Params:   []functions.ParamDef{{Name: "separator", Type: "string", Optional: true}},
+			Returns:  "string",
+			Examples: []string{"{{ tags | join:',' }}", "{{ lines | join }}"},
+		},
+		{
+			Name:     ModifierNameSplit,
+			Group:    functions.GroupText,
+			Summary:  "Splits a string into a list on a separator.",
+			Params:   []functions.ParamDef{{Name: "separator", Type: "string"}},
+			Returns:  "[]string",
+			Examples: []string{"{{ csv_line | split:',' }}"},
+		},
+	}
+}
@@ -36,3 +36,33 @@ func Modifiers() map[string]functions.GlobalModifier {
 		string(ModifierNameTrimSuffix): trimSuffixModifier,
 	}
 }
+
+// Defs describes the trimming modifiers, in the order Modifiers lists them.
+func Defs() []functions.ModifierDef {
+	return []functions.ModifierDef{
+		{
+			Name:     ModifierNameTrim,
+			Group:    functions.GroupText,
+			Summary:  "Removes leading and trailing whitespace, or the characters of the given set.",
+			Params:   []functions.ParamDef{{Name: "cutset", Type: "string", Optional: true}},
+			Returns:  "string",
+			Examples: []string{"{{ name | trim }}", "{{ code | trim:'0' }}"},
+		},
+		{
+			Name:     ModifierNameTrimPrefix,
+			Group:    functions.GroupText,
+			Summary:  "Removes a leading prefix, or leading whitespace when none is given.",
+			Params:   []functions.ParamDef{{Name: "prefix", Type: "string", Optional: true}},
+			Returns:  "string",
+			Examples: []string{"{{ path | trim_prefix:'/' }}"},
+		},
+		{
+			Name:     ModifierNameTrimSuffix,
+			Group:    functions.GroupText,
+			Summary:  "Removes a trailing suffix, or trailing whitespace when none is given.",
+			Params:   []functions.ParamDef{{Name: "suffix", Type: "string", Optional: true}},
+			Returns:  "string",
+			Examples: []string{"{{ url | trim_suffix:'/' }}"},
+		},
+	}
+}
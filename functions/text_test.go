@@ -61,3 +61,20 @@ func Test_AsText_LeavesParamsAndArity(t *testing.T) {
 		t.Fatalf("expected arity rejection, got %v", err)
 	}
 }
+
+// Test_AsMarkup confirms AsMarkup leaves the result alone and only marks the
+// modifier for an engine to recognize.
+func Test_AsMarkup(t *testing.T) {
+	plain := Wrap(func(s string) (string, error) { return "<b>" + s + "</b>", nil })
+	mod := AsMarkup(plain)
+	out, err := mod("x", nil)
+	if err != nil || out != "<b>x</b>" {
+		t.Fatalf("expected the plain result, got %#v, %v", out, err)
+	}
+	if !IsMarkup(mod) || IsMarkup(plain) || IsMarkup(AsText(plain)) || IsMarkup(nil) {
+		t.Fatal("IsMarkup did not tell the decorated modifier apart")
+	}
+	if as, ok := ArityOf(mod); !ok || as.String() != "0" {
+		t.Fatalf("ArityOf = %v, %v", as, ok)
+	}
+}
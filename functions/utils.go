@@ -10,8 +10,12 @@ import (
 
 // ValueString asserts the value is a string, returning ErrInvalidValueType otherwise.
 func ValueString(v any) (string, error) {
-	if vv, ok := v.(string); ok {
+	switch vv := v.(type) {
+	case string:
 		return vv, nil
+	case SafeString:
+		return string(vv), nil
+	default:
 	}
 	return "", fmt.Errorf("%w: expected string, got %T", ErrInvalidValueType, v)
 }
@@ -203,6 +207,8 @@ func ConditionIsTrue(condition any) bool {
 			return true
 		}
 		return len(v) > 0
+	case SafeString:
+		return ConditionIsTrue(string(v))
 	case int:
 		return v > 0
 	case int8:
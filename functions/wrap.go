@@ -1,6 +1,9 @@
 package functions
 
-import "reflect"
+import (
+	"context"
+	"reflect"
+)
 
 // The Wrap family adapts a typed modifier into the engine's untyped
 // GlobalModifier signature. A modifier author writes a plain, testable function
@@ -24,13 +27,19 @@ import "reflect"
 // string argument. The concrete detail (which value, which type) is added once,
 // lazily, by whoever surfaces the failure - Overload wraps its terminal
 // no-clause-matched error with the value type and param count.
+//
+// Every Wrap also answers ArityOf with the param count it accepts, so the
+// engine can check a call's arguments before rendering it.
 
 // Wrap adapts a no-parameter typed modifier, func(In) (Out, error). It matches
 // its arity exactly: a clause that declares no params rejects a call that passes
 // any, so under Overload a stray or mistyped argument falls through to another
 // clause (or surfaces as an error) rather than being silently ignored.
 func Wrap[In, Out any](fn func(In) (Out, error)) GlobalModifier {
-	return func(value any, params []any) (any, error) {
+	return answering(func(value any, params []any) (any, error) {
+		if probe, ok := value.(*arityProbe); ok {
+			return probe.answer(Arity{0, 0})
+		}
 		if len(params) > 0 {
 			return nil, ErrInvalidParamType
 		}
@@ -39,13 +48,16 @@ func Wrap[In, Out any](fn func(In) (Out, error)) GlobalModifier {
 			return nil, ErrInvalidValueType
 		}
 		return fn(in)
-	}
+	})
 }
 
 // WrapOne adapts a one-parameter typed modifier, func(In, P0) (Out, error). It
 // matches exactly one param, rejecting a call that passes more.
 func WrapOne[In, P0, Out any](fn func(In, P0) (Out, error)) GlobalModifier {
-	return func(value any, params []any) (any, error) {
+	return answering(func(value any, params []any) (any, error) {
+		if probe, ok := value.(*arityProbe); ok {
+			return probe.answer(Arity{1, 1})
+		}
 		if len(params) > 1 {
 			return nil, ErrInvalidParamType
 		}
@@ -58,13 +70,16 @@ func WrapOne[In, P0, Out any](fn func(In, P0) (Out, error)) GlobalModifier {
 			return nil, err
 		}
 		return fn(in, p0)
-	}
+	})
 }
 
 // WrapTwo adapts a two-parameter typed modifier, func(In, P0, P1) (Out, error).
 // It matches exactly two params, rejecting a call that passes more.
 func WrapTwo[In, P0, P1, Out any](fn func(In, P0, P1) (Out, error)) GlobalModifier {
-	return func(value any, params []any) (any, error) {
+	return answering(func(value any, params []any) (any, error) {
+		if probe, ok := value.(*arityProbe); ok {
+			return probe.answer(Arity{2, 2})
+		}
 		if len(params) > 2 {
 			return nil, ErrInvalidParamType
 		}
@@ -81,13 +96,35 @@ func WrapTwo[In, P0, P1, Out any](fn func(In, P0, P1) (Out, error)) GlobalModifi
 			return nil, err
 		}
 		return fn(in, p0, p1)
-	}
+	})
+}
+
+// WrapContext adapts a no-parameter typed modifier that takes the render's
+// context, func(context.Context, In) (Out, error), into a ContextAwareModifier.
+// It coerces and rejects exactly as Wrap does.
+func WrapContext[In, Out any](fn func(context.Context, In) (Out, error)) ContextAwareModifier {
+	return answering(func(ctx context.Context, value any, params []any) (any, error) {
+		if probe, ok := value.(*arityProbe); ok {
+			return probe.answer(Arity{0, 0})
+		}
+		if len(params) > 0 {
+			return nil, ErrInvalidParamType
+		}
+		in, ok := coerce[In](value)
+		if !ok {
+			return nil, ErrInvalidValueType
+		}
+		return fn(ctx, in)
+	})
 }
 
 // WrapVariadic adapts a variadic typed modifier, func(In, ...P) (Out, error),
 // where every param shares the type P.
 func WrapVariadic[In, P, Out any](fn func(In, ...P) (Out, error)) GlobalModifier {
-	return func(value any, params []any) (any, error) {
+	return answering(func(value any, params []any) (any, error) {
+		if probe, ok := value.(*arityProbe); ok {
+			return probe.answer(Arity{0, -1})
+		}
 		in, ok := coerce[In](value)
 		if !ok {
 			return nil, ErrInvalidValueType
@@ -101,7 +138,7 @@ func WrapVariadic[In, P, Out any](fn func(In, ...P) (Out, error)) GlobalModifier
 			ps[i] = p
 		}
 		return fn(in, ps...)
-	}
+	})
 }
 
 // coerce converts an untyped value to T. It first tries a direct assertion, then
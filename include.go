@@ -0,0 +1,203 @@
+package sintax
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// includeSpec is what an include tag asks for: the template to render, the
+// variables to pass it, and whether it sees only those.
+type includeSpec struct {
+	// name is the expression naming the template, usually a quoted literal.
+	name exprNode
+	// with is the map of variables to pass, or nil when the tag passes none.
+	with exprNode
+	// only isolates the included template from the including one's variables.
+	only bool
+	// ignoreMissing renders nothing for a template the loader does not have.
+	ignoreMissing bool
+}
+
+// renderInclude renders the template an include tag names, through the
+// engine's loader, into out. It sees a copy of the including template's
+// variables with the tag's `with` map laid over them, or with `only` just that
+// map, and nothing it sets leaks back. Loading it counts as a level of nesting,
+// so a template that includes itself stops at ErrMaxDepthExceeded.
+func (r *TokenRenderer) renderInclude(out *output, token Token, vars map[string]any) error {
+	spec, err := includeSpecOf(r.parser, token)
+	if err != nil {
+		return err
+	}
+	nameValue, err := spec.name.eval(r, vars)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate include name: %w", err)
+	}
+	name, ok := nameValue.(string)
+	if !ok {
+		return fmt.Errorf("include takes a template name, got %T", nameValue)
+	}
+	if r.depth+1 > r.maxDepth {
+		return fmt.Errorf("failed to include %q: %w", name, ErrMaxDepthExceeded)
+	}
+
+	tokens, err := r.load(name)
+	if err != nil {
+		if spec.ignoreMissing && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to include %q: %w", name, err)
+	}
+
+	scope := map[string]any{}
+	if !spec.only {
+		scope = childScope(vars)
+	}
+	if spec.with != nil {
+		with, err := spec.with.eval(r, vars)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate variables for %q: %w", name, err)
+		}
+		m, ok := with.(map[string]any)
+		if !ok {
+			return fmt.Errorf("include %q with takes a map of variables, got %T", name, with)
+		}
+		maps.Copy(scope, m)
+	}
+
+	child := *r
+	child.depth = r.depth + 1
+	child.name = name
+	child.includes = append(slices.Clip(r.includes), IncludeFrame{Name: r.name, Line: tokenPos(token).Line})
+	child.layout, child.block = nil, nil
+	if err := child.define(tokens); err != nil {
+		return fmt.Errorf("failed to include %q: %w", name, err)
+	}
+	return child.renderTemplate(out, tokens, scope)
+}
+
+// traced marks err, raised by tok, with the chain of includes the render is
+// inside. The innermost range to see an error marks it, so tok is the tag that
+// failed, and the ranges around it pass it on untouched. A break or continue is
+// not a failure and passes through as is.
+func (r *TokenRenderer) traced(err error, tok Token) error {
+	var ie *IncludeError
+	var ls *loopSignal
+	if len(r.includes) == 0 || errors.As(err, &ie) || errors.As(err, &ls) {
+		return err
+	}
+	return &IncludeError{
+		Chain: append(slices.Clip(r.includes), IncludeFrame{Name: r.name, Line: tokenPos(tok).Line}),
+		Err:   err,
+	}
+}
+
+// includeSpecOf returns what an IncludeToken asks for, preferring the parse
+// cached at parse time.
+func includeSpecOf(p *StringParser, token Token) (*includeSpec, error) {
+	if bt, ok := token.(BaseToken); ok && bt.parsedInclude != nil {
+		return bt.parsedInclude, nil
+	}
+	spec, err := p.parseInclude(token.Raw())
+	if err != nil {
+		return nil, fmt.Errorf("invalid include %q: %s", token.Raw(), err.msg)
+	}
+	return spec, nil
+}
+
+// parseInclude parses the contents of an include tag,
+// `include name [ignore missing] [with vars] [only]`. Offsets in errors are
+// relative to s, trimmed.
+func (p *StringParser) parseInclude(s string) (*includeSpec, *parseError) {
+	s = strings.TrimSpace(s)
+	rest := strings.TrimPrefix(s, "include")
+	base := len(s) - len(rest)
+	spec := &includeSpec{}
+
+	if w := topLevelWord(rest, "only"); w >= 0 {
+		if strings.TrimSpace(rest[w+len("only"):]) != "" {
+			return nil, errAt(base+w, "only must come last in an include")
+		}
+		spec.only = true
+		rest = rest[:w]
+	}
+
+	nameText := rest
+	if w := topLevelWord(rest, "with"); w >= 0 {
+		raw := rest[w+len("with"):]
+		withText := strings.TrimSpace(raw)
+		if withText == "" {
+			return nil, errAt(base+w, "with is missing the variables to pass, as in \"with {\\\"x\\\": x}\"")
+		}
+		node, err := p.parseExpr(withText)
+		if err != nil {
+			return nil, err.shift(base + w + len("with") + len(raw) - len(strings.TrimLeft(raw, " \t\r\n")))
+		}
+		spec.with = node
+		nameText = rest[:w]
+	}
+
+	if w := topLevelWord(nameText, "ignore"); w >= 0 {
+		if strings.Join(strings.Fields(nameText[w:]), " ") != "ignore missing" {
+			return nil, errAt(base+w, "expected \"ignore missing\"")
+		}
+		spec.ignoreMissing = true
+		nameText = nameText[:w]
+	}
+
+	name := strings.TrimSpace(nameText)
+	if name == "" {
+		return nil, errAt(base, "include takes a template name, as in \"include \\\"row.tpl\\\"\"")
+	}
+	node, err := p.parseExpr(name)
+	if err != nil {
+		return nil, err.shift(base + len(nameText) - len(strings.TrimLeft(nameText, " \t\r\n")))
+	}
+	spec.name = node
+	return spec, nil
+}
+
+// includeName is the template name an include tag gives when it is a quoted
+// literal, for the token to carry, or "" when it is computed.
+func includeName(spec *includeSpec) string {
+	if lit, ok := spec.name.(*literalNode); ok {
+		if name, ok := lit.value.(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// topLevelWord returns the index of the first whole word w in s that sits
+// outside quotes and brackets, or -1 when there is none.
+func topLevelWord(s, w string) int {
+	depth := 0
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case depth == 0 && strings.HasPrefix(s[i:], w):
+			end := i + len(w)
+			if (i == 0 || isSpaceByte(s[i-1])) && (end == len(s) || isSpaceByte(s[end])) {
+				return i
+			}
+		default:
+		}
+	}
+	return -1
+}
@@ -0,0 +1,161 @@
+package sintax
+
+import (
+	"errors"
+	iofs "io/fs"
+	"strings"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions/fs"
+)
+
+func Test_E2E_Include(t *testing.T) {
+	loader := fs.MapLoader{
+		"row.tpl":    "<Tx>{{ tx | key:'amount' }}{{ currency | default:'' }}</Tx>",
+		"name.tpl":   "[{{ name }}]",
+		"opt.tpl":    "[{{ name | default:'none' }}]",
+		"setter.tpl": "{{ set name = 'changed' }}{{ name }}",
+		"macros.tpl": "{{ macro em(x) }}*{{ x }}*{{ endmacro }}{{ em(name) }}",
+		"base.tpl":   "<{{ block b }}{{ endblock }}>",
+		"child.tpl":  "{{ extends \"base.tpl\" }}{{ block b }}{{ name }}{{ endblock }}",
+		"nested.tpl": "({{ include \"name.tpl\" }})",
+	}
+	vars := map[string]any{
+		"name":     "ada",
+		"currency": "EUR",
+		"txs":      []any{map[string]any{"amount": 1}, map[string]any{"amount": 2}},
+		"which":    "name.tpl",
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "sees the including template's variables",
+			template: `{{ include "name.tpl" }}`,
+			want:     "[ada]",
+		},
+		{
+			name:     "with lays variables over them",
+			template: `{{ for tx in txs }}{{ include "row.tpl" with {"tx": tx} }}{{ endfor }}`,
+			want:     "<Tx>1EUR</Tx><Tx>2EUR</Tx>",
+		},
+		{
+			name:     "only hides everything but the with map",
+			template: `{{ for tx in txs }}{{ include "row.tpl" with {"tx": tx} only }}{{ endfor }}`,
+			want:     "<Tx>1</Tx><Tx>2</Tx>",
+		},
+		{
+			name:     "only passes the with map alone, or nothing",
+			template: `{{ include "row.tpl" with {tx: {"amount": 3} } only }}|{{ include "opt.tpl" only }}`,
+			want:     "<Tx>3</Tx>|[none]",
+		},
+		{
+			name:     "what it sets stays inside",
+			template: `{{ include "setter.tpl" }} {{ name }}`,
+			want:     "changed ada",
+		},
+		{
+			name:     "ignore missing renders nothing",
+			template: `a{{ include "nope.tpl" ignore missing }}b`,
+			want:     "ab",
+		},
+		{
+			name:     "the name is an expression",
+			template: `{{ include which }}{{ include which | upper | lower }}`,
+			want:     "[ada][ada]",
+		},
+		{
+			name:     "it calls its own macros",
+			template: `{{ include "macros.tpl" }}`,
+			want:     "*ada*",
+		},
+		{
+			name:     "it may extend a layout",
+			template: `{{ include "child.tpl" }}`,
+			want:     "<ada>",
+		},
+		{
+			name:     "it may include another",
+			template: `{{ include "nested.tpl" with {"name": "bob"} }}`,
+			want:     "([bob])",
+		},
+	}
+
+	s := New(builtins(), WithLoader(loader))
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+func Test_E2E_Include_Errors(t *testing.T) {
+	loader := fs.MapLoader{
+		"self.tpl":   `{{ include "self.tpl" }}`,
+		"broken.tpl": "{{ if }}",
+		"bad.tpl":    `{{ include "nope.tpl" }}`,
+	}
+	s := New(builtins(), WithLoader(loader))
+
+	_, err := s.Render(`{{ include "nope.tpl" }}`, nil)
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+	assert.True(t, strings.Contains(err.Error(), `failed to include "nope.tpl"`), "unexpected error %v", err)
+
+	// ignore missing covers the named template only, not what it includes
+	_, err = s.Render(`{{ include "bad.tpl" ignore missing }}`, nil)
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+
+	_, err = s.Render(`{{ include "broken.tpl" ignore missing }}`, nil)
+	assert.ErrorIs(t, err, ErrInvalidSyntax)
+
+	_, err = s.Render(`{{ include "self.tpl" }}`, nil)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+
+	_, err = s.Render(`{{ include "self.tpl" with 'x' }}`, nil)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "with takes a map of variables, got string"), "unexpected error %v", err)
+
+	_, err = s.Render(`{{ include n }}`, map[string]any{"n": 1})
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "include takes a template name, got int"), "unexpected error %v", err)
+
+	_, err = New(builtins()).Render(`{{ include "self.tpl" }}`, nil)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "no loader configured"), "unexpected error %v", err)
+}
+
+// An error inside an included template carries the chain of includes that led
+// to it, each with the line of its tag, ending at the tag that failed.
+func Test_E2E_Include_ErrorChain(t *testing.T) {
+	loader := fs.MapLoader{
+		"group.tpl": "<Grp>\n{{ for tx in txs }}\n" +
+			"{{ include \"row.tpl\" with {\"tx\": tx} }}\n" +
+			"{{ endfor }}\n</Grp>",
+		"row.tpl":   "<Tx>\n{{ tx | key:'amount' }}\n{{ tx | key:'missing' }}\n</Tx>",
+		"macro.tpl": "{{ from \"lib.tpl\" import boom }}\n{{ boom() }}",
+		"lib.tpl":   "{{ macro boom() }}\n\n{{ nope }}{{ endmacro }}",
+	}
+	s := New(builtins(), WithLoader(loader))
+
+	_, err := s.Render("head\n{{ include \"group.tpl\" }}", map[string]any{"txs": []any{map[string]any{"amount": 1}}})
+	var includeErr *IncludeError
+	assert.True(t, errors.As(err, &includeErr), "expected an *IncludeError, got %T", err)
+	assert.Equal(t, []IncludeFrame{{Name: "", Line: 2}, {Name: "group.tpl", Line: 3}, {Name: "row.tpl", Line: 3}}, includeErr.Chain)
+	assert.True(t, strings.Contains(err.Error(), "line 2 -> group.tpl:3 -> row.tpl:3: "), "unexpected error %v", err)
+
+	// a macro from another template fails on its own template's line
+	_, err = s.Render(`{{ include "macro.tpl" }}`, nil)
+	assert.True(t, errors.As(err, &includeErr), "expected an *IncludeError, got %T", err)
+	assert.Equal(t, []IncludeFrame{{Name: "", Line: 1}, {Name: "lib.tpl", Line: 3}}, includeErr.Chain)
+	assert.ErrorIs(t, err, ErrVariableNotFound)
+
+	// a failure outside any include is not wrapped
+	_, err = s.Render("{{ nope }}", nil)
+	assert.True(t, !errors.As(err, &includeErr), "unexpected include chain on %v", err)
+}
@@ -0,0 +1,395 @@
+package sintax
+
+import (
+	"sort"
+	"strings"
+)
+
+// Analysis is what Inspect reads off a template without rendering it: the
+// inputs it needs, the modifiers it calls, the files it reaches for, and how
+// its blocks nest.
+type Analysis struct {
+	// Variables are the root variables the template reads from the variables it
+	// is rendered with, sorted by name. A name the template binds itself, with a
+	// for loop, a set or a capture, is left out where it is bound, as is
+	// everything a macro body reads, since a macro only sees its arguments.
+	Variables []Variable
+	// Modifiers are the names of the modifiers the template calls, sorted.
+	Modifiers []string
+	// Files are the templates and files the template names literally, in the
+	// order it names them. A name computed at render time cannot be known here
+	// and is left out.
+	Files []FileRef
+	// Structure is the nesting of the template's block tags.
+	Structure []Node
+}
+
+// Variable is a root variable a template reads.
+type Variable struct {
+	Name string
+	// Optional is set when every read of the variable is answered by a default
+	// further down its pipeline, so the template renders without it.
+	Optional bool
+	// Pos is where the first read of the variable starts.
+	Pos Position
+}
+
+// FileRef is a template or file a template names literally.
+type FileRef struct {
+	Path string
+	// Via is what reads it: "include", "extends", "import" or "file".
+	Via string
+	Pos Position
+}
+
+// Node is a block tag of a template and the block tags nested in it. An if or a
+// for with more than one branch holds the tags of its first branch followed by
+// one "elif" or "else" node per further branch, holding that branch's tags.
+type Node struct {
+	// Kind is the tag's keyword: if, elif, else, for, macro, block, capture,
+	// raw, include, extends or from.
+	Kind string
+	// Expr is what follows the keyword, such as the condition of an if or the
+	// name of a block.
+	Expr     string
+	Pos      Position
+	Children []Node
+}
+
+// Inspect parses template and reports what it reads, calls and includes,
+// without rendering it. The template is not loaded further, so the variables
+// of the templates it includes are not part of the result.
+func (s *sintax) Inspect(template string) (*Analysis, error) {
+	tmpl, err := s.Compile(template)
+	if err != nil {
+		return nil, err
+	}
+	return inspect(tmpl.tokens, s.pathAccess), nil
+}
+
+// Inspect parses template and reports what it reads, calls and includes, using
+// an engine configured by opts. See the engine's Inspect.
+func Inspect(template string, opts ...Option) (*Analysis, error) {
+	return New(opts...).Inspect(template)
+}
+
+// inspector is the state of one Inspect walk over a token stream.
+type inspector struct {
+	pathAccess bool
+
+	vars      map[string]*Variable
+	required  map[string]bool
+	modifiers map[string]bool
+	files     []FileRef
+	// calls are the modifier calls read, in order, for Validate.
+	calls []modifierCall
+
+	// frames are the blocks open at the token being read, innermost last.
+	frames []*inspectFrame
+}
+
+// inspectFrame is a block open during the walk.
+type inspectFrame struct {
+	kind TokenType
+	node *Node
+	// branch is the node the tags being read nest under, the block's own node
+	// or the elif or else node of the branch in progress.
+	branch *Node
+	// bound are the names bound in the block, set for a block that scopes
+	// them: the template itself, a for loop and a macro body.
+	bound map[string]bool
+}
+
+func inspect(tokens []Token, pathAccess bool) *Analysis {
+	in := walk(tokens, pathAccess)
+	a := &Analysis{Files: in.files, Structure: in.frames[0].node.Children}
+	for _, v := range in.vars {
+		v.Optional = !in.required[v.Name]
+		a.Variables = append(a.Variables, *v)
+	}
+	sort.Slice(a.Variables, func(i, j int) bool { return a.Variables[i].Name < a.Variables[j].Name })
+	for name := range in.modifiers {
+		a.Modifiers = append(a.Modifiers, name)
+	}
+	sort.Strings(a.Modifiers)
+	return a
+}
+
+// walk reads tokens into a fresh inspector.
+func walk(tokens []Token, pathAccess bool) *inspector {
+	root := &Node{}
+	in := &inspector{
+		pathAccess: pathAccess,
+		vars:       make(map[string]*Variable),
+		required:   make(map[string]bool),
+		modifiers:  make(map[string]bool),
+		frames:     []*inspectFrame{{node: root, branch: root, bound: make(map[string]bool)}},
+	}
+	for _, token := range tokens {
+		in.token(token)
+	}
+	return in
+}
+
+func (in *inspector) token(token Token) {
+	bt, ok := token.(BaseToken)
+	if !ok {
+		return
+	}
+	pos := bt.Pos()
+	switch bt.Type() {
+	case VariableToken, FilteredVariableToken:
+		in.term(bt, pos)
+	case ShorthandIfToken, CallToken:
+		in.expr(bt.parsedExpr, pos)
+	case IfToken:
+		in.expr(bt.parsedExpr, pos)
+		in.open(bt, false)
+	case ElifToken:
+		in.expr(bt.parsedExpr, pos)
+		in.branch(bt)
+	case ElseToken:
+		in.branch(bt)
+	case ForToken:
+		in.expr(bt.parsedExpr, pos)
+		frame := in.open(bt, true)
+		keyName, loopVar := "", bt.Name()
+		if idx := strings.IndexByte(loopVar, ','); idx >= 0 {
+			keyName, loopVar = loopVar[:idx], loopVar[idx+1:]
+		}
+		for _, name := range []string{loopName, keyName, loopVar, loopVar + "_index", loopVar + "_first", loopVar + "_last", loopVar + "_key"} {
+			frame.bound[name] = true
+		}
+		in.expr(bt.parsedFilter, pos)
+	case MacroToken:
+		frame := in.open(bt, true)
+		if bt.parsedMacro != nil {
+			for _, p := range bt.parsedMacro.params {
+				frame.bound[p.name] = true
+				in.expr(p.def, pos)
+			}
+		}
+	case BlockToken, CaptureToken, RawToken:
+		in.open(bt, false)
+	case IfEndToken, ForEndToken, MacroEndToken, BlockEndToken, RawEndToken:
+		in.close()
+	case CaptureEndToken:
+		if frame := in.close(); frame != nil {
+			in.bind(frame.node.Expr)
+		}
+	case SetToken:
+		in.expr(bt.parsedExpr, pos)
+		in.bind(bt.Name())
+	case IncludeToken:
+		in.leaf(bt)
+		if spec := bt.parsedInclude; spec != nil {
+			in.expr(spec.name, pos)
+			in.expr(spec.with, pos)
+			in.file(includeName(spec), controlName(IncludeToken), pos)
+		}
+	case ExtendsToken:
+		in.leaf(bt)
+		in.file(bt.Name(), controlName(ExtendsToken), pos)
+	case ImportToken:
+		in.leaf(bt)
+		if bt.parsedImport != nil {
+			in.file(bt.parsedImport.source, "import", pos)
+		}
+	default:
+	}
+}
+
+// open starts the block token opens, scoping the names bound in it when scoped.
+func (in *inspector) open(token BaseToken, scoped bool) *inspectFrame {
+	node := in.node(token)
+	frame := &inspectFrame{kind: token.Type(), node: node, branch: node}
+	if scoped {
+		frame.bound = make(map[string]bool)
+	}
+	in.frames = append(in.frames, frame)
+	return frame
+}
+
+// branch starts the elif or else branch of the innermost block. The else of a
+// for renders without the loop's names.
+func (in *inspector) branch(token BaseToken) {
+	frame := in.top()
+	if frame == nil {
+		return
+	}
+	frame.node.Children = append(frame.node.Children, in.nodeOf(token))
+	frame.branch = &frame.node.Children[len(frame.node.Children)-1]
+	if frame.kind == ForToken {
+		frame.bound = make(map[string]bool)
+	}
+}
+
+// close ends the innermost block, returning it, or nil at the top level.
+func (in *inspector) close() *inspectFrame {
+	frame := in.top()
+	if frame == nil {
+		return nil
+	}
+	in.frames = in.frames[:len(in.frames)-1]
+	parent := in.frames[len(in.frames)-1]
+	parent.branch.Children = append(parent.branch.Children, *frame.node)
+	return frame
+}
+
+// top returns the innermost open block, or nil at the top level.
+func (in *inspector) top() *inspectFrame {
+	if len(in.frames) < 2 {
+		return nil
+	}
+	return in.frames[len(in.frames)-1]
+}
+
+// leaf records a tag that opens no block.
+func (in *inspector) leaf(token BaseToken) {
+	branch := in.frames[len(in.frames)-1].branch
+	branch.Children = append(branch.Children, in.nodeOf(token))
+}
+
+// node returns a detached node for the block token opens, which close attaches
+// to its parent once its children are known.
+func (in *inspector) node(token BaseToken) *Node {
+	n := in.nodeOf(token)
+	return &n
+}
+
+func (in *inspector) nodeOf(token BaseToken) Node {
+	kind := controlName(token.Type())
+	expr := strings.TrimSpace(token.Raw())
+	switch token.Type() {
+	case BlockToken, CaptureToken:
+		expr = token.Name()
+	case ForToken, IncludeToken, ExtendsToken, ImportToken, MacroToken:
+		expr = strings.TrimSpace(strings.TrimPrefix(expr, kind))
+	default:
+	}
+	return Node{Kind: kind, Expr: expr, Pos: token.Pos()}
+}
+
+// bind binds name in the innermost block that scopes names, the way set binds
+// into the scope it renders in.
+func (in *inspector) bind(name string) {
+	for i := len(in.frames) - 1; i >= 0; i-- {
+		if in.frames[i].bound != nil {
+			in.frames[i].bound[name] = true
+			return
+		}
+	}
+}
+
+// read records a read of the variable name, optional when a default answers a
+// miss on it.
+func (in *inspector) read(name string, optional bool, pos Position) {
+	root := name
+	if strings.ContainsAny(name, pathChars) && (in.pathAccess || isLoopPath(name)) {
+		if r, _, err := splitPath(name); err == nil {
+			root = r
+		}
+	}
+	for i := len(in.frames) - 1; i >= 0; i-- {
+		frame := in.frames[i]
+		if frame.bound[root] {
+			return
+		}
+		if frame.kind == MacroToken {
+			// a macro body sees its arguments and nothing else
+			return
+		}
+	}
+	if _, ok := in.vars[root]; !ok {
+		in.vars[root] = &Variable{Name: root, Pos: pos}
+	}
+	if !optional {
+		in.required[root] = true
+	}
+}
+
+func (in *inspector) file(path, via string, pos Position) {
+	if path != "" {
+		in.files = append(in.files, FileRef{Path: path, Via: via, Pos: pos})
+	}
+}
+
+// term records what a variable or modifier pipeline token reads and calls.
+func (in *inspector) term(token BaseToken, pos Position) {
+	if token.Type() == VariableToken {
+		in.read(token.Name(), false, pos)
+		return
+	}
+	head, funcs := varAndFuncs(token)
+	defaulted := false
+	for _, fn := range funcs {
+		in.modifiers[fn.Name] = true
+		in.calls = append(in.calls, modifierCall{fn: fn, pos: pos})
+		if fn.Name == defaultModifier {
+			defaulted = true
+		}
+		for _, arg := range fn.Args {
+			if name, ok := arg.Value.(string); ok && arg.Var {
+				in.read(name, false, pos)
+			}
+		}
+	}
+	switch {
+	case isQuotedWith(head, `"`):
+		in.literal(unquote(head, `"`), funcs, pos)
+	case isQuotedWith(head, `'`):
+		in.literal(unquote(head, `'`), funcs, pos)
+	default:
+		in.read(head, defaulted, pos)
+	}
+}
+
+// literal records a quoted pipeline head handed straight to file as a path.
+func (in *inspector) literal(value string, funcs []Func, pos Position) {
+	if len(funcs) > 0 && funcs[0].Name == fileModifier {
+		in.file(value, fileModifier, pos)
+	}
+}
+
+// expr records what a parsed expression reads and calls.
+func (in *inspector) expr(node exprNode, pos Position) {
+	switch n := node.(type) {
+	case *termNode:
+		if bt, ok := n.token.(BaseToken); ok {
+			in.term(bt, pos)
+		}
+	case *mapNode:
+		for _, e := range n.entries {
+			in.expr(e.value, pos)
+		}
+	case *ternaryNode:
+		in.expr(n.cond, pos)
+		in.expr(n.then, pos)
+		in.expr(n.els, pos)
+	case *notNode:
+		in.expr(n.x, pos)
+	case *logicNode:
+		in.expr(n.left, pos)
+		in.expr(n.right, pos)
+	case *compareNode:
+		in.expr(n.left, pos)
+		in.expr(n.right, pos)
+	case *rangeNode:
+		for _, arg := range n.args {
+			in.expr(arg, pos)
+		}
+	case *callNode:
+		for _, arg := range n.args {
+			in.expr(arg.value, pos)
+		}
+	default:
+		// a literal reads and calls nothing
+	}
+}
+
+// The modifiers Inspect knows by name: default answers a miss, and file reads
+// the path it is handed.
+const (
+	defaultModifier = "default"
+	fileModifier    = "file"
+)
@@ -0,0 +1,68 @@
+package sintax
+
+import (
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_E2E_Inspect(t *testing.T) {
+	template := `{{ from "lib.tpl" import row }}Dear {{ name | upper }},
+{{ for i, tx in txs if tx | key:'amount' | gt:min_amount }}{{ tx_index }} {{ row(tx, currency) }} {{ loop.index }}{{ endfor }}
+{{ set total = txs | length }}{{ total }} {{ note | default:'-' }} {{ title | default:'' }}{{ title }}
+{{ if vip }}{{ include "vip.tpl" with {"level": level} }}{{ elif guest }}guest{{ else }}{{ "legal.txt" | file }}{{ endif }}
+{{ macro m(x) }}{{ x }}{{ inner }}{{ endmacro }}{{ capture c }}{{ sig }}{{ endcapture }}{{ c }}`
+
+	a, err := New(builtins()).Inspect(template)
+	assert.NoError(t, err)
+
+	names := make([]string, 0, len(a.Variables))
+	var optional []string
+	for _, v := range a.Variables {
+		names = append(names, v.Name)
+		if v.Optional {
+			optional = append(optional, v.Name)
+		}
+	}
+	assert.Equal(t, []string{"currency", "guest", "level", "min_amount", "name", "note", "sig", "title", "txs", "vip"}, names)
+	assert.Equal(t, []string{"note"}, optional)
+	assert.Equal(t, Position{Offset: 36, Line: 1, Column: 37}, a.Variables[4].Pos)
+
+	assert.Equal(t, []string{"default", "file", "gt", "key", "length", "upper"}, a.Modifiers)
+
+	assert.Equal(t, []FileRef{
+		{Path: "lib.tpl", Via: "import", Pos: Position{Offset: 0, Line: 1, Column: 1}},
+		{Path: "vip.tpl", Via: "include", Pos: Position{Offset: 298, Line: 4, Column: 13}},
+		{Path: "legal.txt", Via: "file", Pos: Position{Offset: 374, Line: 4, Column: 89}},
+	}, a.Files)
+
+	kinds := func(nodes []Node) []string {
+		out := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			out = append(out, n.Kind+" "+n.Expr)
+		}
+		return out
+	}
+	assert.Equal(t, []string{`from "lib.tpl" import row`, "for i, tx in txs if tx | key:'amount' | gt:min_amount", "if vip", "macro m(x)", "capture c"}, kinds(a.Structure))
+	ifNode := a.Structure[2]
+	assert.Equal(t, []string{`include "vip.tpl" with {"level": level}`, "elif guest", "else "}, kinds(ifNode.Children))
+}
+
+// Path access decides whether a dotted name is a path into a root variable.
+func Test_E2E_Inspect_Paths(t *testing.T) {
+	template := `{{ user.name }}{{ for r in rows }}{{ r.id }}{{ endfor }}`
+
+	a, err := Inspect(template, builtins(), WithPathAccess())
+	assert.NoError(t, err)
+	assert.Len(t, a.Variables, 2)
+	assert.Equal(t, "rows", a.Variables[0].Name)
+	assert.Equal(t, "user", a.Variables[1].Name)
+
+	a, err = Inspect(template, builtins())
+	assert.NoError(t, err)
+	assert.Len(t, a.Variables, 3)
+	assert.Equal(t, "r.id", a.Variables[0].Name)
+
+	_, err = Inspect("{{ if }}", builtins())
+	assert.ErrorIs(t, err, ErrInvalidSyntax)
+}
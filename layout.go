@@ -0,0 +1,294 @@
+package sintax
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// superName is the call that renders the block a block overrides.
+const superName = "super"
+
+// layout is the chain of templates a render inherits through, the template
+// being rendered first and the base layout, the one that extends nothing, last.
+type layout struct {
+	levels []layoutLevel
+}
+
+// layoutLevel is one template of the chain: its name, the blocks it defines,
+// keyed by name, and the macros their bodies can call.
+type layoutLevel struct {
+	name   string
+	blocks map[string][]Token
+	macros macros
+}
+
+// blockFrame is the block whose body is rendering, and the level of the chain
+// that body came from, so super() knows where to continue.
+type blockFrame struct {
+	name  string
+	level int
+}
+
+// renderTemplate renders a whole template into out, through its layout when it
+// extends another.
+func (r *TokenRenderer) renderTemplate(out *output, tokens []Token, vars map[string]any) error {
+	if extends := findExtends(tokens); extends >= 0 {
+		return r.renderExtends(out, tokens, extends, vars)
+	}
+	_, err := r.renderRange(out, tokens, 0, len(tokens), templateScope(tokens, vars))
+	return err
+}
+
+// findExtends returns the index of a template's extends tag, or -1 when it has
+// none. Parse keeps the tag at the top level and allows one.
+func findExtends(tokens []Token) int {
+	for i, tok := range tokens {
+		if tok.Type() == ExtendsToken {
+			return i
+		}
+	}
+	return -1
+}
+
+// renderExtends renders a template that extends another. It walks up the chain
+// of extends, loading each parent through the engine's loader, and renders the
+// base layout with every block taken from the lowest template of the chain that
+// defines it. What each template sets at its top level runs first, from the
+// child up, and the base's own sets run as it renders, so a child can hand a
+// value such as a page title up to a layout that does not set it, and a set
+// higher up the chain has the last word. A layout that only supplies a
+// fallback writes `{{ set title = title | default:'Page' }}`. Each parent
+// loaded counts as a level of nesting, which is what stops a cycle of extends
+// at ErrMaxDepthExceeded.
+func (r *TokenRenderer) renderExtends(out *output, tokens []Token, extends int, vars map[string]any) error {
+	scope := childScope(vars)
+	chain := &layout{}
+	ms := r.macros
+	name := r.name
+	for depth := r.depth; ; depth++ {
+		chain.levels = append(chain.levels, layoutLevel{name: name, blocks: blocksOf(tokens), macros: ms})
+		if err := r.assignTopLevel(tokens, scope); err != nil {
+			return err
+		}
+
+		parent := tokens[extends].Name()
+		if depth+1 > r.maxDepth {
+			return fmt.Errorf("failed to extend %q: %w", parent, ErrMaxDepthExceeded)
+		}
+		var err error
+		if tokens, err = r.load(parent); err != nil {
+			return fmt.Errorf("failed to extend %q: %w", parent, err)
+		}
+		child := *r
+		child.depth = depth + 1
+		child.name = parent
+		if err := child.define(tokens); err != nil {
+			return fmt.Errorf("failed to extend %q: %w", parent, err)
+		}
+		ms, name = child.macros, parent
+
+		if extends = findExtends(tokens); extends < 0 {
+			break
+		}
+	}
+
+	chain.levels = append(chain.levels, layoutLevel{name: name, blocks: blocksOf(tokens), macros: ms})
+	base := *r
+	base.name = name
+	base.macros = ms
+	base.layout = chain
+	_, err := base.renderRange(out, tokens, 0, len(tokens), scope)
+	return err
+}
+
+// assignTopLevel runs the sets and captures at the top level of a template that
+// extends another. Parse allows nothing else there to render.
+func (r *TokenRenderer) assignTopLevel(tokens []Token, vars map[string]any) error {
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].Type() {
+		case SetToken:
+			if err := r.assign(tokens[i], vars); err != nil {
+				return err
+			}
+		case CaptureToken:
+			next, err := r.renderCapture(tokens, i, len(tokens), vars)
+			if err != nil {
+				return err
+			}
+			i = next - 1
+		case BlockToken, MacroToken:
+			end, err := findBlockEnd(tokens, i, len(tokens), tokens[i].Type(), closerOf(tokens[i].Type()))
+			if err != nil {
+				return err
+			}
+			i = end
+		default:
+		}
+	}
+	return nil
+}
+
+// blocksOf collects the bodies of every block a template defines, nested ones
+// included.
+func blocksOf(tokens []Token) map[string][]Token {
+	var blocks map[string][]Token
+	for i, tok := range tokens {
+		if tok.Type() != BlockToken {
+			continue
+		}
+		end, err := findBlockEnd(tokens, i, len(tokens), BlockToken, BlockEndToken)
+		if err != nil {
+			continue
+		}
+		if blocks == nil {
+			blocks = make(map[string][]Token)
+		}
+		blocks[tok.Name()] = tokens[i+1 : end]
+	}
+	return blocks
+}
+
+// renderBlock renders the block at index `start`: its own body, or, when the
+// render inherits through a layout, the body of the lowest template in the
+// chain that defines the block. The body renders in the scope the block sits
+// in. It returns the index just past the endblock.
+func (r *TokenRenderer) renderBlock(out *output, tokens []Token, start, end int, vars map[string]any) (int, error) {
+	endIdx, err := findBlockEnd(tokens, start, end, BlockToken, BlockEndToken)
+	if err != nil {
+		return start, err
+	}
+	name := tokens[start].Name()
+	if r.layout == nil {
+		child := *r
+		child.block = &blockFrame{name: name}
+		if _, err := child.renderRange(out, tokens, start+1, endIdx, vars); err != nil {
+			return start, err
+		}
+		return endIdx + 1, nil
+	}
+	if err := r.renderLevel(out, name, 0, vars); err != nil {
+		return start, err
+	}
+	return endIdx + 1, nil
+}
+
+// renderLevel renders the body of block name from the first level of the chain
+// at or above from that defines it.
+func (r *TokenRenderer) renderLevel(out *output, name string, from int, vars map[string]any) error {
+	for level := from; level < len(r.layout.levels); level++ {
+		body, ok := r.layout.levels[level].blocks[name]
+		if !ok {
+			continue
+		}
+		child := *r
+		child.block = &blockFrame{name: name, level: level}
+		child.name = r.layout.levels[level].name
+		child.macros = r.layout.levels[level].macros
+		_, err := child.renderRange(out, body, 0, len(body), vars)
+		return err
+	}
+	return fmt.Errorf("block %s has no parent block for %s() to render", name, superName)
+}
+
+// callSuper renders the block the block in progress overrides, into a string.
+func (r *TokenRenderer) callSuper(vars map[string]any) (any, error) {
+	if r.block == nil {
+		return nil, fmt.Errorf("%s() called outside a block", superName)
+	}
+	if r.layout == nil {
+		return nil, fmt.Errorf("block %s has no parent block for %s() to render", r.block.name, superName)
+	}
+	var sb strings.Builder
+	if err := r.renderLevel(r.newOutput(&sb), r.block.name, r.block.level+1, vars); err != nil {
+		return nil, err
+	}
+	return r.markup(sb.String()), nil
+}
+
+// load reads the template name through the engine's loader and parses it, or
+// reuses the tokens it parsed last time while the loader returns the same
+// source. A loader that takes a context loads under the render's.
+func (r *TokenRenderer) load(name string) ([]Token, error) {
+	if r.loader == nil {
+		return nil, errors.New("no loader configured, see WithLoader")
+	}
+	if err := r.canceled(); err != nil {
+		return nil, err
+	}
+	var source string
+	var err error
+	if cl, ok := r.loader.(ContextLoader); ok {
+		source, _, err = cl.LoadContext(r.ctx, name)
+	} else {
+		source, _, err = r.loader.Load(name)
+	}
+	if err != nil {
+		return nil, r.stopped(err)
+	}
+	if r.loaded != nil {
+		if tokens, ok := r.loaded.get(name, source); ok {
+			return tokens, nil
+		}
+	}
+	tokens, err := r.parser.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if r.loaded != nil {
+		r.loaded.put(name, source, tokens)
+	}
+	return tokens, nil
+}
+
+// parseExtends reads the template name an extends tag's contents give, which
+// must be a quoted string. Offsets in the error are relative to s, trimmed.
+func parseExtends(s string) (string, *parseError) {
+	s = strings.TrimSpace(s)
+	name := strings.TrimSpace(strings.TrimPrefix(s, "extends"))
+	at := len(s) - len(name)
+	if !isQuotedWith(name, `"`) && !isQuotedWith(name, `'`) || len(name) < 2 {
+		return "", errAt(at, "extends takes a quoted template name, as in \"extends \\\"base.tpl\\\"\"")
+	}
+	return unquote(name, name[:1]), nil
+}
+
+// checkExtends rejects content a template that extends another would never
+// render. Its layout renders in its place, so at the top level, outside its
+// blocks, such a template may only hold whitespace, comments, sets, captures,
+// macros and imports.
+func checkExtends(tokens []Token, lines lineIndex) error {
+	if findExtends(tokens) < 0 {
+		return nil
+	}
+	depth := 0
+	for _, tok := range tokens {
+		switch tok.Type() {
+		case BlockToken, MacroToken, CaptureToken:
+			depth++
+			continue
+		case BlockEndToken, MacroEndToken, CaptureEndToken:
+			depth--
+			continue
+		default:
+		}
+		if depth > 0 {
+			continue
+		}
+		switch tok.Type() {
+		case ExtendsToken, CommentToken, SetToken, ImportToken:
+		case TextToken:
+			if trimmed := strings.TrimLeft(tok.Raw(), " \t\r\n"); trimmed != "" {
+				offset := tokenPos(tok).Offset + len(tok.Raw()) - len(trimmed)
+				return lines.syntaxError(offset, "text outside a block is never rendered in a template that extends another")
+			}
+		default:
+			what := controlName(tok.Type())
+			if isValueToken(tok.Type()) {
+				what = "output"
+			}
+			return lines.syntaxError(tokenPos(tok).Offset, "%s outside a block is never rendered in a template that extends another", what)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package sintax
+
+// Limits caps what a single render may spend, so a template from an untrusted
+// author cannot run away with a shared service. A zero field leaves that
+// resource unlimited, so the zero Limits is no limit at all. Every render gets
+// the whole budget afresh, and a nested template rendered by `template` draws
+// on its parent's.
+type Limits struct {
+	// MaxOutputBytes caps the bytes a render writes. A nested template is held
+	// to the same cap on its own output before its parent writes it.
+	MaxOutputBytes int64
+	// MaxLoopIterations caps the loop iterations a render runs, counted across
+	// every loop it enters, nested ones included. An item a for filter turns
+	// down counts as an iteration too.
+	MaxLoopIterations int
+	// MaxModifierCalls caps the modifier calls a render makes, counting each
+	// link of each pipeline every time it runs.
+	MaxModifierCalls int
+	// MaxNestedLoops caps how deeply loops nest, so 1 allows a loop but not a
+	// loop inside it.
+	MaxNestedLoops int
+}
+
+// The names a LimitError reports, matching the Limits fields.
+const (
+	limitOutputBytes    = "MaxOutputBytes"
+	limitLoopIterations = "MaxLoopIterations"
+	limitModifierCalls  = "MaxModifierCalls"
+	limitNestedLoops    = "MaxNestedLoops"
+)
+
+// budget is what one render has spent against its Limits. The renderer holds a
+// pointer to it, so the copies renderNested makes share their parent's.
+type budget struct {
+	limits     Limits
+	iterations int
+	calls      int
+	loops      int
+}
+
+// newBudget returns nil when limits caps nothing, so an unlimited render pays
+// for a nil check per counted step and nothing more.
+func newBudget(limits Limits) *budget {
+	if limits == (Limits{}) {
+		return nil
+	}
+	return &budget{limits: limits}
+}
+
+// iterate counts one loop iteration.
+func (b *budget) iterate() error {
+	if b == nil || b.limits.MaxLoopIterations == 0 {
+		return nil
+	}
+	b.iterations++
+	if b.iterations > b.limits.MaxLoopIterations {
+		return &LimitError{Limit: limitLoopIterations, Max: int64(b.limits.MaxLoopIterations)}
+	}
+	return nil
+}
+
+// call counts one modifier call.
+func (b *budget) call() error {
+	if b == nil || b.limits.MaxModifierCalls == 0 {
+		return nil
+	}
+	b.calls++
+	if b.calls > b.limits.MaxModifierCalls {
+		return &LimitError{Limit: limitModifierCalls, Max: int64(b.limits.MaxModifierCalls)}
+	}
+	return nil
+}
+
+// enterLoop counts one more level of loop nesting. Every successful enterLoop
+// is paired with a leaveLoop.
+func (b *budget) enterLoop() error {
+	if b == nil {
+		return nil
+	}
+	if b.limits.MaxNestedLoops > 0 && b.loops >= b.limits.MaxNestedLoops {
+		return &LimitError{Limit: limitNestedLoops, Max: int64(b.limits.MaxNestedLoops)}
+	}
+	b.loops++
+	return nil
+}
+
+func (b *budget) leaveLoop() {
+	if b != nil {
+		b.loops--
+	}
+}
+
+// maxOutput is the output cap, zero for none.
+func (b *budget) maxOutput() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.limits.MaxOutputBytes
+}
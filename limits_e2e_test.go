@@ -0,0 +1,124 @@
+package sintax
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_E2E_Limits_Trip(t *testing.T) {
+	vars := map[string]any{
+		"items":   []any{1, 2, 3},
+		"name":    "ada",
+		"long":    "abcdefgh",
+		"partial": "{{ for x in items }}{{ x }}{{ endfor }}",
+	}
+
+	testCases := []struct {
+		name     string
+		limits   Limits
+		template string
+		limit    string
+	}{
+		{
+			name:     "output bytes",
+			limits:   Limits{MaxOutputBytes: 10},
+			template: "{{ for x in items }}item {{ x }}\n{{ endfor }}",
+			limit:    "MaxOutputBytes",
+		},
+		{
+			name:     "loop iterations across nested loops",
+			limits:   Limits{MaxLoopIterations: 8},
+			template: "{{ for x in items }}{{ for y in items }}.{{ endfor }}{{ endfor }}",
+			limit:    "MaxLoopIterations",
+		},
+		{
+			name:     "items a filter turns down",
+			limits:   Limits{MaxLoopIterations: 10},
+			template: "{{ for i in range(0, 50000000) if false }}x{{ endfor }}done",
+			limit:    "MaxLoopIterations",
+		},
+		{
+			name:     "items a filter turns down in a listed source",
+			limits:   Limits{MaxLoopIterations: 2},
+			template: "{{ for x in items if false }}x{{ endfor }}done",
+			limit:    "MaxLoopIterations",
+		},
+		{
+			name:     "modifier calls",
+			limits:   Limits{MaxModifierCalls: 5},
+			template: "{{ for x in items }}{{ name | upper | lower }}{{ endfor }}",
+			limit:    "MaxModifierCalls",
+		},
+		{
+			name:     "nested loops",
+			limits:   Limits{MaxNestedLoops: 1},
+			template: "{{ for x in items }}{{ for y in items }}.{{ endfor }}{{ endfor }}",
+			limit:    "MaxNestedLoops",
+		},
+		{
+			name:     "nested template draws on the parent's iterations",
+			limits:   Limits{MaxLoopIterations: 5},
+			template: "{{ for x in items }}{{ endfor }}{{ partial | template }}",
+			limit:    "MaxLoopIterations",
+		},
+		{
+			name:     "nested template output is capped before it is written",
+			limits:   Limits{MaxOutputBytes: 2},
+			template: "{{ partial | template | length }}",
+			limit:    "MaxOutputBytes",
+		},
+		{
+			name:     "a template that is a lone value",
+			limits:   Limits{MaxOutputBytes: 3},
+			template: "{{ long }}",
+			limit:    "MaxOutputBytes",
+		},
+		{
+			name:     "a template that is a lone safe value",
+			limits:   Limits{MaxOutputBytes: 3},
+			template: "{{ long | safe }}",
+			limit:    "MaxOutputBytes",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(builtins(), WithLimits(tt.limits)).Render(tt.template, vars)
+			assert.ErrorIs(t, err, ErrLimitExceeded)
+
+			var limitErr *LimitError
+			assert.True(t, errors.As(err, &limitErr), "expected a *LimitError, got %v", err)
+			assert.Equal(t, tt.limit, limitErr.Limit)
+			assert.True(t, strings.Contains(err.Error(), tt.limit), "message %q does not name the limit", err)
+		})
+	}
+}
+
+// A render that stays inside every cap renders as if there were none, and each
+// render gets the whole budget again.
+func Test_E2E_Limits_WithinBudget(t *testing.T) {
+	s := New(builtins(), WithLimits(Limits{
+		MaxOutputBytes:    6,
+		MaxLoopIterations: 3,
+		MaxModifierCalls:  3,
+		MaxNestedLoops:    1,
+	}))
+
+	for range 3 {
+		out, err := s.Render("{{ for x in items }}{{ x | upper }},{{ endfor }}", map[string]any{"items": []any{"a", "b", "c"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "A,B,C,", out)
+	}
+}
+
+// A streamed render writes what fit under the cap and nothing of the write that
+// would have crossed it.
+func Test_E2E_Limits_OutputStreamStopsAtCap(t *testing.T) {
+	var sb strings.Builder
+	err := New(builtins(), WithLimits(Limits{MaxOutputBytes: 5})).RenderTo(&sb, "abc{{ name }}", map[string]any{"name": "defg"})
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Equal(t, "abc", sb.String())
+}
@@ -0,0 +1,110 @@
+package sintax
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions/fs"
+)
+
+// The `file` modifier and the template tags read through one loader, so a
+// partial pulled in by `file | template` can itself extend a layout.
+func Test_E2E_Loader_SharedByFileAndTags(t *testing.T) {
+	loader := fs.MapLoader{
+		"base.tpl": "<{{ block body }}{{ endblock }}>",
+		"card.tpl": "{{ extends \"base.tpl\" }}{{ block body }}card {{ name }}{{ endblock }}",
+	}
+	s := New(builtins(), WithModifiers(fs.ModifiersFrom(loader)), WithLoader(loader))
+
+	out, err := s.Render(`{{ "card.tpl" | file | template }}`, map[string]any{"name": "ada"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<card ada>", out)
+
+	out, err = s.Render(`{{ "nope.tpl" | file | default:'none' }}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "none", out)
+}
+
+// A chain lets templates on disk override the ones an application ships with.
+func Test_E2E_Loader_Chain(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "base.tpl"), []byte("disk {{ block b }}{{ endblock }}"), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	shipped := fs.MapLoader{
+		"base.tpl": "shipped {{ block b }}{{ endblock }}",
+		"page.tpl": "{{ extends \"base.tpl\" }}{{ block b }}page{{ endblock }}",
+	}
+	s := New(builtins(), WithLoader(fs.ChainLoader(fs.DirLoader(dir), shipped)))
+
+	out, err := s.Render(`{{ extends "page.tpl" }}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "disk page", out)
+}
+
+// cancelingLoader cancels the render it loads for, the way a store would give
+// up on a deadline that passes mid-load.
+type cancelingLoader struct{ cancel context.CancelFunc }
+
+func (l cancelingLoader) Load(string) (string, time.Time, error) {
+	return "", time.Time{}, nil
+}
+
+func (l cancelingLoader) LoadContext(ctx context.Context, _ string) (string, time.Time, error) {
+	l.cancel()
+	return "", time.Time{}, ctx.Err()
+}
+
+func Test_E2E_Loader_LoadsUnderRenderContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := New(builtins(), WithLoader(cancelingLoader{cancel: cancel}))
+
+	_, err := s.RenderContext(ctx, `{{ extends "base.tpl" }}`, nil)
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// A loaded template is parsed once, and parsed again once the loader has a
+// different one under the same name.
+func Test_E2E_Loader_ReloadsChanged(t *testing.T) {
+	loader := fs.MapLoader{"row.tpl": "[{{ x }}]"}
+	s := New(builtins(), WithLoader(loader))
+
+	tpl := `{{ for x in items }}{{ include "row.tpl" }}{{ endfor }}`
+	vars := map[string]any{"items": []any{1, 2}}
+	out, err := s.Render(tpl, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, "[1][2]", out)
+
+	loader["row.tpl"] = "({{ x }})"
+	out, err = s.Render(tpl, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, "(1)(2)", out)
+}
+
+// stampedLoader reports the same modTime for every load, the way a filesystem
+// does for two edits within its clock's granularity.
+type stampedLoader map[string]string
+
+func (l stampedLoader) Load(name string) (string, time.Time, error) {
+	return l[name], time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), nil
+}
+
+func Test_E2E_Loader_ReloadsChangedWithinModTime(t *testing.T) {
+	loader := stampedLoader{"row.tpl": "[{{ x }}]"}
+	s := New(builtins(), WithLoader(loader))
+
+	out, err := s.Render(`{{ include "row.tpl" }}`, map[string]any{"x": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "[1]", out)
+
+	loader["row.tpl"] = "({{ x }})"
+	out, err = s.Render(`{{ include "row.tpl" }}`, map[string]any{"x": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "(1)", out)
+}
@@ -0,0 +1,463 @@
+package sintax
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/toaweme/sintax/functions"
+)
+
+// loopName is the variable a for loop binds its metadata to, and cycleName the
+// one call it answers.
+const (
+	loopName  = "loop"
+	cycleName = loopName + ".cycle"
+)
+
+// loopState is the `loop` binding of a for loop. The loop moves it from item
+// to item in place rather than binding a fresh set of fields each pass, and its
+// fields are worked out only when a template reads them. Inside the body it
+// hides a caller variable named `loop`, which is back once the loop ends.
+type loopState struct {
+	// index is the 0-based position of the current item and length the number
+	// of items the loop visits, -1 when a lazy source cannot tell. last is
+	// known either way, from reading one item ahead.
+	index  int
+	length int
+	last   bool
+	// prev and next are the items either side of the current one, nil at the
+	// ends.
+	prev, next any
+	// parent is the binding of the loop around this one, nil for the outermost.
+	parent *loopState
+}
+
+// depth is 1 for the outermost loop, and one more for each loop around it.
+func (l *loopState) depth() int {
+	d := 1
+	for p := l.parent; p != nil; p = p.parent {
+		d++
+	}
+	return d
+}
+
+// String renders a bare `{{ loop }}` as the 1-based position over the length,
+// such as `loop 2/3`, or just the position when a lazy source cannot tell.
+func (l *loopState) String() string {
+	if l.length < 0 {
+		return fmt.Sprintf("loop %d", l.index+1)
+	}
+	return fmt.Sprintf("loop %d/%d", l.index+1, l.length)
+}
+
+// field reads one field of the binding, as a path segment such as the `index`
+// of `loop.index`.
+func (l *loopState) field(key any) (any, error) {
+	name, _ := key.(string)
+	switch name {
+	case "length", "revindex", "revindex1":
+		if l.length < 0 {
+			return nil, functions.Miss("loop.%s is unknown for a lazy source", name)
+		}
+	default:
+	}
+	switch name {
+	case "index":
+		return l.index, nil
+	case "index1":
+		return l.index + 1, nil
+	case "revindex":
+		return l.length - 1 - l.index, nil
+	case "revindex1":
+		return l.length - l.index, nil
+	case "length":
+		return l.length, nil
+	case "first":
+		return l.index == 0, nil
+	case "last":
+		return l.last, nil
+	case "depth":
+		return l.depth(), nil
+	case "previtem":
+		return l.prev, nil
+	case "nextitem":
+		return l.next, nil
+	case "parent":
+		if l.parent == nil {
+			return nil, nil
+		}
+		return l.parent, nil
+	default:
+	}
+	return nil, functions.Miss("loop has no field %v", key)
+}
+
+// isLoopPath reports whether name reads a field of the `loop` binding, which
+// resolves as a path whether or not WithPathAccess is on.
+func isLoopPath(name string) bool {
+	rest, ok := strings.CutPrefix(name, loopName)
+	return ok && rest != "" && (rest[0] == '.' || rest[0] == '[')
+}
+
+// cycle answers `loop.cycle(a, b, ...)`, the argument at the current index of
+// the innermost loop, wrapping around, so `loop.cycle('odd', 'even')` alternates.
+func (r *TokenRenderer) cycle(call *callNode, vars map[string]any) (any, error) {
+	l, ok := vars[loopName].(*loopState)
+	if !ok {
+		return nil, fmt.Errorf("%s() called outside a for loop", cycleName)
+	}
+	return call.args[l.index%len(call.args)].value.eval(r, vars)
+}
+
+// loopItem is one item a for tag visits: the value, and the map key, struct
+// field name or iter.Seq2 key it sits under, if any.
+type loopItem struct {
+	key   any
+	value any
+}
+
+// loopSource hands a for loop the items it visits, one at a time.
+type loopSource struct {
+	// items are the items of a source listed up front, such as a map's
+	// entries, read from at onwards. index instead reads item i of a source
+	// that can be indexed in place, such as a slice, so an element is only
+	// boxed once the loop reaches it. pull reads the next item of a lazy
+	// source, one that yields its items on demand and is never held whole,
+	// such as a channel or an iterator.
+	items []loopItem
+	index func(i int) loopItem
+	at    int
+	pull  func() (loopItem, bool, error)
+	// stop releases a lazy source a loop leaves before reaching its end.
+	stop func()
+	// length is the number of items, or -1 for a lazy source that cannot tell
+	// without running through them.
+	length int
+	// keyed reports whether the items carry a key of their own, as a map's
+	// entries, a struct's fields and an iter.Seq2's pairs do, rather than a
+	// position.
+	keyed bool
+}
+
+// next returns the next item, reporting false once there are none left.
+func (s *loopSource) next() (loopItem, bool, error) {
+	if s.pull != nil {
+		return s.pull()
+	}
+	if s.at >= s.length {
+		return loopItem{}, false, nil
+	}
+	s.at++
+	if s.index != nil {
+		return s.index(s.at - 1), true, nil
+	}
+	return s.items[s.at-1], true, nil
+}
+
+func (s *loopSource) close() {
+	if s.stop != nil {
+		s.stop()
+	}
+}
+
+// filter drops the items keep turns down. Each item is bound into scope with
+// bind, at the position it would take, for keep to read. A listed or indexed
+// source is filtered up front, so it still knows its length, and a lazy one as
+// it is read.
+func (s *loopSource) filter(bind func(map[string]any, int, loopItem), keep func(map[string]any) (bool, error), scope map[string]any) error {
+	if s.pull == nil {
+		var kept []loopItem
+		for {
+			item, ok, _ := s.next()
+			if !ok {
+				break
+			}
+			bind(scope, len(kept), item)
+			ok, err := keep(scope)
+			if err != nil {
+				return err
+			}
+			if ok {
+				kept = append(kept, item)
+			}
+		}
+		s.items, s.index, s.at, s.length = kept, nil, 0, len(kept)
+		return nil
+	}
+	pull, kept := s.pull, 0
+	s.length = -1
+	s.pull = func() (loopItem, bool, error) {
+		for {
+			item, ok, err := pull()
+			if !ok || err != nil {
+				return item, ok, err
+			}
+			bind(scope, kept, item)
+			if ok, err = keep(scope); err != nil {
+				return loopItem{}, false, err
+			}
+			if ok {
+				kept++
+				return item, true, nil
+			}
+		}
+	}
+	return nil
+}
+
+// loopSource returns the items a for tag visits in iterable, in order:
+//   - a slice's or array's elements
+//   - a map's entries, sorted by key
+//   - a struct's visible fields, in declaration order
+//   - a string's characters
+//   - a range() call's integers
+//   - what a channel delivers until it is closed
+//   - what an iter.Seq or iter.Seq2 yields
+//
+// A slice's elements are read as the loop reaches them and the last three
+// lazily, and nil iterates nothing.
+func (r *TokenRenderer) loopSource(iterable any, expr string) (*loopSource, error) {
+	switch v := iterable.(type) {
+	case nil:
+		return &loopSource{}, nil
+	case intRange:
+		return v.source(), nil
+	case string:
+		return stringSource(v), nil
+	default:
+	}
+	rv := reflect.ValueOf(iterable)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return &loopSource{}, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return &loopSource{
+			length: rv.Len(),
+			index: func(i int) loopItem {
+				return loopItem{value: rv.Index(i).Interface()}
+			},
+		}, nil
+	case reflect.Map:
+		keys := rv.MapKeys()
+		if rv.Type().Key().Kind() == reflect.String {
+			// string-keyed maps (the common case): compare the key strings
+			// directly, avoiding the fmt.Sprint + interface boxing the generic
+			// path allocates on every comparison.
+			sort.Slice(keys, func(a, b int) bool {
+				return keys[a].String() < keys[b].String()
+			})
+		} else {
+			sort.Slice(keys, func(a, b int) bool {
+				return fmt.Sprint(keys[a].Interface()) < fmt.Sprint(keys[b].Interface())
+			})
+		}
+		items := make([]loopItem, len(keys))
+		for i, k := range keys {
+			items[i] = loopItem{key: k.Interface(), value: rv.MapIndex(k).Interface()}
+		}
+		return &loopSource{items: items, length: len(items), keyed: true}, nil
+	case reflect.Struct:
+		// a struct iterates its visible fields in declaration order, bound like a
+		// map's entries, so a DTO can be listed without converting it to a map
+		var items []loopItem
+		if err := r.fields.Each(rv, func(name string, value any) error {
+			items = append(items, loopItem{key: name, value: value})
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return &loopSource{items: items, length: len(items), keyed: true}, nil
+	case reflect.String:
+		return stringSource(rv.String()), nil
+	case reflect.Chan:
+		if rv.Type().ChanDir()&reflect.RecvDir == 0 {
+			return nil, fmt.Errorf("for: %q is a send-only channel", expr)
+		}
+		if rv.IsNil() {
+			return &loopSource{}, nil
+		}
+		return r.chanSource(rv), nil
+	case reflect.Func:
+		if src, ok := seqSource(rv); ok {
+			return src, nil
+		}
+	default:
+	}
+	return nil, fmt.Errorf("for: %q is not iterable (got %s)", expr, rv.Kind())
+}
+
+// stringSource visits the characters of s, each as a string of its own.
+func stringSource(s string) *loopSource {
+	rest := s
+	return &loopSource{
+		length: utf8.RuneCountInString(s),
+		pull: func() (loopItem, bool, error) {
+			if rest == "" {
+				return loopItem{}, false, nil
+			}
+			_, size := utf8.DecodeRuneInString(rest)
+			c := rest[:size]
+			rest = rest[size:]
+			return loopItem{value: c}, true, nil
+		},
+	}
+}
+
+// chanSource receives from ch until it is closed, or until the render is
+// canceled while it waits.
+func (r *TokenRenderer) chanSource(ch reflect.Value) *loopSource {
+	cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: ch}}
+	if r.done != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(r.done)})
+	}
+	return &loopSource{
+		length: -1,
+		pull: func() (loopItem, bool, error) {
+			chosen, value, ok := reflect.Select(cases)
+			if chosen == 1 {
+				return loopItem{}, false, r.canceled()
+			}
+			if !ok {
+				return loopItem{}, false, nil
+			}
+			return loopItem{value: value.Interface()}, true, nil
+		},
+	}
+}
+
+// rangeName is the call that counts through integers as a for iterable.
+const rangeName = "range"
+
+// intRange is what range(start, stop, step) evaluates to: the integers from
+// start up to but not including stop, step apart, counting down for a negative
+// step.
+type intRange struct {
+	start, stop, step int
+}
+
+// length is the number of integers the range holds.
+func (g intRange) length() int {
+	switch {
+	case g.step > 0 && g.stop > g.start:
+		return (g.stop - g.start + g.step - 1) / g.step
+	case g.step < 0 && g.stop < g.start:
+		return (g.start - g.stop - g.step - 1) / -g.step
+	default:
+	}
+	return 0
+}
+
+func (g intRange) source() *loopSource {
+	n, i := g.length(), 0
+	return &loopSource{
+		length: n,
+		pull: func() (loopItem, bool, error) {
+			if i >= n {
+				return loopItem{}, false, nil
+			}
+			i++
+			return loopItem{value: g.start + (i-1)*g.step}, true, nil
+		},
+	}
+}
+
+// rangeNode is a range() call, `range(stop)`, `range(start, stop)` or
+// `range(start, stop, step)`.
+type rangeNode struct {
+	args []exprNode
+}
+
+func (n *rangeNode) eval(r *TokenRenderer, vars map[string]any) (any, error) {
+	bounds := make([]int, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(r, vars)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := functions.ValueInt(value)
+		if !ok {
+			return nil, fmt.Errorf("%s() takes integers, got %T", rangeName, value)
+		}
+		bounds[i] = b
+	}
+	g := intRange{stop: bounds[0], step: 1}
+	if len(bounds) > 1 {
+		g.start, g.stop = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		g.step = bounds[2]
+	}
+	if g.step == 0 {
+		return nil, fmt.Errorf("%s() step must not be 0", rangeName)
+	}
+	return g, nil
+}
+
+// isRangeCall reports whether a for iterable is a range() call.
+func isRangeCall(expr string) bool {
+	name, _, ok := splitCall(strings.TrimSpace(expr))
+	return ok && name == rangeName
+}
+
+// parseRange parses a range() call. Offsets in the error are relative to s.
+func (p *StringParser) parseRange(s string) (exprNode, *parseError) {
+	_, inner, _ := splitCall(s)
+	innerAt := len(rangeName) + 1
+	parts, err := splitArgs(inner)
+	if err != nil {
+		return nil, err.shift(innerAt)
+	}
+	if len(parts) == 0 || len(parts) > 3 {
+		return nil, errAt(0, "%s() takes 1 to 3 arguments, got %d", rangeName, len(parts))
+	}
+	node := &rangeNode{}
+	for _, part := range parts {
+		if _, _, named := cutNamed(part.text); named {
+			return nil, errAt(innerAt+part.at, "%s() takes no named arguments", rangeName)
+		}
+		arg, err := p.parseExpr(part.text)
+		if err != nil {
+			return nil, err.shift(innerAt + part.at)
+		}
+		node.args = append(node.args, arg)
+	}
+	return node, nil
+}
+
+// loopSignal is how a break or continue tag unwinds the body of the loop it
+// sits in, up to renderFor. The parser rejects either one outside a loop, so
+// it reaches a caller only from a hand-built token stream.
+type loopSignal struct{ name string }
+
+func (s *loopSignal) Error() string { return s.name + " outside a for loop" }
+
+var (
+	errBreak    = &loopSignal{name: "break"}
+	errContinue = &loopSignal{name: "continue"}
+)
+
+// forFilterOf returns the condition of a ForToken's "if" filter clause, or nil
+// when it has none, preferring the tree cached at parse time.
+func (r *TokenRenderer) forFilterOf(token Token) (exprNode, error) {
+	if bt, ok := token.(BaseToken); ok && (bt.parsedFilter != nil || bt.parsedExpr != nil) {
+		return bt.parsedFilter, nil
+	}
+	_, expr := parseForExpr(token.Raw())
+	_, cond, filtered := cutForFilter(expr)
+	if !filtered {
+		return nil, nil
+	}
+	node, err := r.parser.parseExpr(cond)
+	if err != nil {
+		return nil, fmt.Errorf("invalid for filter %q: %s", cond, err.msg)
+	}
+	return node, nil
+}
@@ -0,0 +1,286 @@
+package sintax
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions/fs"
+)
+
+func Test_E2E_LoopControl(t *testing.T) {
+	vars := map[string]any{
+		"items":   []any{1, 2, 3, 4, 5},
+		"none":    []any{},
+		"nothing": nil,
+		"txs": []any{
+			map[string]any{"id": "a", "amount": 10},
+			map[string]any{"id": "b", "amount": 0},
+			map[string]any{"id": "c", "amount": -3},
+			map[string]any{"id": "d", "amount": 7},
+		},
+		"prices": map[string]any{"apple": 3, "kiwi": 0, "pear": 2},
+		"rows":   [][]any{{1, 2}, {3, 4}},
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "else renders for an empty collection",
+			template: "{{ for x in none }}{{ x }}{{ else }}nothing{{ endfor }}",
+			want:     "nothing",
+		},
+		{
+			name:     "else renders for a nil collection",
+			template: "{{ for x in nothing }}{{ x }}{{ else }}nothing{{ endfor }}",
+			want:     "nothing",
+		},
+		{
+			name:     "else is skipped when there are items",
+			template: "{{ for x in items }}{{ x }}{{ else }}nothing{{ endfor }}",
+			want:     "12345",
+		},
+		{
+			name:     "an if's else inside the body belongs to the if",
+			template: "{{ for x in items }}{{ if x > 3 }}+{{ else }}-{{ endif }}{{ else }}nothing{{ endfor }}",
+			want:     "---++",
+		},
+		{
+			name:     "a for's else inside an if belongs to the for",
+			template: "{{ if items }}{{ for x in none }}{{ x }}{{ else }}empty{{ endfor }}{{ else }}no items{{ endif }}",
+			want:     "empty",
+		},
+		{
+			name:     "break stops the loop",
+			template: "{{ for x in items }}{{ if x == 3 }}{{ break }}{{ endif }}{{ x }}{{ endfor }}",
+			want:     "12",
+		},
+		{
+			name:     "continue skips the rest of the body",
+			template: "{{ for x in items }}{{ if x == 2 or x == 4 }}{{ continue }}{{ endif }}{{ x }}{{ endfor }}",
+			want:     "135",
+		},
+		{
+			name:     "break leaves only the innermost loop",
+			template: "{{ for row in rows }}[{{ for c in row }}{{ c }}{{ break }}{{ endfor }}]{{ endfor }}",
+			want:     "[1][3]",
+		},
+		{
+			name:     "filter skips items",
+			template: "{{ for tx in txs if tx | key:'amount' | gt:0 }}{{ tx | key:'id' }}{{ endfor }}",
+			want:     "ad",
+		},
+		{
+			name:     "positions count only the kept items",
+			template: "{{ for tx in txs if tx | key:'amount' | gt:0 }}{{ tx_index }}{{ tx_first ? 'F' : '' }}{{ tx_last ? 'L' : '' }} {{ endfor }}",
+			want:     "0F 1L ",
+		},
+		{
+			name:     "the index name follows the kept items",
+			template: "{{ for i, x in items if x > 2 }}{{ i }}:{{ x }} {{ endfor }}",
+			want:     "0:3 1:4 2:5 ",
+		},
+		{
+			name:     "filter sees a map's key",
+			template: "{{ for name, price in prices if price > 0 }}{{ name }} {{ endfor }}|{{ for p in prices if p_key != 'apple' }}{{ p_key }} {{ endfor }}",
+			want:     "apple pear |kiwi pear ",
+		},
+		{
+			name:     "else renders when the filter keeps nothing",
+			template: "{{ for x in items if x > 9 }}{{ x }}{{ else }}none over 9{{ endfor }}",
+			want:     "none over 9",
+		},
+		{
+			name:     "block lines are trimmed",
+			template: "{{ for x in items }}\n  {{ if x > 2 }}\n    {{ break }}\n  {{ endif }}\n{{ x }}\n{{ else }}\nnone\n{{ endfor }}\n",
+			want:     "1\n2\n",
+		},
+	}
+
+	s := New(builtins())
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+// A break inside an included template's own loop stops that loop only.
+func Test_E2E_LoopControl_Include(t *testing.T) {
+	loader := fs.MapLoader{
+		"first.tpl": "{{ for x in xs }}{{ x }}{{ break }}{{ endfor }}",
+	}
+	s := New(builtins(), WithLoader(loader))
+
+	out, err := s.RenderString(`{{ for xs in rows }}{{ include "first.tpl" }};{{ endfor }}`, map[string]any{"rows": [][]any{{1, 2}, {3, 4}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "1;3;", out)
+}
+
+func Test_E2E_LoopBinding(t *testing.T) {
+	vars := map[string]any{
+		"items":  []any{"a", "b", "c"},
+		"rows":   [][]any{{1, 2}, {3}},
+		"prices": map[string]any{"apple": 3, "kiwi": 0, "pear": 2},
+		"loop":   "outer",
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "positions",
+			template: "{{ for x in items }}{{ loop.index }}{{ loop.index1 }}{{ loop.revindex }}{{ loop.revindex1 }}/{{ loop.length }} {{ endfor }}",
+			want:     "0123/3 1212/3 2301/3 ",
+		},
+		{
+			name:     "first and last",
+			template: "{{ for x in items }}{{ if loop.first }}[{{ endif }}{{ x }}{{ if loop.last }}]{{ else }},{{ endif }}{{ endfor }}",
+			want:     "[a,b,c]",
+		},
+		{
+			name:     "neighbours are missing at the ends",
+			template: "{{ for x in items }}{{ loop.previtem | default:'-' }}{{ x }}{{ loop.nextitem | default:'-' }} {{ endfor }}",
+			want:     "-ab abc bc- ",
+		},
+		{
+			name:     "cycle",
+			template: "{{ for x in items }}{{ loop.cycle('odd', 'even') }} {{ endfor }}",
+			want:     "odd even odd ",
+		},
+		{
+			name:     "nested loops see their parent",
+			template: "{{ for row in rows }}{{ for c in row }}{{ loop.depth }}:{{ loop.parent.index }}.{{ loop.index }}{{ loop.parent.parent | default:'' }} {{ endfor }}{{ endfor }}",
+			want:     "2:0.0 2:0.1 2:1.0 ",
+		},
+		{
+			name:     "counts the kept items of a filtered loop",
+			template: "{{ for p in prices if p > 0 }}{{ p_key }} {{ loop.index1 }}/{{ loop.length }} {{ endfor }}",
+			want:     "apple 1/2 pear 2/2 ",
+		},
+		{
+			name:     "flat names keep working",
+			template: "{{ for x in items }}{{ x_index }}{{ x_first ? 'F' : '' }}{{ x_last ? 'L' : '' }} {{ endfor }}",
+			want:     "0F 1 2L ",
+		},
+		{
+			name:     "an unknown field is a miss",
+			template: "{{ for x in items }}{{ loop.nope | default:'?' }}{{ endfor }}",
+			want:     "???",
+		},
+		{
+			name:     "a bare loop renders its position",
+			template: "{{ for x in items }}{{ loop }}, {{ endfor }}{{ for row in rows }}{{ for c in row }}{{ loop.parent }} {{ endfor }}{{ endfor }}",
+			want:     "loop 1/3, loop 2/3, loop 3/3, loop 1/2 loop 1/2 loop 2/2 ",
+		},
+		{
+			name:     "the binding hides a variable named loop for the body only",
+			template: "{{ loop }} {{ for x in items }}{{ loop.index }}{{ endfor }} {{ loop }}",
+			want:     "outer 012 outer",
+		},
+		{
+			name:     "a loop variable named loop wins",
+			template: "{{ for loop in items }}{{ loop }}{{ endfor }}",
+			want:     "abc",
+		},
+	}
+
+	s := New(builtins())
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+func Test_E2E_LoopSources(t *testing.T) {
+	vars := map[string]any{
+		"n":    3,
+		"word": "héllo",
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "range with a step",
+			template: "{{ for i in range(1, 10, 2) }}{{ i }}{{ loop.last ? '.' : ',' }}{{ endfor }}",
+			want:     "1,3,5,7,9.",
+		},
+		{
+			name:     "range counting down",
+			template: "{{ for i in range(5, 0, -2) }}{{ i }} {{ endfor }}",
+			want:     "5 3 1 ",
+		},
+		{
+			name:     "range up to a variable",
+			template: "{{ for i in range(n) }}{{ i }}/{{ loop.length }} {{ endfor }}",
+			want:     "0/3 1/3 2/3 ",
+		},
+		{
+			name:     "empty range",
+			template: "{{ for i in range(3, 3) }}{{ i }}{{ else }}none{{ endfor }}",
+			want:     "none",
+		},
+		{
+			name:     "filtered range",
+			template: "{{ for i in range(10) if i > 6 }}{{ i }}{{ i_last ? '' : ',' }}{{ endfor }}",
+			want:     "7,8,9",
+		},
+		{
+			name:     "string characters",
+			template: "{{ for c in word }}[{{ c }}]{{ endfor }} {{ for i, c in word }}{{ i }}{{ endfor }}",
+			want:     "[h][é][l][l][o] 01234",
+		},
+	}
+
+	s := New(builtins())
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+
+	_, err := s.RenderString("{{ for i in range(1, 5, 0) }}{{ i }}{{ endfor }}", nil)
+	assert.Error(t, err)
+	_, err = s.RenderString("{{ for i in range('a') }}{{ i }}{{ endfor }}", nil)
+	assert.Error(t, err)
+}
+
+// A channel is read as the loop goes, so a producer that waits on an
+// unbuffered send still streams through, and the loop ends when it closes.
+func Test_E2E_LoopSources_Channel(t *testing.T) {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := range 4 {
+			ch <- i
+		}
+	}()
+
+	out, err := New(builtins()).RenderString("{{ for x in ch }}{{ x }}{{ loop.last ? '.' : ',' }}{{ loop.length | default:'' }}{{ endfor }}", map[string]any{"ch": ch})
+	assert.NoError(t, err)
+	assert.Equal(t, "0,1,2,3.", out)
+}
+
+func Test_E2E_LoopSources_ChannelCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := New(builtins()).RenderContext(ctx, "{{ for x in ch }}{{ x }}{{ endfor }}", map[string]any{"ch": make(chan int)})
+	assert.ErrorIs(t, err, ErrCanceled)
+}
@@ -0,0 +1,52 @@
+//go:build go1.23
+
+package sintax
+
+import (
+	"iter"
+	"reflect"
+)
+
+// seqSource reads an iter.Seq or iter.Seq2 one item at a time, pulling each
+// from the iterator as the loop asks for it, and reports false for any other
+// function. A Seq2 yields keyed items, bound like a map's entries.
+func seqSource(fn reflect.Value) (*loopSource, bool) {
+	t := fn.Type()
+	switch {
+	case t.CanSeq2():
+		if fn.IsNil() {
+			return &loopSource{}, true
+		}
+		next, stop := iter.Pull2(fn.Seq2())
+		return &loopSource{
+			length: -1,
+			keyed:  true,
+			stop:   stop,
+			pull: func() (loopItem, bool, error) {
+				k, v, ok := next()
+				if !ok {
+					return loopItem{}, false, nil
+				}
+				return loopItem{key: k.Interface(), value: v.Interface()}, true, nil
+			},
+		}, true
+	case t.CanSeq():
+		if fn.IsNil() {
+			return &loopSource{}, true
+		}
+		next, stop := iter.Pull(fn.Seq())
+		return &loopSource{
+			length: -1,
+			stop:   stop,
+			pull: func() (loopItem, bool, error) {
+				v, ok := next()
+				if !ok {
+					return loopItem{}, false, nil
+				}
+				return loopItem{value: v.Interface()}, true, nil
+			},
+		}, true
+	default:
+	}
+	return nil, false
+}
@@ -0,0 +1,49 @@
+//go:build go1.23
+
+package sintax
+
+import (
+	"iter"
+	"strings"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+// naturals never ends, so a loop over it only finishes by breaking, which
+// proves the loop pulls items as it goes. stopped reports whether the
+// iterator was released.
+func naturals(stopped *bool) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		defer func() { *stopped = true }()
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func Test_E2E_LoopSources_Seq(t *testing.T) {
+	var stopped bool
+	var sb strings.Builder
+	err := New(builtins()).RenderTo(&sb, "{{ for x in xs if x > 2 }}{{ if x > 6 }}{{ break }}{{ endif }}{{ x }} {{ endfor }}", map[string]any{"xs": naturals(&stopped)})
+	assert.NoError(t, err)
+	assert.Equal(t, "3 4 5 6 ", sb.String())
+	assert.True(t, stopped, "the iterator was not released")
+}
+
+func Test_E2E_LoopSources_Seq2(t *testing.T) {
+	pairs := func(yield func(string, int) bool) {
+		for i, k := range []string{"b", "a"} {
+			if !yield(k, i) {
+				return
+			}
+		}
+	}
+	var seq2 iter.Seq2[string, int] = pairs
+
+	out, err := New(builtins()).RenderString("{{ for k, v in pairs }}{{ k }}={{ v }}{{ loop.last ? '' : ',' }}{{ endfor }}|{{ for v in pairs }}{{ v_key }}{{ endfor }}", map[string]any{"pairs": seq2})
+	assert.NoError(t, err)
+	assert.Equal(t, "b=0,a=1|ba", out)
+}
@@ -0,0 +1,11 @@
+//go:build !go1.23
+
+package sintax
+
+import "reflect"
+
+// seqSource reports false for every function before Go 1.23, which has no
+// iter.Seq to read.
+func seqSource(reflect.Value) (*loopSource, bool) {
+	return nil, false
+}
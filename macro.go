@@ -0,0 +1,543 @@
+package sintax
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// macroSig is what a macro tag declares: `macro address(name, bic='X')`.
+type macroSig struct {
+	name   string
+	params []macroParam
+}
+
+// macroParam is one declared parameter. A parameter with a default may be left
+// out of a call, and def is the literal it then takes.
+type macroParam struct {
+	name string
+	def  exprNode
+}
+
+// importSpec is what a from-import tag asks for: `from "sepa.tpl" import
+// address, account as acct`.
+type importSpec struct {
+	source string
+	names  []importName
+}
+
+// importName is one imported macro and the name it is called by here.
+type importName struct {
+	name, alias string
+}
+
+// macro is a macro ready to call: its signature, the tokens of its body, and
+// the macros of the template that defined it, which are the only other macros
+// its body can call.
+type macro struct {
+	sig   *macroSig
+	body  []Token
+	scope macros
+	// template is the name of the template the macro is defined in.
+	template string
+}
+
+// macros are the macros a template can call, keyed by the name it calls them by.
+type macros map[string]*macro
+
+// callNode is a macro call, `address(debtor_name, debtor_iban, bic='X')`. The
+// macro is looked up when the call is evaluated rather than when it is parsed,
+// since an imported one is only known once its template has been loaded.
+type callNode struct {
+	name string
+	args []callArg
+}
+
+// callArg is one argument of a call. name is empty for a positional argument.
+type callArg struct {
+	name  string
+	value exprNode
+}
+
+func (n *callNode) eval(r *TokenRenderer, vars map[string]any) (any, error) {
+	switch n.name {
+	case superName:
+		return r.callSuper(vars)
+	case cycleName:
+		return r.cycle(n, vars)
+	default:
+	}
+	m, ok := r.macros[n.name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMacroNotFound, n.name)
+	}
+	args, err := m.bind(n, r, vars)
+	if err != nil {
+		return nil, err
+	}
+	return r.callMacro(m, args)
+}
+
+// bind evaluates the call's arguments against the caller's vars and binds them
+// to the macro's parameters, positional ones first, then named ones, then the
+// defaults of the parameters left over. The result is the macro body's whole
+// scope, since a macro sees nothing of its caller beyond what it was passed.
+func (m *macro) bind(call *callNode, r *TokenRenderer, vars map[string]any) (map[string]any, error) {
+	params := m.sig.params
+	args := make(map[string]any, len(params))
+	positional := 0
+	for _, arg := range call.args {
+		if arg.name == "" {
+			positional++
+		}
+	}
+	if positional > len(params) {
+		return nil, fmt.Errorf("macro %s takes %d arguments, got %d", m.sig.name, len(params), positional)
+	}
+
+	for i, arg := range call.args {
+		name := arg.name
+		if name == "" {
+			name = params[i].name
+		} else if !m.hasParam(name) {
+			return nil, fmt.Errorf("macro %s has no parameter %s", m.sig.name, name)
+		}
+		if _, dup := args[name]; dup {
+			return nil, fmt.Errorf("macro %s got argument %s twice", m.sig.name, name)
+		}
+		value, err := arg.value.eval(r, vars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate argument %s of macro %s: %w", name, m.sig.name, err)
+		}
+		args[name] = value
+	}
+
+	for _, p := range params {
+		if _, ok := args[p.name]; ok {
+			continue
+		}
+		if p.def == nil {
+			return nil, fmt.Errorf("macro %s is missing argument %s", m.sig.name, p.name)
+		}
+		value, err := p.def.eval(r, args)
+		if err != nil {
+			return nil, err
+		}
+		args[p.name] = value
+	}
+	return args, nil
+}
+
+func (m *macro) hasParam(name string) bool {
+	for _, p := range m.sig.params {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// callMacro renders m's body against args into a string. The body renders one
+// level deeper than its caller, so a macro calling itself without end is
+// stopped by the same guard as a template rendering itself.
+func (r *TokenRenderer) callMacro(m *macro, args map[string]any) (any, error) {
+	if r.depth+1 > r.maxDepth {
+		return nil, fmt.Errorf("failed to call macro %s: %w", m.sig.name, ErrMaxDepthExceeded)
+	}
+	child := *r
+	child.depth = r.depth + 1
+	child.macros = m.scope
+	child.block = nil
+	child.loop = nil
+	child.name = m.template
+
+	var sb strings.Builder
+	if _, err := child.renderRange(r.newOutput(&sb), m.body, 0, len(m.body), args); err != nil {
+		return nil, fmt.Errorf("failed to call macro %s: %w", m.sig.name, err)
+	}
+	return r.markup(sb.String()), nil
+}
+
+// define binds the macros tokens defines and imports, ahead of rendering them,
+// so a call may come before the definition it calls. Parse keeps both tags at
+// the top level of a template, which is all define looks at.
+func (r *TokenRenderer) define(tokens []Token) error {
+	r.macros = nil
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].Type() {
+		case MacroToken:
+			end, err := findBlockEnd(tokens, i, len(tokens), MacroToken, MacroEndToken)
+			if err != nil {
+				return err
+			}
+			sig, err := macroSigOf(r.parser, tokens[i])
+			if err != nil {
+				return err
+			}
+			if err := r.bindMacro(sig.name, &macro{sig: sig, body: tokens[i+1 : end], template: r.name}); err != nil {
+				return err
+			}
+			i = end
+		case ImportToken:
+			if err := r.importMacros(tokens[i]); err != nil {
+				return err
+			}
+		default:
+		}
+	}
+	for _, m := range r.macros {
+		if m.scope == nil {
+			m.scope = r.macros
+		}
+	}
+	return nil
+}
+
+// bindMacro makes m callable as name in the template being defined.
+func (r *TokenRenderer) bindMacro(name string, m *macro) error {
+	if _, dup := r.macros[name]; dup {
+		return fmt.Errorf("macro %s is defined twice", name)
+	}
+	if r.macros == nil {
+		r.macros = make(macros)
+	}
+	r.macros[name] = m
+	return nil
+}
+
+// importMacros loads the template an import tag names through the engine's
+// loader and binds the macros it asks for. The imported template renders
+// nothing, it only defines. Loading it counts as a level of nesting, so two
+// templates importing each other stop at ErrMaxDepthExceeded.
+func (r *TokenRenderer) importMacros(token Token) error {
+	spec, err := importSpecOf(token)
+	if err != nil {
+		return err
+	}
+	if r.depth+1 > r.maxDepth {
+		return fmt.Errorf("failed to import from %q: %w", spec.source, ErrMaxDepthExceeded)
+	}
+	tokens, err := r.load(spec.source)
+	if err != nil {
+		return fmt.Errorf("failed to import from %q: %w", spec.source, err)
+	}
+
+	child := *r
+	child.depth = r.depth + 1
+	child.name = spec.source
+	if err := child.define(tokens); err != nil {
+		return fmt.Errorf("failed to import from %q: %w", spec.source, err)
+	}
+	for _, n := range spec.names {
+		m, ok := child.macros[n.name]
+		if !ok {
+			return fmt.Errorf("failed to import %s from %q: %w", n.name, spec.source, ErrMacroNotFound)
+		}
+		if err := r.bindMacro(n.alias, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// macroSigOf returns the signature a MacroToken declares, preferring the one
+// cached at parse time.
+func macroSigOf(p *StringParser, token Token) (*macroSig, error) {
+	if bt, ok := token.(BaseToken); ok && bt.parsedMacro != nil {
+		return bt.parsedMacro, nil
+	}
+	sig, err := p.parseMacro(token.Raw())
+	if err != nil {
+		return nil, fmt.Errorf("invalid macro %q: %s", token.Raw(), err.msg)
+	}
+	return sig, nil
+}
+
+// importSpecOf returns what an ImportToken imports, preferring the parse cached
+// at parse time.
+func importSpecOf(token Token) (*importSpec, error) {
+	if bt, ok := token.(BaseToken); ok && bt.parsedImport != nil {
+		return bt.parsedImport, nil
+	}
+	spec, err := parseImport(token.Raw())
+	if err != nil {
+		return nil, fmt.Errorf("invalid import %q: %s", token.Raw(), err.msg)
+	}
+	return spec, nil
+}
+
+// callHeadRe matches the start of a call, the name and its opening parenthesis.
+// loop.cycle is the one call whose name holds a dot.
+var callHeadRe = regexp.MustCompile(`^(?:loop\.cycle|[a-zA-Z_][a-zA-Z0-9_]*)\(`)
+
+// splitCall splits s into the name and argument list of a call, reporting false
+// when s is not a call as a whole. `f(a) | upper` is not one, since the
+// parenthesis closing f's arguments is not where s ends.
+func splitCall(s string) (name, args string, ok bool) {
+	head := callHeadRe.FindString(s)
+	if head == "" {
+		return "", "", false
+	}
+	end := matchingParen(s, len(head)-1)
+	if end != len(s)-1 {
+		return "", "", false
+	}
+	return head[:len(head)-1], s[len(head) : len(s)-1], true
+}
+
+// matchingParen returns the index of the parenthesis closing the one at open,
+// skipping quoted strings, or -1 when it is never closed.
+func matchingParen(s string, open int) int {
+	depth := 0
+	quote := byte(0)
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		default:
+		}
+	}
+	return -1
+}
+
+// splitArgs cuts an argument or parameter list on the commas that sit outside
+// quotes and brackets, keeping each part's offset. An empty list has no parts,
+// and an empty part between two commas is an error.
+func splitArgs(s string) ([]pipelinePart, *parseError) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var parts []pipelinePart
+	partStart, depth := 0, 0
+	quote, quoteAt := byte(0), 0
+	cut := func(end int) *parseError {
+		raw := s[partStart:end]
+		lead := len(raw) - len(strings.TrimLeft(raw, " \t\r\n"))
+		if strings.TrimSpace(raw) == "" {
+			return errAt(partStart, "empty argument")
+		}
+		parts = append(parts, pipelinePart{text: strings.TrimSpace(raw), at: partStart + lead})
+		return nil
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote, quoteAt = c, i
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			if err := cut(i); err != nil {
+				return nil, err
+			}
+			partStart = i + 1
+		default:
+		}
+	}
+	if quote != 0 {
+		return nil, errAt(quoteAt, "unterminated quoted string")
+	}
+	if err := cut(len(s)); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// namedArgRe matches a named argument or a parameter with a default, `name=`
+// followed by its value, without mistaking `a == b` for one.
+var namedArgRe = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)\s*=(?:[^=]|$)`)
+
+// cutNamed splits `name=value` into its name and the offset its value starts
+// at, reporting false for a part that is not named.
+func cutNamed(s string) (name string, valueAt int, ok bool) {
+	m := namedArgRe.FindStringSubmatch(s)
+	if m == nil {
+		return "", 0, false
+	}
+	return m[1], strings.IndexByte(s, '=') + 1, true
+}
+
+// parseCall parses a macro call. at is where text starts in the expression, for
+// errors to point inside the call.
+func (p *StringParser) parseCall(text string, at int) (exprNode, *parseError) {
+	name, inner, _ := splitCall(text)
+	innerAt := at + len(name) + 1
+	parts, err := splitArgs(inner)
+	if err != nil {
+		return nil, err.shift(innerAt)
+	}
+
+	if name == superName && len(parts) > 0 {
+		return nil, errAt(at, "%s() takes no arguments", superName)
+	}
+	if name == rangeName {
+		return nil, errAt(at, "%s() is only valid as the iterable of a for loop", rangeName)
+	}
+	if name == cycleName && len(parts) == 0 {
+		return nil, errAt(at, "%s() takes at least one value to cycle through", cycleName)
+	}
+	call := &callNode{name: name}
+	named := map[string]bool{}
+	for _, part := range parts {
+		partAt := innerAt + part.at
+		argName, valueAt, isNamed := cutNamed(part.text)
+		switch {
+		case isNamed && name == cycleName:
+			return nil, errAt(partAt, "%s() takes no named arguments", cycleName)
+		case isNamed && named[argName]:
+			return nil, errAt(partAt, "argument %s is given twice", argName)
+		case !isNamed && len(named) > 0:
+			return nil, errAt(partAt, "positional argument after a named one")
+		default:
+		}
+		value, err := p.parseExpr(part.text[valueAt:])
+		if err != nil {
+			return nil, err.shift(partAt + valueAt)
+		}
+		if isNamed {
+			named[argName] = true
+		}
+		call.args = append(call.args, callArg{name: argName, value: value})
+	}
+	return call, nil
+}
+
+// parseMacro parses the contents of a macro tag, `macro name(a, b, c='x')`.
+// Offsets in the error are relative to s, trimmed. A default is a literal, so a
+// macro means the same thing wherever it is called from.
+func (p *StringParser) parseMacro(s string) (*macroSig, *parseError) {
+	s = strings.TrimSpace(s)
+	rest := strings.TrimSpace(strings.TrimPrefix(s, "macro"))
+	restAt := len(s) - len(rest)
+	name, inner, ok := splitCall(rest)
+	if !ok {
+		return nil, errAt(0, "malformed macro, expected \"macro name(params)\"")
+	}
+	if name == superName {
+		return nil, errAt(restAt, "%s is reserved for calling a parent block", superName)
+	}
+	if name == cycleName {
+		return nil, errAt(restAt, "%s is reserved for the loop binding", cycleName)
+	}
+	if name == rangeName {
+		return nil, errAt(restAt, "%s is reserved for counting through integers", rangeName)
+	}
+	innerAt := restAt + len(name) + 1
+	parts, err := splitArgs(inner)
+	if err != nil {
+		return nil, err.shift(innerAt)
+	}
+
+	sig := &macroSig{name: name}
+	seen := map[string]bool{}
+	for _, part := range parts {
+		partAt := innerAt + part.at
+		param := macroParam{name: part.text}
+		if paramName, valueAt, isNamed := cutNamed(part.text); isNamed {
+			param.name = paramName
+			text := strings.TrimSpace(part.text[valueAt:])
+			value, ok := parseLiteral(text)
+			if text == "" || !ok {
+				return nil, errAt(partAt+valueAt, "default of parameter %s must be a literal", paramName)
+			}
+			param.def = &literalNode{value: value}
+		} else if len(sig.params) > 0 && sig.params[len(sig.params)-1].def != nil {
+			return nil, errAt(partAt, "parameter %s without a default follows one with a default", part.text)
+		}
+		if !identRe.MatchString(param.name) {
+			return nil, errAt(partAt, "%q is not a parameter name", param.name)
+		}
+		if seen[param.name] {
+			return nil, errAt(partAt, "parameter %s is declared twice", param.name)
+		}
+		seen[param.name] = true
+		sig.params = append(sig.params, param)
+	}
+	return sig, nil
+}
+
+// parseImport parses the contents of a from-import tag,
+// `from "sepa.tpl" import address, account as acct`. Offsets in the error are
+// relative to s, trimmed.
+func parseImport(s string) (*importSpec, *parseError) {
+	const malformed = "malformed from, expected \"from \\\"file.tpl\\\" import name, other as alias\""
+	s = strings.TrimSpace(s)
+	rest := strings.TrimSpace(strings.TrimPrefix(s, "from"))
+	restAt := len(s) - len(rest)
+	if rest == "" || rest[0] != '"' && rest[0] != '\'' {
+		return nil, errAt(restAt, "%s", malformed)
+	}
+	end := closingQuote(rest)
+	if end < 0 {
+		return nil, errAt(restAt, "unterminated quoted string")
+	}
+	spec := &importSpec{source: unquote(rest[:end+1], rest[:1])}
+
+	names := strings.TrimSpace(rest[end+1:])
+	namesAt := len(s) - len(names)
+	list, ok := strings.CutPrefix(names, "import ")
+	if !ok {
+		return nil, errAt(namesAt, "%s", malformed)
+	}
+	listAt := namesAt + len("import ")
+	parts, err := splitArgs(list)
+	if err != nil {
+		return nil, err.shift(listAt)
+	}
+	if len(parts) == 0 {
+		return nil, errAt(namesAt, "import names no macro")
+	}
+	for _, part := range parts {
+		fields := strings.Fields(part.text)
+		n := importName{name: fields[0], alias: fields[0]}
+		switch {
+		case len(fields) == 3 && fields[1] == "as":
+			n.alias = fields[2]
+		case len(fields) != 1:
+			return nil, errAt(listAt+part.at, "malformed import %q, expected \"name\" or \"name as alias\"", part.text)
+		default:
+		}
+		if !identRe.MatchString(n.name) || !identRe.MatchString(n.alias) {
+			return nil, errAt(listAt+part.at, "malformed import %q, expected \"name\" or \"name as alias\"", part.text)
+		}
+		spec.names = append(spec.names, n)
+	}
+	return spec, nil
+}
+
+// closingQuote returns the index of the quote closing the one s starts with, or
+// -1 when there is none.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case s[0]:
+			return i
+		default:
+		}
+	}
+	return -1
+}
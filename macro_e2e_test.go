@@ -0,0 +1,186 @@
+package sintax
+
+import (
+	iofs "io/fs"
+	"strings"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions/fs"
+)
+
+func Test_E2E_Macro(t *testing.T) {
+	vars := map[string]any{
+		"debtor_name": "Ada",
+		"debtor_iban": "DE89",
+		"debtor_bic":  "COBADEFF",
+		"items":       []any{"a", "b"},
+	}
+	const address = "{{ macro address(name, iban, bic='') }}<{{ name }}|{{ iban }}|{{ bic }}>{{ endmacro }}"
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "positional arguments and a default",
+			template: address + "{{ address(debtor_name, debtor_iban) }}",
+			want:     "<Ada|DE89|>",
+		},
+		{
+			name:     "every argument positional",
+			template: address + "{{ address(debtor_name, debtor_iban, debtor_bic) }}",
+			want:     "<Ada|DE89|COBADEFF>",
+		},
+		{
+			name:     "named arguments in any order",
+			template: address + "{{ address(iban='X1', bic=debtor_bic, name=debtor_name | upper) }}",
+			want:     "<ADA|X1|COBADEFF>",
+		},
+		{
+			name:     "positional then named",
+			template: address + "{{ address(debtor_name, debtor_iban, bic='B') }}",
+			want:     "<Ada|DE89|B>",
+		},
+		{
+			name:     "called before it is defined",
+			template: "{{ greet('x') }}{{ macro greet(who) }}hi {{ who }}{{ endmacro }}",
+			want:     "hi x",
+		},
+		{
+			name:     "called in a loop",
+			template: "{{ macro li(x) }}<li>{{ x }}</li>{{ endmacro }}{{ for x in items }}{{ li(x) }}{{ endfor }}",
+			want:     "<li>a</li><li>b</li>",
+		},
+		{
+			name:     "a macro calls another",
+			template: "{{ macro b(x) }}[{{ x }}]{{ endmacro }}{{ macro a(x) }}{{ b(x) }}{{ b(x) }}{{ endmacro }}{{ a('y') }}",
+			want:     "[y][y]",
+		},
+		{
+			name:     "a call is a value in an expression",
+			template: "{{ macro tag(x) }}<{{ x }}>{{ endmacro }}{{ set t = tag('b') }}{{ t | upper }} {{ items ? tag('y') : '' }}",
+			want:     "<B> <y>",
+		},
+		{
+			name:     "a macro body has its own loops and sets",
+			template: "{{ macro list(xs, sep=', ') }}{{ for x in xs }}{{ set v = x | upper }}{{ v }}{{ if not x_last }}{{ sep }}{{ endif }}{{ endfor }}{{ endmacro }}{{ list(items) }}/{{ list(xs=items, sep='-') }}",
+			want:     "A, B/A-B",
+		},
+		{
+			name:     "definition lines are trimmed",
+			template: "{{ macro row(x) }}\n  <Row>{{ x }}</Row>\n{{ endmacro }}\n{{ row(1) }}",
+			want:     "  <Row>1</Row>\n",
+		},
+	}
+
+	s := New(builtins())
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+// A macro sees its arguments and nothing of its caller, and what it sets stays
+// inside the call.
+func Test_E2E_Macro_IsolatedScope(t *testing.T) {
+	s := New(builtins())
+
+	_, err := s.Render("{{ macro m() }}{{ secret }}{{ endmacro }}{{ m() }}", map[string]any{"secret": "s"})
+	assert.ErrorIs(t, err, ErrVariableNotFound)
+
+	out, err := s.Render("{{ macro m(x) }}{{ set x = 'inner' }}{{ x }}{{ endmacro }}{{ m('a') }} {{ x }}", map[string]any{"x": "outer"})
+	assert.NoError(t, err)
+	assert.Equal(t, "inner outer", out)
+}
+
+func Test_E2E_Macro_CallErrors(t *testing.T) {
+	const m = "{{ macro m(a, b='') }}{{ a }}{{ b }}{{ endmacro }}"
+
+	testCases := []struct {
+		name     string
+		template string
+		message  string
+	}{
+		{name: "too many arguments", template: m + "{{ m(1, 2, 3) }}", message: "macro m takes 2 arguments, got 3"},
+		{name: "unknown parameter", template: m + "{{ m(1, c=2) }}", message: "macro m has no parameter c"},
+		{name: "argument given twice", template: m + "{{ m(1, a=2) }}", message: "macro m got argument a twice"},
+		{name: "missing argument", template: m + "{{ m(b=2) }}", message: "macro m is missing argument a"},
+		{name: "argument misses", template: m + "{{ m(nope) }}", message: "failed to evaluate argument a of macro m"},
+	}
+
+	s := New(builtins())
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Render(tt.template, nil)
+			assert.Error(t, err)
+			assert.True(t, strings.Contains(err.Error(), tt.message), "message %q does not mention %q", err, tt.message)
+		})
+	}
+
+	_, err := s.Render("{{ nope(1) }}", nil)
+	assert.ErrorIs(t, err, ErrMacroNotFound)
+}
+
+// A macro may call itself, so it can walk a tree, and one that does so without
+// end is stopped by the nesting guard.
+func Test_E2E_Macro_Recursion(t *testing.T) {
+	s := New(builtins())
+	tree := map[string]any{"name": "root", "children": []any{
+		map[string]any{"name": "a", "children": []any{map[string]any{"name": "a1"}}},
+		map[string]any{"name": "b"},
+	}}
+
+	out, err := s.Render("{{ macro node(n) }}{{ n | key:'name' }}{{ for c in n | key:'children' | default:[] }}({{ node(c) }}){{ endfor }}{{ endmacro }}{{ node(tree) }}", map[string]any{"tree": tree})
+	assert.NoError(t, err)
+	assert.Equal(t, "root(a(a1))(b)", out)
+
+	_, err = s.Render("{{ macro loop(x) }}{{ loop(x) }}{{ endmacro }}{{ loop(1) }}", nil)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+}
+
+func Test_E2E_Macro_Import(t *testing.T) {
+	loader := fs.MapLoader{
+		"sepa.tpl": "{{ macro address(name, iban) }}<Nm>{{ name }}</Nm><IBAN>{{ iban | upper }}</IBAN>{{ endmacro }}" +
+			"{{ macro party(p) }}<Pty>{{ address(p | key:'name', p | key:'iban') }}</Pty>{{ endmacro }}" +
+			"ignored text {{ never_rendered }}",
+		"both.tpl":   "{{ from \"sepa.tpl\" import address }}{{ macro wrapped(n) }}({{ address(n, 'x') }}){{ endmacro }}",
+		"broken.tpl": "{{ macro m( }}",
+		"cycle.tpl":  "{{ from \"cycle.tpl\" import m }}{{ macro m() }}{{ endmacro }}",
+	}
+	vars := map[string]any{"debtor": map[string]any{"name": "Ada", "iban": "de89"}}
+	s := New(builtins(), WithLoader(loader))
+
+	out, err := s.Render(`{{ from "sepa.tpl" import address, party as debtor_party }}{{ address('Bob', 'fr76') }}|{{ debtor_party(debtor) }}`, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, "<Nm>Bob</Nm><IBAN>FR76</IBAN>|<Pty><Nm>Ada</Nm><IBAN>DE89</IBAN></Pty>", out)
+
+	// an imported macro calls what its own template imported
+	out, err = s.Render(`{{ from "both.tpl" import wrapped }}{{ wrapped('n') }}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "(<Nm>n</Nm><IBAN>X</IBAN>)", out)
+
+	_, err = s.Render(`{{ from "sepa.tpl" import nope }}`, nil)
+	assert.ErrorIs(t, err, ErrMacroNotFound)
+
+	_, err = s.Render(`{{ from "missing.tpl" import m }}`, nil)
+	assert.ErrorIs(t, err, iofs.ErrNotExist)
+
+	_, err = s.Render(`{{ from "broken.tpl" import m }}`, nil)
+	assert.ErrorIs(t, err, ErrInvalidSyntax)
+
+	_, err = s.Render(`{{ from "cycle.tpl" import m }}`, nil)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+
+	_, err = s.Render(`{{ from "sepa.tpl" import address }}{{ macro address() }}{{ endmacro }}`, nil)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "macro address is defined twice"), "unexpected error %v", err)
+
+	_, err = New(builtins()).Render(`{{ from "sepa.tpl" import address }}`, nil)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "no loader configured"), "unexpected error %v", err)
+}
@@ -0,0 +1,56 @@
+package sintax
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/toaweme/sintax/functions"
+)
+
+// WithModifierDefs registers descriptions of the engine's modifiers, keyed by
+// their names, for Modifiers to list and Validate to check calls against. A
+// later definition of a name replaces an earlier one. Registering a modifier
+// drops the definition its name had, since that described the modifier being
+// replaced, so pass definitions after the modifiers they describe. Each group
+// under functions/* hands out its own with Defs, and defaults.All registers them
+// all.
+func WithModifierDefs(defs ...ModifierDef) Option {
+	return func(c *config) {
+		for _, def := range defs {
+			c.defs[string(def.Name)] = def
+		}
+	}
+}
+
+// Modifiers lists every modifier the engine can call, sorted by name, for
+// tooling such as autocomplete and generated docs. A modifier registered
+// without a definition is listed by name alone, and a definition of a name the
+// engine has no modifier for is left out.
+func (s *sintax) Modifiers() []ModifierDef {
+	return s.render.Modifiers()
+}
+
+// Modifiers lists the renderer's modifiers. See the engine's Modifiers.
+func (r *TokenRenderer) Modifiers() []ModifierDef {
+	defs := make([]ModifierDef, 0, len(r.funcs)+len(r.ctxFuncs)+len(r.awareFuncs))
+	add := func(name string) {
+		def, ok := r.defs[name]
+		if !ok {
+			def = ModifierDef{Name: functions.ModifierName(name)}
+		}
+		defs = append(defs, def)
+	}
+	for name := range r.funcs {
+		add(name)
+	}
+	for name := range r.ctxFuncs {
+		add(name)
+	}
+	for name := range r.awareFuncs {
+		add(name)
+	}
+	slices.SortFunc(defs, func(a, b ModifierDef) int {
+		return strings.Compare(string(a.Name), string(b.Name))
+	})
+	return defs
+}
@@ -0,0 +1,58 @@
+package sintax
+
+import (
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions"
+)
+
+func Test_E2E_Modifiers(t *testing.T) {
+	s := New(builtins(), WithModifiers(map[string]GlobalModifier{
+		"redact": func(any, []any) (any, error) { return "***", nil },
+		"upper":  func(any, []any) (any, error) { return "UP", nil },
+	}), WithModifierDefs(ModifierDef{Name: "unused", Summary: "registered without a modifier"}))
+
+	byName := map[functions.ModifierName]ModifierDef{}
+	var names []functions.ModifierName
+	for _, def := range s.Modifiers() {
+		byName[def.Name] = def
+		names = append(names, def.Name)
+	}
+
+	// sorted, one entry per modifier of any kind
+	assert.Equal(t, functions.ModifierName("concat"), names[0])
+	assert.Equal(t, functions.ModifierName("yaml"), names[len(names)-1])
+	assert.Equal(t, functions.GroupText, byName["template"].Group)
+	assert.Equal(t, functions.GroupFileSystem, byName["file"].Group)
+
+	def := byName["default"]
+	assert.True(t, def.AnswersMiss, "default answers a miss")
+	assert.Equal(t, []ParamDef{{Name: "fallback", Type: "any"}}, def.Params)
+
+	// a modifier without a definition is listed by name alone, and so is a
+	// built-in replaced after its definition was registered
+	assert.Equal(t, ModifierDef{Name: "redact"}, byName["redact"])
+	assert.Equal(t, ModifierDef{Name: "upper"}, byName["upper"])
+
+	_, ok := byName["unused"]
+	assert.True(t, !ok, "a definition without a modifier is not listed")
+}
+
+// A modifier that cannot say what it takes is checked against its definition.
+func Test_E2E_Modifiers_ValidateAgainstDefs(t *testing.T) {
+	pad := func(any, []any) (any, error) { return "", nil }
+	s := New(builtins(), WithModifiers(map[string]GlobalModifier{"pad": pad}))
+	assert.NoError(t, s.Validate("{{ x | pad:1,2,3 }}"))
+
+	s = New(builtins(), WithModifiers(map[string]GlobalModifier{"pad": pad}), WithModifierDefs(ModifierDef{
+		Name:   "pad",
+		Params: []ParamDef{{Name: "width", Type: "int"}, {Name: "fill", Type: "string", Optional: true}},
+	}))
+	assert.NoError(t, s.Validate("{{ x | pad:4 }}{{ x | pad:4,'0' }}"))
+	assert.ErrorIs(t, s.Validate("{{ x | pad }}"), ErrInvalidArity)
+
+	// the built-ins written by hand are covered the same way
+	assert.ErrorIs(t, s.Validate("{{ x | eq }}"), ErrInvalidArity)
+	assert.ErrorIs(t, s.Validate("{{ body | template:a,b }}"), ErrInvalidArity)
+}
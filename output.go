@@ -0,0 +1,107 @@
+package sintax
+
+import (
+	"html"
+	"io"
+)
+
+// output is the sink a render writes into. Render points it at a
+// strings.Builder and RenderTo at the caller's writer, so both walk the same
+// code and produce the same bytes. It counts what it has written and keeps the
+// first write failure, after which every write fails the same way, so a render
+// stops at the first token that could not be delivered rather than carrying on
+// into a writer that is already broken.
+//
+// With a max set, a write that would take the total past it fails with a
+// *LimitError instead, without writing any of it.
+type output struct {
+	w   io.Writer
+	sw  io.StringWriter
+	n   int64
+	max int64
+	err error
+
+	// escape is how writeValue escapes a value, and markup, for
+	// EscapeHTMLContext, where in the HTML written so far the next value lands.
+	escape AutoEscape
+	markup markupState
+}
+
+func newOutput(w io.Writer, maxBytes int64) *output {
+	out := &output{w: w, max: maxBytes}
+	// most writers worth streaming to (bufio.Writer, bytes.Buffer, os.File,
+	// strings.Builder) take a string without the []byte copy
+	out.sw, _ = w.(io.StringWriter)
+	return out
+}
+
+// newOutput returns an output onto w for a render by r, under its output
+// limit and escaping values the way it is configured to.
+func (r *TokenRenderer) newOutput(w io.Writer) *output {
+	out := newOutput(w, r.budget.maxOutput())
+	out.escape = r.escape
+	return out
+}
+
+// writeString writes s to the underlying writer, reporting a failure as a
+// *WriteError.
+func (o *output) writeString(s string) error {
+	if o.err != nil {
+		return o.err
+	}
+	if s == "" {
+		return nil
+	}
+	if o.escape == EscapeHTMLContext {
+		o.markup.feed(s)
+	}
+	if o.max > 0 && o.n+int64(len(s)) > o.max {
+		o.err = &LimitError{Limit: limitOutputBytes, Max: o.max}
+		return o.err
+	}
+	var n int
+	var err error
+	if o.sw != nil {
+		n, err = o.sw.WriteString(s)
+	} else {
+		n, err = o.w.Write([]byte(s))
+	}
+	o.n += int64(n)
+	if err == nil && n < len(s) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		o.err = &WriteError{Written: o.n, Err: err}
+	}
+	return o.err
+}
+
+// writeValue writes v the way stringify renders it, so a value interpolated
+// among text reads the same whether it was rendered or streamed. Under
+// auto-escaping the text is escaped first, unless v is a SafeString.
+func (o *output) writeValue(v any) error {
+	if s, ok := v.(SafeString); ok {
+		return o.writeString(string(s))
+	}
+	if m, ok := v.(markupText); ok {
+		s, err := o.markup.escapeMarkup(string(m))
+		if err != nil {
+			return err
+		}
+		return o.writeString(s)
+	}
+	s := stringify(v)
+	switch o.escape {
+	case EscapeHTML:
+		s = html.EscapeString(s)
+	case EscapeXML:
+		s = xmlEscaper.Replace(s)
+	case EscapeHTMLContext:
+		var err error
+		if s, err = o.markup.escape(s, v); err != nil {
+			return err
+		}
+	default:
+	}
+	return o.writeString(s)
+}
@@ -1,30 +1,84 @@
 package sintax
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // StringParser is the default Parser implementation, tokenizing templates
-// delimited by "{{" and "}}".
+// delimited by "{{" and "}}", or by the pair NewStringParserWithDelimiters was
+// given.
 type StringParser struct {
 	opener string
 	closer string
 }
 
+// The standard delimiters.
+const (
+	defaultOpener = "{{"
+	defaultCloser = "}}"
+)
+
 // NewStringParser creates a StringParser using the standard "{{"/"}}" delimiters.
 func NewStringParser() *StringParser {
 	return &StringParser{
-		opener: "{{",
-		closer: "}}",
+		opener: defaultOpener,
+		closer: defaultCloser,
+	}
+}
+
+// NewStringParserWithDelimiters creates a StringParser whose tags open with
+// opener and close with closer, so a template for a format that is itself full
+// of literal "{{ }}", such as a Helm chart, can mark its own tags with "[[ ]]"
+// or "<% %>" instead. Trim markers keep their meaning inside any pair, so
+// "<%- x -%>" trims like "{{- x -}}".
+//
+// A pair the parser could not read unambiguously fails with an error wrapping
+// ErrInvalidDelimiters: an empty delimiter, one holding whitespace, an opener
+// ending or a closer starting with the "-" trim marker, and a pair where one
+// delimiter contains the other.
+func NewStringParserWithDelimiters(opener, closer string) (*StringParser, error) {
+	if err := checkDelimiters(opener, closer); err != nil {
+		return nil, err
+	}
+	return &StringParser{opener: opener, closer: closer}, nil
+}
+
+// checkDelimiters rejects a delimiter pair the parser cannot tell apart from
+// tag contents or from each other.
+func checkDelimiters(opener, closer string) error {
+	switch {
+	case opener == "" || closer == "":
+		return fmt.Errorf("%w: opener %q and closer %q must both be non-empty", ErrInvalidDelimiters, opener, closer)
+	case strings.IndexFunc(opener+closer, unicode.IsSpace) >= 0:
+		return fmt.Errorf("%w: %q and %q must not contain whitespace", ErrInvalidDelimiters, opener, closer)
+	case strings.HasSuffix(opener, "-") || strings.HasPrefix(closer, "-"):
+		// "<-" would read "<-- x" as a trim marker on an opener of "<-"
+		return fmt.Errorf("%w: opener %q must not end, and closer %q must not start, with the \"-\" trim marker", ErrInvalidDelimiters, opener, closer)
+	case strings.Contains(opener, closer) || strings.Contains(closer, opener):
+		return fmt.Errorf("%w: opener %q and closer %q must not contain one another", ErrInvalidDelimiters, opener, closer)
+	default:
+		return nil
 	}
 }
 
 var _ Parser = (*StringParser)(nil)
 
-// Parse tokenizes template into a slice of Tokens.
+// Parse tokenizes template into a slice of Tokens. Every token records the
+// Position it starts at. A template that cannot mean anything, such as an
+// unclosed tag, a tag whose contents are not an expression or a block keyword,
+// a block that is never closed or closed by the wrong tag, or a malformed
+// modifier call, fails with a *SyntaxError pointing at the offending spot
+// rather than surfacing later as a render error with no location, or as text.
 func (p *StringParser) Parse(template string) ([]Token, error) {
 	var tokens []Token
+	lines := newLineIndex(template)
+	var blocks blockStack
+	var defined map[string]bool
 
 	i := 0
 	for {
@@ -36,6 +90,7 @@ func (p *StringParser) Parse(template string) ([]Token, error) {
 				tokens = append(tokens, BaseToken{
 					TokenType: TextToken,
 					RawValue:  template[i:],
+					PosValue:  lines.position(i),
 				})
 			}
 			break
@@ -49,19 +104,33 @@ func (p *StringParser) Parse(template string) ([]Token, error) {
 			tokens = append(tokens, BaseToken{
 				TokenType: TextToken,
 				RawValue:  template[i:openerIndex],
+				PosValue:  lines.position(i),
 			})
 		}
 
 		// find the next occurrence of `closer`, after the opener
 		startOfInner := openerIndex + len(p.opener)
-		closerIndex := strings.Index(template[startOfInner:], p.closer)
-		if closerIndex == -1 {
-			// no matching closer found, so the rest of the string is treated as text
+
+		// {{# ... #}} is a comment. It closes at the first "#}}", so a comment
+		// may hold tags of its own, such as a commented-out {{ x }}.
+		if strings.HasPrefix(template[startOfInner:], commentMark) {
+			end := strings.Index(template[startOfInner+len(commentMark):], commentMark+p.closer)
+			if end == -1 {
+				return nil, lines.syntaxError(openerIndex, "unclosed comment, missing %q", commentMark+p.closer)
+			}
+			end += startOfInner + len(commentMark)
 			tokens = append(tokens, BaseToken{
-				TokenType: TextToken,
-				RawValue:  template[openerIndex:], // everything from opener
+				TokenType: CommentToken,
+				RawValue:  template[startOfInner+len(commentMark) : end],
+				PosValue:  lines.position(openerIndex),
 			})
-			break
+			i = end + len(commentMark) + len(p.closer)
+			continue
+		}
+
+		closerIndex := strings.Index(template[startOfInner:], p.closer)
+		if closerIndex == -1 {
+			return nil, lines.syntaxError(openerIndex, "unclosed tag, missing %q", p.closer)
 		}
 
 		// adjust to absolute index
@@ -69,12 +138,14 @@ func (p *StringParser) Parse(template string) ([]Token, error) {
 
 		// extract the substring (contents) between opener and closer
 		contents := template[startOfInner:closerIndex]
+		contentsStart := startOfInner
 
 		// detect Jinja-style trim markers: {{- expr -}}
 		trimLeft := strings.HasPrefix(contents, "-")
 		trimRight := strings.HasSuffix(contents, "-")
 		if trimLeft {
 			contents = contents[1:]
+			contentsStart++
 		}
 		if trimRight && len(contents) > 0 {
 			contents = contents[:len(contents)-1]
@@ -87,7 +158,22 @@ func (p *StringParser) Parse(template string) ([]Token, error) {
 
 		// create the appropriate token
 		tokenType := p.detectTokenType(contents)
-		tokens = append(tokens, p.createToken(tokenType, contents))
+		if err := p.checkTag(tokenType, contents, contentsStart, lines); err != nil {
+			return nil, err
+		}
+		token := p.createToken(tokenType, contents)
+		if bt, ok := token.(BaseToken); ok {
+			bt.PosValue = lines.position(openerIndex)
+			bt.tag = template[openerIndex : closerIndex+len(p.closer)]
+			token = bt
+		}
+		if err := blocks.track(token, openerIndex, lines); err != nil {
+			return nil, err
+		}
+		if err := define(&defined, token, openerIndex, lines); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
 
 		// move `i` beyond the closer
 		i = closerIndex + len(p.closer)
@@ -95,23 +181,526 @@ func (p *StringParser) Parse(template string) ([]Token, error) {
 		// {{ -}}: strip leading whitespace from following text token (incl. newlines).
 		// we do this by advancing `i` past any whitespace + optional newlines.
 		if trimRight {
-			for i < len(template) {
-				c := template[i]
-				if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
-					i++
-					continue
-				}
-				break
+			i = skipSpace(template, i)
+		}
+
+		// the contents of a raw block are text up to its endraw, whatever tags
+		// they hold, so they are read here rather than by the loop above.
+		if tokenType == RawToken {
+			var err error
+			if tokens, i, err = p.readRaw(template, tokens, openerIndex, i, lines); err != nil {
+				return nil, err
 			}
 		}
 	}
 
+	if err := blocks.close(lines); err != nil {
+		return nil, err
+	}
+	if err := checkExtends(tokens, lines); err != nil {
+		return nil, err
+	}
+
 	// post-pass that auto-trims whitespace around control tags sitting alone on a line.
 	tokens = autoTrimBlockLines(tokens)
 
 	return tokens, nil
 }
 
+// commentMark opens and closes a comment just inside the delimiters.
+const commentMark = "#"
+
+// skipSpace returns the index of the first byte at or after i in s that is not
+// whitespace.
+func skipSpace(s string, i int) int {
+	for i < len(s) {
+		c := s[i]
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			i++
+			continue
+		}
+		break
+	}
+	return i
+}
+
+// readRaw reads the body of a raw block opened at rawOffset, starting at i, up
+// to its endraw tag, appending the body as a single text token and the endraw
+// as a RawEndToken. The body is never parsed, so it may hold anything,
+// unbalanced and malformed tags included. Trim markers on the endraw tag work
+// as they do anywhere else. It returns the index just past the endraw.
+func (p *StringParser) readRaw(template string, tokens []Token, rawOffset, i int, lines lineIndex) ([]Token, int, error) {
+	for from := i; ; {
+		at := strings.Index(template[from:], p.opener)
+		if at == -1 {
+			return nil, i, lines.syntaxError(rawOffset, "raw block is never closed, missing %s", controlName(RawEndToken))
+		}
+		at += from
+		inner := at + len(p.opener)
+		end := strings.Index(template[inner:], p.closer)
+		if end == -1 {
+			return nil, i, lines.syntaxError(rawOffset, "raw block is never closed, missing %s", controlName(RawEndToken))
+		}
+		end += inner
+		contents := template[inner:end]
+		trimLeft := strings.HasPrefix(contents, "-")
+		trimRight := strings.HasSuffix(contents, "-")
+		if strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(contents, "-"), "-")) != "endraw" {
+			from = inner
+			continue
+		}
+
+		body := template[i:at]
+		if trimLeft {
+			body = strings.TrimRight(body, " \t\r\n")
+		}
+		if body != "" {
+			tokens = append(tokens, BaseToken{
+				TokenType: TextToken,
+				RawValue:  body,
+				PosValue:  lines.position(i),
+			})
+		}
+		tokens = append(tokens, BaseToken{
+			TokenType: RawEndToken,
+			PosValue:  lines.position(at),
+		})
+
+		next := end + len(p.closer)
+		if trimRight {
+			next = skipSpace(template, next)
+		}
+		return tokens, next, nil
+	}
+}
+
+// checkTag rejects a tag whose contents cannot mean anything: contents that are
+// neither an expression nor a block keyword, an if or for whose expression does
+// not parse, and a malformed modifier pipeline. offset is where contents starts
+// in the template, so the error can point inside the tag.
+func (p *StringParser) checkTag(tokenType TokenType, contents string, offset int, lines lineIndex) error {
+	lead := len(contents) - len(strings.TrimLeft(contents, " \t\r\n"))
+	trimmed := strings.TrimSpace(contents)
+	var err *parseError
+	switch tokenType {
+	case UndefinedToken:
+		if trimmed == "" {
+			return lines.syntaxError(offset, "empty tag")
+		}
+		err = errAt(0, "unrecognized tag contents %q", trimmed)
+	case FilteredVariableToken:
+		err = checkPipeline(trimmed)
+	case ShorthandIfToken, CallToken:
+		_, err = p.parseExpr(trimmed)
+	case IfToken:
+		cond := trimPrefix(trimmed, "if")
+		if cond == "" {
+			return lines.syntaxError(offset+lead, "if is missing a condition")
+		}
+		_, err = p.parseExpr(cond)
+		err = err.shift(len(trimmed) - len(cond))
+	case ElifToken:
+		cond := elifCondition(trimmed)
+		if cond == "" {
+			return lines.syntaxError(offset+lead, "elif is missing a condition")
+		}
+		_, err = p.parseExpr(cond)
+		err = err.shift(len(trimmed) - len(cond))
+	case ForToken:
+		spec, expr := parseForExpr(trimmed)
+		if expr == "" || spec == "" {
+			return lines.syntaxError(offset+lead, "malformed for, expected \"for x in items\" or \"for k, v in items\"")
+		}
+		for _, name := range strings.Split(spec, ",") {
+			if !identRe.MatchString(name) {
+				return lines.syntaxError(offset+lead, "malformed for, %q is not a loop variable name", name)
+			}
+		}
+		at := strings.LastIndex(trimmed, expr)
+		iterable, cond, filtered := cutForFilter(expr)
+		if isRangeCall(iterable) {
+			_, err = p.parseRange(iterable)
+		} else {
+			err = p.checkExpr("for iterable", iterable)
+		}
+		if err = err.shift(at); err != nil || !filtered {
+			break
+		}
+		if cond == "" {
+			return lines.syntaxError(offset+lead+at+len(iterable), "for filter is missing a condition")
+		}
+		_, err = p.parseExpr(cond)
+		err = err.shift(at + len(expr) - len(cond))
+	case SetToken:
+		name, expr, ok := parseAssign(trimmed)
+		if !ok || !identRe.MatchString(name) {
+			return lines.syntaxError(offset+lead, "malformed %s, expected \"%s name = value\"", assignKeyword(trimmed), assignKeyword(trimmed))
+		}
+		if expr == "" {
+			return lines.syntaxError(offset+lead+len(trimmed), "%s %s is missing a value", assignKeyword(trimmed), name)
+		}
+		_, err = p.parseExpr(expr)
+		err = err.shift(len(trimmed) - len(expr))
+	case CaptureToken:
+		name := strings.TrimSpace(strings.TrimPrefix(trimmed, "capture"))
+		if !identRe.MatchString(name) {
+			return lines.syntaxError(offset+lead, "malformed capture, expected \"capture name\"")
+		}
+	case MacroToken:
+		_, err = p.parseMacro(trimmed)
+	case ImportToken:
+		_, err = parseImport(trimmed)
+	case ExtendsToken:
+		_, err = parseExtends(trimmed)
+	case IncludeToken:
+		_, err = p.parseInclude(trimmed)
+	case BlockToken:
+		name := strings.TrimSpace(strings.TrimPrefix(trimmed, "block"))
+		if !identRe.MatchString(name) {
+			return lines.syntaxError(offset+lead, "malformed block, expected \"block name\"")
+		}
+	default:
+	}
+	if err != nil {
+		return lines.syntaxError(offset+lead+err.at, "%s", err.msg)
+	}
+	return nil
+}
+
+// checkExpr checks the iterable a for carries, which must be a single variable
+// or modifier pipeline.
+func (p *StringParser) checkExpr(what, expr string) *parseError {
+	switch p.detectTokenType(expr) {
+	case VariableToken:
+		return nil
+	case FilteredVariableToken:
+		return checkPipeline(expr)
+	default:
+	}
+	return errAt(0, "%s %q is not a variable or modifier pipeline", what, expr)
+}
+
+// identRe matches a modifier or loop variable name.
+var identRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// checkPipeline rejects a modifier pipeline the renderer could only misread: an
+// unterminated quote, a missing head value, an empty or misnamed modifier, and an
+// empty argument. s is trimmed, and the error offset is relative to it.
+func checkPipeline(s string) *parseError {
+	segments, err := splitPipeline(s)
+	if err != nil {
+		return err
+	}
+
+	head := segments[0]
+	if head.text == "" {
+		return errAt(0, "missing value before %q", "|")
+	}
+	if !isQuotedWith(head.text, `"`) && !isQuotedWith(head.text, `'`) && !variableNameRe.MatchString(head.text) {
+		return errAt(head.at, "%q is not a variable name or quoted string", head.text)
+	}
+
+	for _, seg := range segments[1:] {
+		if seg.text == "" {
+			return errAt(seg.at, "missing modifier name after %q", "|")
+		}
+		name, args, hasArgs := strings.Cut(seg.text, ":")
+		name = strings.TrimSpace(name)
+		if !identRe.MatchString(name) {
+			return errAt(seg.at, "invalid modifier name %q", name)
+		}
+		if !hasArgs {
+			continue
+		}
+		argsAt := seg.at + strings.Index(seg.text, ":") + 1
+		parts, err := splitPipelineOn(args, ',')
+		if err != nil {
+			return err.shift(argsAt)
+		}
+		for _, arg := range parts {
+			if arg.text == "" {
+				return errAt(argsAt+arg.at, "empty argument to modifier %q", name)
+			}
+			if !isQuotedWith(arg.text, `"`) && !isQuotedWith(arg.text, `'`) && !variableNameRe.MatchString(arg.text) && strings.ContainsAny(arg.text, " \t\"'") {
+				return errAt(argsAt+arg.at, "malformed argument %q to modifier %q", arg.text, name)
+			}
+		}
+	}
+	return nil
+}
+
+// pipelinePart is one separated part of a pipeline or argument list, trimmed,
+// with the offset its text starts at in the string it was cut from.
+type pipelinePart struct {
+	text string
+	at   int
+}
+
+// parseError is malformed tag contents and the offset, relative to the text
+// being checked, it was noticed at. Parse turns it into a *SyntaxError once it
+// knows where that text sits in the template.
+type parseError struct {
+	msg string
+	at  int
+}
+
+func errAt(at int, format string, args ...any) *parseError {
+	return &parseError{msg: fmt.Sprintf(format, args...), at: at}
+}
+
+// shift moves the error's offset by n, for text checked as a slice of a larger
+// string. It is nil-safe, so a check's result can be shifted unconditionally.
+func (e *parseError) shift(n int) *parseError {
+	if e == nil {
+		return nil
+	}
+	return &parseError{msg: e.msg, at: e.at + n}
+}
+
+// splitPipeline cuts a pipeline on its `|` separators, skipping quoted sections.
+func splitPipeline(s string) ([]pipelinePart, *parseError) {
+	return splitPipelineOn(s, '|')
+}
+
+// splitPipelineOn cuts s on sep wherever sep sits outside quotes, keeping each
+// part's offset. An unterminated quote is an error pointing at its opening mark.
+func splitPipelineOn(s string, sep byte) ([]pipelinePart, *parseError) {
+	var parts []pipelinePart
+	partStart := 0
+	quote, quoteAt := byte(0), 0
+	cut := func(end int) {
+		raw := s[partStart:end]
+		lead := len(raw) - len(strings.TrimLeft(raw, " \t\r\n"))
+		parts = append(parts, pipelinePart{text: strings.TrimSpace(raw), at: partStart + lead})
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote, quoteAt = c, i
+		case c == sep:
+			cut(i)
+			partStart = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, errAt(quoteAt, "unterminated quoted string")
+	}
+	cut(len(s))
+	return parts, nil
+}
+
+// blockStack tracks the if and for blocks open at the current point of a parse,
+// so an unbalanced template is rejected at parse time with the position of the
+// tag that broke it.
+type blockStack []openBlock
+
+// openBlock is a block tag waiting for its closer.
+type openBlock struct {
+	kind    TokenType
+	offset  int
+	sawElse bool
+}
+
+// track updates the stack for token, which starts at offset in the template.
+func (s *blockStack) track(token Token, offset int, lines lineIndex) error {
+	switch token.Type() {
+	case IfToken, ForToken, CaptureToken, BlockToken:
+		*s = append(*s, openBlock{kind: token.Type(), offset: offset})
+	case MacroToken, ImportToken, ExtendsToken:
+		// both are bound before the template renders, whatever its data, so
+		// neither may sit where the data decides whether it is reached
+		if top := s.top(); top != nil {
+			return lines.syntaxError(offset, "%s must be at the top level of a template, not inside a %s block", controlName(token.Type()), controlName(top.kind))
+		}
+		if token.Type() == MacroToken {
+			*s = append(*s, openBlock{kind: MacroToken, offset: offset})
+		}
+	case ElifToken:
+		top := s.top()
+		if top == nil || top.kind != IfToken {
+			return lines.syntaxError(offset, "elif outside an if block")
+		}
+		if top.sawElse {
+			return lines.syntaxError(offset, "elif after the else of its if block")
+		}
+	case ElseToken:
+		// a for takes an else too, rendered when the loop has nothing to visit
+		top := s.top()
+		if top == nil || top.kind != IfToken && top.kind != ForToken {
+			return lines.syntaxError(offset, "else outside an if or for block")
+		}
+		if top.sawElse {
+			return lines.syntaxError(offset, "%s block already has an else", controlName(top.kind))
+		}
+		top.sawElse = true
+	case BreakToken, ContinueToken:
+		if !s.inside(ForToken) {
+			return lines.syntaxError(offset, "%s outside a for loop", controlName(token.Type()))
+		}
+	case RawEndToken:
+		// a raw block reads up to its own endraw, so one reaching here has no raw
+		return lines.syntaxError(offset, "endraw without a matching raw")
+	case IfEndToken, ForEndToken, CaptureEndToken, MacroEndToken, BlockEndToken:
+		opener := openerOf(token.Type())
+		top := s.top()
+		if top == nil {
+			return lines.syntaxError(offset, "%s without a matching %s", controlName(token.Type()), controlName(opener))
+		}
+		if top.kind != opener {
+			return lines.syntaxError(offset, "%s closes a %s block, expected %s", controlName(token.Type()), controlName(top.kind), closerName(top.kind))
+		}
+		*s = (*s)[:len(*s)-1]
+	default:
+	}
+	return nil
+}
+
+// close reports the innermost block still open once the whole template has been
+// read.
+func (s *blockStack) close(lines lineIndex) error {
+	if top := s.top(); top != nil {
+		return lines.syntaxError(top.offset, "%s block is never closed, missing %s", controlName(top.kind), closerName(top.kind))
+	}
+	return nil
+}
+
+// inside reports whether a block of kind is open, however deeply nested.
+func (s *blockStack) inside(kind TokenType) bool {
+	for _, b := range *s {
+		if b.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *blockStack) top() *openBlock {
+	if len(*s) == 0 {
+		return nil
+	}
+	return &(*s)[len(*s)-1]
+}
+
+// closerName names the tag that closes a block opened by t.
+func closerName(t TokenType) string {
+	return controlName(closerOf(t))
+}
+
+// closerOf returns the tag that closes a block opened by t.
+func closerOf(t TokenType) TokenType {
+	switch t {
+	case ForToken:
+		return ForEndToken
+	case CaptureToken:
+		return CaptureEndToken
+	case MacroToken:
+		return MacroEndToken
+	case BlockToken:
+		return BlockEndToken
+	default:
+		return IfEndToken
+	}
+}
+
+// openerOf returns the block tag that the closer t closes.
+func openerOf(t TokenType) TokenType {
+	switch t {
+	case ForEndToken:
+		return ForToken
+	case CaptureEndToken:
+		return CaptureToken
+	case MacroEndToken:
+		return MacroToken
+	case BlockEndToken:
+		return BlockToken
+	default:
+		return IfToken
+	}
+}
+
+// define records the macro, block or extends tag token in defined, rejecting a
+// second macro or block of one name and a second extends, each of which would
+// leave one of the two silently ignored.
+func define(defined *map[string]bool, token Token, offset int, lines lineIndex) error {
+	var key string
+	switch token.Type() {
+	case MacroToken, BlockToken:
+		key = controlName(token.Type()) + " " + token.Name()
+	case ExtendsToken:
+		key = controlName(ExtendsToken)
+	default:
+		return nil
+	}
+	if (*defined)[key] {
+		if token.Type() == ExtendsToken {
+			return lines.syntaxError(offset, "a template extends at most one other")
+		}
+		return lines.syntaxError(offset, "%s is defined twice", key)
+	}
+	if *defined == nil {
+		*defined = make(map[string]bool)
+	}
+	(*defined)[key] = true
+	return nil
+}
+
+// lineIndex maps byte offsets in a template to line and column positions. It
+// holds the offset each line starts at, so a lookup is a binary search rather
+// than a rescan of the template.
+type lineIndex struct {
+	src    string
+	starts []int
+}
+
+func newLineIndex(src string) lineIndex {
+	starts := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return lineIndex{src: src, starts: starts}
+}
+
+// position returns the 1-based line and column of offset. Columns count
+// characters rather than bytes, so they match what an editor shows.
+func (l lineIndex) position(offset int) Position {
+	line := sort.Search(len(l.starts), func(i int) bool { return l.starts[i] > offset }) - 1
+	start := l.starts[line]
+	return Position{
+		Offset: offset,
+		Line:   line + 1,
+		Column: utf8.RuneCountInString(l.src[start:offset]) + 1,
+	}
+}
+
+// syntaxError builds a *SyntaxError at offset, carrying the source line it sits
+// on.
+func (l lineIndex) syntaxError(offset int, format string, args ...any) *SyntaxError {
+	pos := l.position(offset)
+	start := l.starts[pos.Line-1]
+	end := strings.IndexByte(l.src[start:], '\n')
+	if end < 0 {
+		end = len(l.src)
+	} else {
+		end += start
+	}
+	return &SyntaxError{
+		Msg:     fmt.Sprintf(format, args...),
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Offset:  pos.Offset,
+		Snippet: strings.TrimSuffix(l.src[start:end], "\r"),
+	}
+}
+
 // stripPrevTextRight strips trailing whitespace from the last token if it is
 // a TextToken. when `includeNewlines` is true, newlines are also stripped.
 func stripPrevTextRight(tokens []Token, includeNewlines bool) {
@@ -141,7 +730,7 @@ func stripPrevTextRight(tokens []Token, includeNewlines bool) {
 }
 
 // autoTrimBlockLines removes the surrounding whitespace + newline for control
-// tags (if/else/endif/for/endfor) that sit alone on their own line. specifically:
+// tags (if/elif/else/endif/for/endfor) that sit alone on their own line. specifically:
 //   - if the preceding text token's tail (after the last \n) is all whitespace,
 //     strip that trailing whitespace; and
 //   - if the following text token starts with optional whitespace then \n,
@@ -158,7 +747,10 @@ func autoTrimBlockLines(tokens []Token) []Token {
 
 	isBlock := func(t Token) bool {
 		switch t.Type() {
-		case IfToken, ElseToken, IfEndToken, ForToken, ForEndToken:
+		case IfToken, ElifToken, ElseToken, IfEndToken, ForToken, ForEndToken,
+			CommentToken, RawToken, RawEndToken, SetToken, CaptureToken, CaptureEndToken,
+			MacroToken, MacroEndToken, ImportToken, BlockToken, BlockEndToken, ExtendsToken,
+			BreakToken, ContinueToken:
 			return true
 		default:
 		}
@@ -171,11 +763,12 @@ func autoTrimBlockLines(tokens []Token) []Token {
 		}
 
 		// previous-text tail check, met at start-of-template (i==0), or when the prev text
-		// contains a '\n' followed only by whitespace until the end.
+		// contains a '\n' followed only by whitespace until the end. it reads the text
+		// as parsed, since the tag before may already have stripped the newline that
+		// ends its own line, which is the one this tag's line starts after.
 		prevOK := i == 0
-		var prevBt BaseToken
 		if i > 0 {
-			prev := out[i-1]
+			prev := tokens[i-1]
 			if prev.Type() == TextToken {
 				if bt, ok := prev.(BaseToken); ok {
 					tail := bt.RawValue
@@ -191,7 +784,6 @@ func autoTrimBlockLines(tokens []Token) []Token {
 						}
 						if allWS {
 							prevOK = true
-							prevBt = bt
 						}
 					}
 				}
@@ -232,17 +824,22 @@ func autoTrimBlockLines(tokens []Token) []Token {
 			continue
 		}
 
-		// strip trailing whitespace from prev, back to the last newline kept
-		if i > 0 && out[i-1].Type() == TextToken {
-			tail := prevBt.RawValue
-			nl := strings.LastIndexByte(tail, '\n')
-			prevBt.RawValue = tail[:nl+1]
-			prevBt.Var = prevBt.RawValue
-			out[i-1] = prevBt
+		// strip trailing whitespace from prev, back to the last newline kept. a prev
+		// the tag before already stripped up to its end has no newline left, and
+		// goes entirely.
+		if i > 0 {
+			if prevBt, ok := out[i-1].(BaseToken); ok && prevBt.Type() == TextToken {
+				tail := prevBt.RawValue
+				nl := strings.LastIndexByte(tail, '\n')
+				prevBt.RawValue = tail[:nl+1]
+				prevBt.Var = prevBt.RawValue
+				out[i-1] = prevBt
+			}
 		}
 
 		// strip leading whitespace + newline from next
 		if i < len(out)-1 && out[i+1].Type() == TextToken {
+			nextBt.PosValue = nextBt.PosValue.advance(nextBt.RawValue[:nextStripUntil])
 			nextBt.RawValue = nextBt.RawValue[nextStripUntil:]
 			nextBt.Var = nextBt.RawValue
 			out[nextIdx] = nextBt
@@ -252,10 +849,13 @@ func autoTrimBlockLines(tokens []Token) []Token {
 	return out
 }
 
-// variableNameRe matches a bare variable name (letters, digits, underscore and dots).
+// variableNameRe matches a bare variable name (letters, digits, underscore and
+// dots), optionally followed by bracket segments holding an index or a quoted
+// key, with `\.` standing for a literal dot. The brackets and escapes only mean
+// a path under WithPathAccess; without it the whole name is one literal key.
 // Compiled once at package load: detectTokenType runs it against every
 // token, so compiling per call dominated parse allocations.
-var variableNameRe = regexp.MustCompile(`^([a-zA-Z_.][a-zA-Z0-9_.]*)$`)
+var variableNameRe = regexp.MustCompile(`^(?:[a-zA-Z_.]|\\\.)(?:[a-zA-Z0-9_.]|\\\.)*(?:\[(?:-?[0-9]+|"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')\](?:[a-zA-Z0-9_.]|\\\.)*)*$`)
 
 // isVariable reports whether s is a bare variable name
 // (as opposed to a quoted string, number, boolean, or filtered expression).
@@ -268,16 +868,48 @@ func (p *StringParser) detectTokenType(s string) TokenType {
 
 	if s == "endif" {
 		return IfEndToken
+	} else if s == "raw" {
+		return RawToken
+	} else if s == "endraw" {
+		return RawEndToken
+	} else if s == "endcapture" {
+		return CaptureEndToken
+	} else if s == "endmacro" {
+		return MacroEndToken
+	} else if s == "endblock" {
+		return BlockEndToken
+	} else if keywordTag(s, "block") {
+		return BlockToken
+	} else if keywordTag(s, "extends") {
+		return ExtendsToken
+	} else if keywordTag(s, "macro") {
+		return MacroToken
+	} else if keywordTag(s, "from") {
+		return ImportToken
+	} else if keywordTag(s, "include") {
+		return IncludeToken
+	} else if assignKeyword(s) != "" {
+		return SetToken
+	} else if keywordTag(s, "capture") {
+		return CaptureToken
 	} else if s == "endfor" {
 		return ForEndToken
 	} else if strings.HasPrefix(s, "for ") {
 		return ForToken
 	} else if strings.HasPrefix(s, "if ") || s == "if" {
 		return IfToken
-	} else if strings.HasPrefix(s, "else") {
+	} else if elifKeyword(s) != "" {
+		return ElifToken
+	} else if s == "else" {
 		return ElseToken
-	} else if strings.Contains(s, " ? ") && strings.Contains(s, " : ") {
+	} else if s == "break" {
+		return BreakToken
+	} else if s == "continue" {
+		return ContinueToken
+	} else if strings.Contains(s, " ? ") {
 		return ShorthandIfToken
+	} else if _, _, ok := splitCall(s); ok {
+		return CallToken
 	} else if p.isVariable(s) {
 		return VariableToken
 	} else if strings.Contains(s, "|") {
@@ -287,6 +919,58 @@ func (p *StringParser) detectTokenType(s string) TokenType {
 	return UndefinedToken
 }
 
+// elifKeyword returns the keyword s opens an elif branch with, "elif" or its
+// spelling "else if", or "" when s is not an elif.
+func elifKeyword(s string) string {
+	for _, kw := range []string{"elif", "else if"} {
+		if s == kw || strings.HasPrefix(s, kw+" ") {
+			return kw
+		}
+	}
+	return ""
+}
+
+// assignKeyword returns the keyword s opens an assignment with, "set" or its
+// spelling "let", or "" when s is not an assignment.
+func assignKeyword(s string) string {
+	for _, kw := range []string{"set", "let"} {
+		if keywordTag(s, kw) {
+			return kw
+		}
+	}
+	return ""
+}
+
+// keywordTag reports whether s is a tag opening with the keyword kw followed by
+// an operand, as in "capture name". A pipe after the keyword makes it a
+// variable of that name instead, so `{{ set | upper }}` still reads a variable
+// called set.
+func keywordTag(s, kw string) bool {
+	if !strings.HasPrefix(s, kw+" ") {
+		return false
+	}
+	rest := strings.TrimSpace(s[len(kw):])
+	return rest != "" && rest[0] != '|'
+}
+
+// parseAssign splits the contents of a set tag into the name it binds and the
+// expression after the "=". ok is false when there is no "=" after a name.
+func parseAssign(s string) (name, expr string, ok bool) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSpace(strings.TrimPrefix(s, assignKeyword(s)))
+	eq := strings.IndexByte(s, '=')
+	if eq < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(s[:eq]), strings.TrimSpace(s[eq+1:]), true
+}
+
+// elifCondition returns the condition of an elif tag's contents.
+func elifCondition(s string) string {
+	s = strings.TrimSpace(s)
+	return strings.TrimSpace(strings.TrimPrefix(s, elifKeyword(s)))
+}
+
 func trimPrefix(s string, prefix string) string {
 	s = strings.TrimSpace(s)
 	if strings.HasPrefix(s, prefix) {
@@ -333,23 +1017,104 @@ func (p *StringParser) createToken(tokenType TokenType, value string) Token {
 		}
 		return token
 	case IfToken:
-		return BaseToken{TokenType: IfToken, RawValue: trimPrefix(value, "if")}
+		cond := trimPrefix(value, "if")
+		node, _ := p.parseExpr(cond)
+		return BaseToken{TokenType: IfToken, RawValue: cond, parsedExpr: node}
+	case ElifToken:
+		cond := elifCondition(value)
+		node, _ := p.parseExpr(cond)
+		return BaseToken{TokenType: ElifToken, RawValue: cond, parsedExpr: node}
 	case ElseToken:
 		return BaseToken{TokenType: ElseToken}
+	case BreakToken:
+		return BaseToken{TokenType: BreakToken}
+	case ContinueToken:
+		return BaseToken{TokenType: ContinueToken}
 	case IfEndToken:
 		return BaseToken{TokenType: IfEndToken}
 	case ShorthandIfToken:
-		return BaseToken{TokenType: ShorthandIfToken, RawValue: value}
+		// a malformed expression leaves the cache empty, and the renderer reports
+		// it when it reaches the token. Parse has already rejected it by then.
+		node, _ := p.parseExpr(strings.TrimSpace(value))
+		return BaseToken{TokenType: ShorthandIfToken, RawValue: value, parsedExpr: node}
 	case ForToken:
 		loopVar, expr := parseForExpr(value)
-		return BaseToken{TokenType: ForToken, RawValue: strings.TrimSpace(value), Var: loopVar, LoopExprValue: expr}
+		iterable, cond, filtered := cutForFilter(expr)
+		token := BaseToken{TokenType: ForToken, RawValue: strings.TrimSpace(value), Var: loopVar, LoopExprValue: iterable, parsedExpr: p.exprToken(iterable)}
+		if isRangeCall(iterable) {
+			token.parsedExpr, _ = p.parseRange(iterable)
+		}
+		if filtered {
+			token.parsedFilter, _ = p.parseExpr(cond)
+		}
+		return token
 	case ForEndToken:
 		return BaseToken{TokenType: ForEndToken}
+	case RawToken:
+		return BaseToken{TokenType: RawToken}
+	case RawEndToken:
+		return BaseToken{TokenType: RawEndToken}
+	case SetToken:
+		name, expr, _ := parseAssign(value)
+		node, _ := p.parseExpr(expr)
+		return BaseToken{TokenType: SetToken, RawValue: expr, Var: name, parsedExpr: node}
+	case CaptureToken:
+		return BaseToken{TokenType: CaptureToken, Var: strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "capture"))}
+	case CaptureEndToken:
+		return BaseToken{TokenType: CaptureEndToken}
+	case MacroToken:
+		// a malformed signature leaves the cache empty, as for an expression
+		sig, _ := p.parseMacro(value)
+		token := BaseToken{TokenType: MacroToken, RawValue: strings.TrimSpace(value), parsedMacro: sig}
+		if sig != nil {
+			token.Var = sig.name
+		}
+		return token
+	case MacroEndToken:
+		return BaseToken{TokenType: MacroEndToken}
+	case ImportToken:
+		spec, _ := parseImport(value)
+		token := BaseToken{TokenType: ImportToken, RawValue: strings.TrimSpace(value), parsedImport: spec}
+		if spec != nil {
+			token.Var = spec.source
+		}
+		return token
+	case BlockToken:
+		return BaseToken{TokenType: BlockToken, Var: strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "block"))}
+	case BlockEndToken:
+		return BaseToken{TokenType: BlockEndToken}
+	case ExtendsToken:
+		name, _ := parseExtends(value)
+		return BaseToken{TokenType: ExtendsToken, RawValue: strings.TrimSpace(value), Var: name}
+	case IncludeToken:
+		spec, _ := p.parseInclude(value)
+		token := BaseToken{TokenType: IncludeToken, RawValue: strings.TrimSpace(value), parsedInclude: spec}
+		if spec != nil {
+			token.Var = includeName(spec)
+		}
+		return token
+	case CallToken:
+		value = strings.TrimSpace(value)
+		name, _, _ := splitCall(value)
+		node, _ := p.parseExpr(value)
+		return BaseToken{TokenType: CallToken, RawValue: value, Var: name, parsedExpr: node}
 	default:
 		return BaseToken{TokenType: TextToken, RawValue: value, Var: value}
 	}
 }
 
+// exprToken classifies a for iterable into the term evalExpr would render, so a compiled template carries it
+// ready-made. It returns nil for anything that is not a variable or pipeline,
+// leaving evalExpr to report the malformed expression when it is reached.
+func (p *StringParser) exprToken(expr string) exprNode {
+	expr = strings.TrimSpace(expr)
+	tt := p.detectTokenType(expr)
+	if tt != VariableToken && tt != FilteredVariableToken {
+		return nil
+	}
+	return &termNode{token: p.createToken(tt, expr)}
+}
+
 // parseForExpr extracts the loop variable specification and iteration expression
 // from a "for X in Y" or "for K, V in Y" string. the leading "for" has already
 // been recognized; we split on " in ". the returned spec is either "v" (single
@@ -374,3 +1139,14 @@ func parseForExpr(s string) (string, string) {
 	}
 	return lhs, expr
 }
+
+// cutForFilter splits the right-hand side of a for tag at its filter clause,
+// as in "txs if tx | key:'amount' | gt:0", into the iterable and the
+// condition. filtered reports whether there was a clause at all.
+func cutForFilter(expr string) (iterable, cond string, filtered bool) {
+	w := topLevelWord(expr, "if")
+	if w < 0 {
+		return expr, "", false
+	}
+	return strings.TrimSpace(expr[:w]), strings.TrimSpace(expr[w+len("if"):]), true
+}
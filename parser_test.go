@@ -1,12 +1,18 @@
 package sintax
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/toaweme/sintax/assert"
 )
 
 func Test_Parser_Parse(t *testing.T) {
+	// an if condition carries its classified expression token, cached at parse
+	// time the same way a pipeline carries parsedFuncs.
+	condToken := &termNode{token: BaseToken{TokenType: VariableToken, RawValue: "condition", Var: "condition"}}
+
 	type testCase struct {
 		name     string
 		input    string
@@ -19,7 +25,7 @@ func Test_Parser_Parse(t *testing.T) {
 			name:  "basic conditional",
 			input: "{{ if condition }} Hello, World! {{ endif }}",
 			expected: []Token{
-				BaseToken{TokenType: IfToken, RawValue: "condition"},
+				BaseToken{TokenType: IfToken, RawValue: "condition", parsedExpr: condToken},
 				BaseToken{TokenType: TextToken, RawValue: " Hello, World! "},
 				BaseToken{TokenType: IfEndToken, RawValue: ""},
 			},
@@ -28,7 +34,7 @@ func Test_Parser_Parse(t *testing.T) {
 			name:  "basic conditional without spaces",
 			input: "{{if condition}} Hello, World! {{endif}}",
 			expected: []Token{
-				BaseToken{TokenType: IfToken, RawValue: "condition"},
+				BaseToken{TokenType: IfToken, RawValue: "condition", parsedExpr: condToken},
 				BaseToken{TokenType: TextToken, RawValue: " Hello, World! "},
 				BaseToken{TokenType: IfEndToken, RawValue: ""},
 			},
@@ -37,7 +43,7 @@ func Test_Parser_Parse(t *testing.T) {
 			name:  "conditional with variable",
 			input: "{{ if condition }}{{ content }}{{ endif }}",
 			expected: []Token{
-				BaseToken{TokenType: IfToken, RawValue: "condition"},
+				BaseToken{TokenType: IfToken, RawValue: "condition", parsedExpr: condToken},
 				BaseToken{TokenType: VariableToken, RawValue: "content", Var: "content"},
 				BaseToken{TokenType: IfEndToken, RawValue: ""},
 			},
@@ -46,7 +52,7 @@ func Test_Parser_Parse(t *testing.T) {
 			name:  "conditional with variable with filters",
 			input: "{{ if condition }}{{ content | xss | summary:255,300 }}{{ endif }}",
 			expected: []Token{
-				BaseToken{TokenType: IfToken, RawValue: "condition"},
+				BaseToken{TokenType: IfToken, RawValue: "condition", parsedExpr: condToken},
 				BaseToken{TokenType: FilteredVariableToken, RawValue: "content | xss | summary:255,300", Var: "content", parsedVar: "content", parsedFuncs: []Func{{Name: "xss", Args: []Arg{}}, {Name: "summary", Args: []Arg{{Value: 255}, {Value: 300}}}}},
 				BaseToken{TokenType: IfEndToken, RawValue: ""},
 			},
@@ -56,7 +62,7 @@ func Test_Parser_Parse(t *testing.T) {
 			input: "something cool {{ if condition }} beep {{ content | xss | summary:255,300 }}{{ endif }} cool ending ",
 			expected: []Token{
 				BaseToken{TokenType: TextToken, RawValue: "something cool "},
-				BaseToken{TokenType: IfToken, RawValue: "condition"},
+				BaseToken{TokenType: IfToken, RawValue: "condition", parsedExpr: condToken},
 				BaseToken{TokenType: TextToken, RawValue: " beep "},
 				BaseToken{TokenType: FilteredVariableToken, RawValue: "content | xss | summary:255,300", Var: "content", parsedVar: "content", parsedFuncs: []Func{{Name: "xss", Args: []Arg{}}, {Name: "summary", Args: []Arg{{Value: 255}, {Value: 300}}}}},
 				BaseToken{TokenType: IfEndToken, RawValue: ""},
@@ -71,7 +77,7 @@ func Test_Parser_Parse(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			result, err := p.Parse(tc.input)
 			assert.Equal(t, tc.err, err)
-			assert.Equal(t, tc.expected, result)
+			assert.Equal(t, tc.expected, withoutPos(result))
 		})
 	}
 }
@@ -118,11 +124,508 @@ func Test_Parser_ParseVariable(t *testing.T) {
 				return
 			}
 			assert.NoError(t, err)
-			assert.Equal(t, tt.expected, tokens)
+			assert.Equal(t, tt.expected, withoutPos(tokens))
 		})
 	}
 }
 
+// withoutPos zeroes token positions and tag source, so the table tests above
+// compare token kinds and text alone. Positions are asserted on their own in
+// Test_Parser_Positions.
+func withoutPos(tokens []Token) []Token {
+	out := make([]Token, len(tokens))
+	for i, tok := range tokens {
+		if bt, ok := tok.(BaseToken); ok {
+			bt.PosValue, bt.tag = Position{}, ""
+			tok = bt
+		}
+		out[i] = tok
+	}
+	return out
+}
+
+func Test_Parser_Positions(t *testing.T) {
+	input := "Dear {{ name }},\n  {{ if paid }}\n\tThanks ✓ {{ total | decimal:2 }}\n  {{ endif }}\n"
+
+	tokens, err := NewStringParser().Parse(input)
+	assert.NoError(t, err)
+
+	type want struct {
+		tt   TokenType
+		line int
+		col  int
+	}
+	expected := []want{
+		{TextToken, 1, 1},
+		{VariableToken, 1, 6},
+		{TextToken, 1, 16},
+		{IfToken, 2, 3},
+		// the newline after the auto-trimmed if is stripped, so the text starts on line 3
+		{TextToken, 3, 1},
+		{FilteredVariableToken, 3, 11},
+		{TextToken, 3, 34},
+		{IfEndToken, 4, 3},
+		// the trailing newline the endif line ate leaves an empty text run behind
+		{TextToken, 5, 1},
+	}
+	assert.Len(t, tokens, len(expected))
+	for i, w := range expected {
+		pos := tokens[i].(Positioned).Pos()
+		if tokens[i].Type() != w.tt || pos.Line != w.line || pos.Column != w.col {
+			t.Fatalf("token %d: got type %d at %d:%d, want type %d at %d:%d", i, tokens[i].Type(), pos.Line, pos.Column, w.tt, w.line, w.col)
+		}
+		assert.True(t, strings.HasPrefix(input[pos.Offset:], strings.TrimLeft(tokens[i].Raw(), " ")) || tokens[i].Type() != TextToken,
+			"token %d offset %d does not point at its text", i, pos.Offset)
+	}
+}
+
+func Test_Parser_SyntaxErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		input   string
+		line    int
+		column  int
+		message string
+	}{
+		{
+			name:    "unclosed tag",
+			input:   "Hello\n{{ name",
+			line:    2,
+			column:  1,
+			message: "unclosed tag",
+		},
+		{
+			name:    "unrecognized contents",
+			input:   "{{ 42 }}",
+			line:    1,
+			column:  4,
+			message: "unrecognized tag contents",
+		},
+		{
+			name:    "empty tag",
+			input:   "a {{ }} b",
+			line:    1,
+			column:  5,
+			message: "empty tag",
+		},
+		{
+			name:    "stray endfor",
+			input:   "x\n  {{ endfor }}",
+			line:    2,
+			column:  3,
+			message: "endfor without a matching for",
+		},
+		{
+			name:    "mismatched closer",
+			input:   "{{ if a }}{{ endfor }}",
+			line:    1,
+			column:  11,
+			message: "endfor closes a if block",
+		},
+		{
+			name:    "unclosed if reports the opener",
+			input:   "{{ for x in xs }}\n{{ if x }}{{ endfor }}",
+			line:    2,
+			column:  11,
+			message: "endfor closes a if block",
+		},
+		{
+			name:    "never closed",
+			input:   "a\n{{ for x in xs }}{{ x }}",
+			line:    2,
+			column:  1,
+			message: "for block is never closed",
+		},
+		{
+			name:    "else outside if",
+			input:   "{{ else }}",
+			line:    1,
+			column:  1,
+			message: "else outside an if or for block",
+		},
+		{
+			name:    "elif outside if",
+			input:   "{{ for x in xs }}{{ elif x }}{{ endfor }}",
+			line:    1,
+			column:  18,
+			message: "elif outside an if block",
+		},
+		{
+			name:    "second else of a for",
+			input:   "{{ for x in xs }}1{{ else }}2{{ else }}3{{ endfor }}",
+			line:    1,
+			column:  30,
+			message: "for block already has an else",
+		},
+		{
+			name:    "break outside a loop",
+			input:   "{{ if a }}\n  {{ break }}{{ endif }}",
+			line:    2,
+			column:  3,
+			message: "break outside a for loop",
+		},
+		{
+			name:    "continue outside a loop",
+			input:   "{{ continue }}",
+			line:    1,
+			column:  1,
+			message: "continue outside a for loop",
+		},
+		{
+			name:    "for filter without condition",
+			input:   "{{ for x in xs if }}{{ endfor }}",
+			line:    1,
+			column:  15,
+			message: "for filter is missing a condition",
+		},
+		{
+			name:    "for filter that does not parse",
+			input:   "{{ for x in xs if x | }}{{ endfor }}",
+			line:    1,
+			column:  22,
+			message: "missing modifier name after",
+		},
+		{
+			name:    "cycle without values",
+			input:   "{{ for x in xs }}{{ loop.cycle() }}{{ endfor }}",
+			line:    1,
+			column:  21,
+			message: "loop.cycle() takes at least one value",
+		},
+		{
+			name:    "range outside a for",
+			input:   "{{ set xs = range(3) }}",
+			line:    1,
+			column:  13,
+			message: "range() is only valid as the iterable of a for loop",
+		},
+		{
+			name:    "range with too many arguments",
+			input:   "{{ for i in range(1, 2, 3, 4) }}{{ endfor }}",
+			line:    1,
+			column:  13,
+			message: "range() takes 1 to 3 arguments, got 4",
+		},
+		{
+			name:    "elif after else",
+			input:   "{{ if a }}1{{ else }}2{{ elif b }}3{{ endif }}",
+			line:    1,
+			column:  23,
+			message: "elif after the else",
+		},
+		{
+			name:    "elif without condition",
+			input:   "{{ if a }}1{{ else if }}2{{ endif }}",
+			line:    1,
+			column:  15,
+			message: "elif is missing a condition",
+		},
+		{
+			name:    "second else",
+			input:   "{{ if a }}1{{ else }}2{{ else }}3{{ endif }}",
+			line:    1,
+			column:  23,
+			message: "already has an else",
+		},
+		{
+			name:    "empty modifier",
+			input:   "{{ name | | upper }}",
+			line:    1,
+			column:  11,
+			message: "missing modifier name",
+		},
+		{
+			name:    "trailing pipe",
+			input:   "{{ name | }}",
+			line:    1,
+			column:  10,
+			message: "missing modifier name",
+		},
+		{
+			name:    "unterminated quote",
+			input:   "{{ name | default:'x }}",
+			line:    1,
+			column:  19,
+			message: "unterminated quoted string",
+		},
+		{
+			name:    "empty argument",
+			input:   "{{ items | join:',', }}",
+			line:    1,
+			column:  21,
+			message: "empty argument",
+		},
+		{
+			name:    "invalid modifier name",
+			input:   "{{ name | up per }}",
+			line:    1,
+			column:  11,
+			message: "invalid modifier name",
+		},
+		{
+			name:    "malformed for",
+			input:   "{{ for items }}{{ endfor }}",
+			line:    1,
+			column:  4,
+			message: "malformed for",
+		},
+		{
+			name:    "unclosed comment",
+			input:   "a\n{{# note }}",
+			line:    2,
+			column:  1,
+			message: "unclosed comment",
+		},
+		{
+			name:    "raw never closed",
+			input:   "x {{ raw }}{{ y }}",
+			line:    1,
+			column:  3,
+			message: "raw block is never closed, missing endraw",
+		},
+		{
+			name:    "stray endraw",
+			input:   "{{ if a }}{{ endraw }}{{ endif }}",
+			line:    1,
+			column:  11,
+			message: "endraw without a matching raw",
+		},
+		{
+			name:    "set without an equals sign",
+			input:   "{{ set total }}",
+			line:    1,
+			column:  4,
+			message: "malformed set",
+		},
+		{
+			name:    "set to a path",
+			input:   "{{ let a.b = 1 }}",
+			line:    1,
+			column:  4,
+			message: "malformed let",
+		},
+		{
+			name:    "set without a value",
+			input:   "{{ set total = }}",
+			line:    1,
+			column:  15,
+			message: "set total is missing a value",
+		},
+		{
+			name:    "set to a malformed value",
+			input:   "{{ set x = a = b }}",
+			line:    1,
+			column:  12,
+			message: "is not a value, variable, or modifier pipeline",
+		},
+		{
+			name:    "capture never closed",
+			input:   "{{ capture body }}x",
+			line:    1,
+			column:  1,
+			message: "capture block is never closed, missing endcapture",
+		},
+		{
+			name:    "capture closed by endfor",
+			input:   "{{ capture body }}{{ endfor }}",
+			line:    1,
+			column:  19,
+			message: "endfor closes a capture block, expected endcapture",
+		},
+		{
+			name:    "macro never closed",
+			input:   "{{ macro m() }}x",
+			line:    1,
+			column:  1,
+			message: "macro block is never closed, missing endmacro",
+		},
+		{
+			name:    "macro inside a block",
+			input:   "{{ if a }}\n{{ macro m() }}{{ endmacro }}{{ endif }}",
+			line:    2,
+			column:  1,
+			message: "macro must be at the top level of a template, not inside a if block",
+		},
+		{
+			name:    "macro without parentheses",
+			input:   "{{ macro m }}{{ endmacro }}",
+			line:    1,
+			column:  4,
+			message: "malformed macro",
+		},
+		{
+			name:    "macro default that is not a literal",
+			input:   "{{ macro m(a, b=c) }}{{ endmacro }}",
+			line:    1,
+			column:  17,
+			message: "default of parameter b must be a literal",
+		},
+		{
+			name:    "macro defined twice",
+			input:   "{{ macro m() }}{{ endmacro }}{{ macro m() }}{{ endmacro }}",
+			line:    1,
+			column:  30,
+			message: "macro m is defined twice",
+		},
+		{
+			name:    "import inside a block",
+			input:   "{{ for x in xs }}{{ from \"a.tpl\" import m }}{{ endfor }}",
+			line:    1,
+			column:  18,
+			message: "from must be at the top level of a template, not inside a for block",
+		},
+		{
+			name:    "import without names",
+			input:   "{{ from \"a.tpl\" m }}",
+			line:    1,
+			column:  17,
+			message: "malformed from",
+		},
+		{
+			name:    "positional argument after a named one",
+			input:   "{{ m(a=1, 2) }}",
+			line:    1,
+			column:  11,
+			message: "positional argument after a named one",
+		},
+		{
+			name:    "text outside a block in a child template",
+			input:   "{{ extends \"base.tpl\" }}\n  lost\n{{ block b }}{{ endblock }}",
+			line:    2,
+			column:  3,
+			message: "text outside a block is never rendered in a template that extends another",
+		},
+		{
+			name:    "output outside a block in a child template",
+			input:   "{{ extends \"base.tpl\" }}{{ name }}",
+			line:    1,
+			column:  25,
+			message: "output outside a block is never rendered",
+		},
+		{
+			name:    "extends twice",
+			input:   "{{ extends \"a.tpl\" }}{{ extends \"b.tpl\" }}",
+			line:    1,
+			column:  22,
+			message: "a template extends at most one other",
+		},
+		{
+			name:    "extends inside a block",
+			input:   "{{ if a }}{{ extends \"a.tpl\" }}{{ endif }}",
+			line:    1,
+			column:  11,
+			message: "extends must be at the top level",
+		},
+		{
+			name:    "extends without a quoted name",
+			input:   "{{ extends base }}",
+			line:    1,
+			column:  12,
+			message: "extends takes a quoted template name",
+		},
+		{
+			name:    "block defined twice",
+			input:   "{{ block b }}{{ endblock }}{{ block b }}{{ endblock }}",
+			line:    1,
+			column:  28,
+			message: "block b is defined twice",
+		},
+		{
+			name:    "block never closed",
+			input:   "{{ block b }}",
+			line:    1,
+			column:  1,
+			message: "block block is never closed, missing endblock",
+		},
+		{
+			name:    "super with arguments",
+			input:   "{{ block b }}{{ super(1) }}{{ endblock }}",
+			line:    1,
+			column:  17,
+			message: "super() takes no arguments",
+		},
+		{
+			name:    "macro named super",
+			input:   "{{ macro super() }}{{ endmacro }}",
+			line:    1,
+			column:  10,
+			message: "super is reserved",
+		},
+		{
+			name:    "malformed if condition",
+			input:   "{{ if a = b }}{{ endif }}",
+			line:    1,
+			column:  7,
+			message: "is not a value, variable, or modifier pipeline",
+		},
+		{
+			name:    "map entry without a colon",
+			input:   "{{ set m = {\"a\" 1} }}",
+			line:    1,
+			column:  13,
+			message: "malformed map entry",
+		},
+		{
+			name:    "map key given twice",
+			input:   "{{ set m = {a: 1, a: 2} }}",
+			line:    1,
+			column:  19,
+			message: "key a is given twice",
+		},
+		{
+			name:    "include with nothing to pass",
+			input:   "{{ include \"a.tpl\" with }}",
+			line:    1,
+			column:  20,
+			message: "with is missing the variables to pass",
+		},
+		{
+			name:    "include only before with",
+			input:   "{{ include \"a.tpl\" only with {} }}",
+			line:    1,
+			column:  20,
+			message: "only must come last in an include",
+		},
+		{
+			name:    "include ignore without missing",
+			input:   "{{ include \"a.tpl\" ignore }}",
+			line:    1,
+			column:  20,
+			message: "expected \"ignore missing\"",
+		},
+		{
+			name:    "include with a malformed map",
+			input:   "{{ include \"a.tpl\" with {\"a\" 1} }}",
+			line:    1,
+			column:  26,
+			message: "malformed map entry",
+		},
+	}
+
+	p := NewStringParser()
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Parse(tt.input)
+			assert.ErrorIs(t, err, ErrInvalidSyntax)
+
+			var syntaxErr *SyntaxError
+			assert.True(t, errors.As(err, &syntaxErr), "expected a *SyntaxError, got %T", err)
+			assert.True(t, strings.Contains(syntaxErr.Msg, tt.message), "message %q does not mention %q", syntaxErr.Msg, tt.message)
+			assert.Equal(t, tt.line, syntaxErr.Line)
+			assert.Equal(t, tt.column, syntaxErr.Column)
+		})
+	}
+}
+
+func Test_SyntaxError_Excerpt(t *testing.T) {
+	_, err := NewStringParser().Parse("first line\n\tHello {{ name | | upper }}\n")
+
+	var syntaxErr *SyntaxError
+	assert.True(t, errors.As(err, &syntaxErr), "expected a *SyntaxError, got %T", err)
+	assert.Equal(t, "line 2, column 18: missing modifier name after \"|\"", syntaxErr.Error())
+	assert.Equal(t, "\tHello {{ name | | upper }}\n\t                ^", syntaxErr.Excerpt())
+}
+
 func Benchmark_Parser_Parse(b *testing.B) {
 	const tmpl = "something cool {{ if condition }} beep {{ content | xss | summary:255,300 }}{{ endif }} cool ending "
 	b.SetBytes(int64(len(tmpl)))
@@ -131,3 +634,43 @@ func Benchmark_Parser_Parse(b *testing.B) {
 		p.Parse(tmpl)
 	}
 }
+
+func Test_NewStringParserWithDelimiters(t *testing.T) {
+	testCases := []struct {
+		name   string
+		opener string
+		closer string
+		valid  bool
+	}{
+		{name: "brackets", opener: "[[", closer: "]]", valid: true},
+		{name: "erb style", opener: "<%", closer: "%>", valid: true},
+		{name: "single characters", opener: "<", closer: ">", valid: true},
+		{name: "empty opener", opener: "", closer: "}}"},
+		{name: "empty closer", opener: "{{", closer: ""},
+		{name: "same pair", opener: "%%", closer: "%%"},
+		{name: "opener inside closer", opener: "<", closer: "<<"},
+		{name: "closer inside opener", opener: "[[", closer: "["},
+		{name: "whitespace", opener: "{ ", closer: " }"},
+		{name: "opener ends in trim marker", opener: "<-", closer: ">"},
+		{name: "closer starts with trim marker", opener: "<", closer: "->"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewStringParserWithDelimiters(tt.opener, tt.closer)
+			if !tt.valid {
+				assert.ErrorIs(t, err, ErrInvalidDelimiters)
+				return
+			}
+			assert.NoError(t, err)
+
+			tokens, err := p.Parse("a " + tt.opener + " name " + tt.closer + " {{ b }}")
+			assert.NoError(t, err)
+			assert.Equal(t, []Token{
+				BaseToken{TokenType: TextToken, RawValue: "a "},
+				BaseToken{TokenType: VariableToken, RawValue: "name", Var: "name"},
+				BaseToken{TokenType: TextToken, RawValue: " {{ b }}"},
+			}, withoutPos(tokens))
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package sintax
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathChars are the characters that make a variable name a path under
+// WithPathAccess. A name without any of them is a plain key, looked up directly.
+const pathChars = `.[\`
+
+// splitPath splits a variable path such as `user.address.city`, `items[0]` or
+// `headers["X-Id"]` into its root variable name and the keys below it. An index
+// in brackets comes back as an int and everything else as a string. A dot
+// escaped with a backslash belongs to its segment, so `user\.name` is the
+// variable literally named "user.name", and a quoted bracket key is taken whole,
+// dots included.
+func splitPath(name string) (string, []any, error) {
+	var (
+		segments []any
+		cur      strings.Builder
+		// closed is set right after a bracket, where a dot leads into the next
+		// segment rather than ending an empty one
+		closed bool
+	)
+	end := func() error {
+		if cur.Len() == 0 {
+			return fmt.Errorf("path %q has an empty segment", name)
+		}
+		segments = append(segments, cur.String())
+		cur.Reset()
+		return nil
+	}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '\\' && i+1 < len(name) && name[i+1] == '.':
+			cur.WriteByte('.')
+			i++
+		case c == '.':
+			if closed && cur.Len() == 0 {
+				closed = false
+				continue
+			}
+			if err := end(); err != nil {
+				return "", nil, err
+			}
+		case c == '[':
+			if cur.Len() > 0 {
+				if err := end(); err != nil {
+					return "", nil, err
+				}
+			} else if len(segments) == 0 {
+				return "", nil, fmt.Errorf("path %q starts with a bracket", name)
+			}
+			key, next, err := bracketKey(name, i)
+			if err != nil {
+				return "", nil, err
+			}
+			segments = append(segments, key)
+			i = next
+			closed = true
+		default:
+			if closed {
+				return "", nil, fmt.Errorf("path %q needs a dot between a bracket and the next key", name)
+			}
+			cur.WriteByte(c)
+		}
+	}
+	if cur.Len() > 0 || !closed {
+		if err := end(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	root, _ := segments[0].(string)
+	return root, segments[1:], nil
+}
+
+// bracketKey reads the bracket segment opening at name[at], returning its key
+// and the index of the closing bracket.
+func bracketKey(name string, at int) (any, int, error) {
+	i := at + 1
+	if i < len(name) && (name[i] == '"' || name[i] == '\'') {
+		quote := name[i]
+		for j := i + 1; j < len(name); j++ {
+			if name[j] == '\\' {
+				j++
+				continue
+			}
+			if name[j] == quote {
+				if j+1 >= len(name) || name[j+1] != ']' {
+					break
+				}
+				return unquote(name[i:j+1], string(quote)), j + 1, nil
+			}
+		}
+		return nil, 0, fmt.Errorf("path %q has an unterminated bracket key", name)
+	}
+	closing := strings.IndexByte(name[i:], ']')
+	if closing < 0 {
+		return nil, 0, fmt.Errorf("path %q has an unterminated bracket", name)
+	}
+	index, err := strconv.Atoi(name[i : i+closing])
+	if err != nil {
+		return nil, 0, fmt.Errorf("path %q has a bracket that is neither an index nor a quoted key", name)
+	}
+	return index, i + closing, nil
+}
+
+// lookup resolves a variable name against vars, reporting whether the root
+// variable exists at all. Without WithPathAccess a name is a literal key. With
+// it, a name holding a dot, bracket or escape is a path walked one
+// functions.Fields.Lookup at a time, and a path that runs out below an existing root is
+// the miss Lookup reports, catchable like any other. A path into the `loop`
+// binding is walked either way.
+func (r *TokenRenderer) lookup(vars map[string]any, name string) (any, bool, error) {
+	if !strings.ContainsAny(name, pathChars) || !r.pathAccess && !isLoopPath(name) {
+		value, ok := vars[name]
+		return value, ok, nil
+	}
+	root, keys, err := splitPath(name)
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := vars[root]
+	if !ok {
+		return nil, false, nil
+	}
+	for _, key := range keys {
+		if l, ok := value.(*loopState); ok {
+			value, err = l.field(key)
+		} else {
+			value, err = r.fields.Lookup(value, key)
+		}
+		if err != nil {
+			return nil, true, fmt.Errorf("path %q: %w", name, err)
+		}
+	}
+	return value, true, nil
+}
@@ -0,0 +1,101 @@
+package sintax
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions"
+)
+
+type pathAccount struct {
+	IBAN  string
+	Owner *pathOwner
+	note  string //nolint:unused // unexported, must stay invisible to templates
+}
+
+type pathOwner struct {
+	Name string
+}
+
+func pathVars() map[string]any {
+	return map[string]any{
+		"user": map[string]any{
+			"name":    "Ada",
+			"address": map[string]any{"city": "Vilnius"},
+		},
+		"items":     []any{"first", "second"},
+		"headers":   map[string]string{"X-Id": "42", "a.b": "dotted"},
+		"user.name": "literal",
+		"account":   &pathAccount{IBAN: "LT12", Owner: &pathOwner{Name: "Grace"}, note: "secret"},
+		"orphan":    pathAccount{IBAN: "LT34"},
+		"grid":      [][]int{{1, 2}, {3, 4}},
+	}
+}
+
+func Test_E2E_PathAccess_Resolves(t *testing.T) {
+	testCases := []struct {
+		template string
+		want     any
+	}{
+		{template: `{{ user.name }}`, want: "Ada"},
+		{template: `{{ user.address.city }}`, want: "Vilnius"},
+		{template: `{{ items[1] }}`, want: "second"},
+		{template: `{{ items.0 }}`, want: "first"},
+		{template: `{{ grid[1][0] }}`, want: 3},
+		{template: `{{ headers["X-Id"] }}`, want: "42"},
+		{template: `{{ headers['a.b'] }}`, want: "dotted"},
+		{template: `{{ user\.name }}`, want: "literal"},
+		{template: `{{ account.IBAN }}`, want: "LT12"},
+		{template: `{{ account.Owner.Name }}`, want: "Grace"},
+		{template: `{{ user.name | upper }}`, want: "ADA"},
+		{template: `{{ user | key:'name' }}`, want: "Ada"},
+		{template: `{{ "x" | concat:user.address.city }}`, want: "xVilnius"},
+		{template: `{{ user.nickname | default:'anon' }}`, want: "anon"},
+		{template: `{{ items[9] | default:'none' }}`, want: "none"},
+		{template: `{{ orphan.Owner.Name | default:'nobody' }}`, want: "nobody"},
+		{template: `{{ account.note | default:'hidden' }}`, want: "hidden"},
+		{template: `{{ if user.address.city == 'Vilnius' }}lt{{ endif }}`, want: "lt"},
+		{template: `{{ if user.nickname }}nick{{ else }}none{{ endif }}`, want: "none"},
+		{template: `{{ for x in user.tags }}{{ x }}{{ endfor }}`, want: ""},
+		{template: `{{ for row in grid }}{{ row[1] }}{{ endfor }}`, want: "24"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.template, func(t *testing.T) {
+			s := New(builtins(), WithPathAccess())
+
+			out, err := s.Render(tt.template, pathVars())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+// An uncaught missing segment fails the render as a miss, the same error the
+// key modifier reports, so it stays identifiable.
+func Test_E2E_PathAccess_MissingSegmentIsAMiss(t *testing.T) {
+	s := New(builtins(), WithPathAccess())
+
+	_, err := s.Render(`{{ user.nickname }}`, pathVars())
+	assert.ErrorIs(t, err, functions.ErrAllowsDefaultFunc)
+
+	// a missing root is still an absent variable
+	_, err = s.Render(`{{ nobody.name }}`, pathVars())
+	assert.ErrorIs(t, err, ErrVariableNotFound)
+	if errors.Is(err, functions.ErrAllowsDefaultFunc) {
+		t.Errorf("got %v, want a missing root to stay a hard error like a missing variable", err)
+	}
+}
+
+// Without the option a dotted name is one literal key, as it always was.
+func Test_E2E_PathAccess_OffByDefault(t *testing.T) {
+	s := New(builtins())
+
+	out, err := s.Render(`{{ user.name }}`, pathVars())
+	assert.NoError(t, err)
+	assert.Equal(t, "literal", out)
+
+	_, err = s.Render(`{{ items[0] }}`, pathVars())
+	assert.ErrorIs(t, err, ErrVariableNotFound)
+}
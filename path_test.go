@@ -0,0 +1,54 @@
+package sintax
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_splitPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		root    string
+		keys    []any
+		wantErr bool
+	}{
+		{name: "user", root: "user"},
+		{name: "user.address.city", root: "user", keys: []any{"address", "city"}},
+		{name: "items[0]", root: "items", keys: []any{0}},
+		{name: "items[0].name", root: "items", keys: []any{0, "name"}},
+		{name: "rows[1][2]", root: "rows", keys: []any{1, 2}},
+		{name: `headers["X-Id"]`, root: "headers", keys: []any{"X-Id"}},
+		{name: `meta['a.b'].c`, root: "meta", keys: []any{"a.b", "c"}},
+		{name: `meta["say \"hi\""]`, root: "meta", keys: []any{`say "hi"`}},
+		{name: `user\.name`, root: "user.name"},
+		{name: `cfg.db\.host`, root: "cfg", keys: []any{"db.host"}},
+		{name: "user.", wantErr: true},
+		{name: "user..name", wantErr: true},
+		{name: "items[x]", wantErr: true},
+		{name: "items[0", wantErr: true},
+		{name: "items[0]name", wantErr: true},
+		{name: `headers["X-Id]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, keys, err := splitPath(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected %q to be rejected, got %q %v", tt.name, root, keys)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to split %q: %v", tt.name, err)
+			}
+			if root != tt.root {
+				t.Errorf("got root %q, want %q", root, tt.root)
+			}
+			if len(keys) != 0 || len(tt.keys) != 0 {
+				if !reflect.DeepEqual(keys, tt.keys) {
+					t.Errorf("got keys %#v, want %#v", keys, tt.keys)
+				}
+			}
+		})
+	}
+}
@@ -1,10 +1,10 @@
 package sintax
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"reflect"
-	"sort"
+	"io"
 	"strconv"
 	"strings"
 
@@ -38,11 +38,54 @@ type Func struct {
 // into tokens, and TokenRenderer turns those tokens into a value, which is not
 // necessarily a string.
 type TokenRenderer struct {
-	funcs    map[string]GlobalModifier
-	ctxFuncs map[string]ContextualModifier
-	parser   *StringParser
-	maxDepth int
-	depth    int
+	funcs      map[string]GlobalModifier
+	ctxFuncs   map[string]ContextualModifier
+	awareFuncs map[string]ContextAwareModifier
+	defs       map[string]ModifierDef
+	parser     *StringParser
+	maxDepth   int
+	depth      int
+	pathAccess bool
+	fields     functions.Fields
+	loader     Loader
+	loaded     *loadCache
+	escape     AutoEscape
+	undefined  UndefinedPolicy
+
+	// macros are the macros the template being rendered defines and imports,
+	// bound by define on the copy that renders it. layout is the chain of
+	// templates it inherits through, when it extends another, and block the
+	// block whose body is rendering, for super().
+	macros macros
+	layout *layout
+	block  *blockFrame
+
+	// loop is the `loop` binding of the innermost for loop rendering, the
+	// parent of any loop nested in it.
+	loop *loopState
+
+	// name is the template the tokens being rendered come from, as the loader
+	// knows it, or "" for the one the render started from. includes is the
+	// chain of include tags the render is inside, for IncludeError.
+	name     string
+	includes []IncludeFrame
+
+	// ctx is the context of the render in progress, and done its Done channel,
+	// read once so the per-token check is a single non-blocking receive. They
+	// and budget are set on the per-render copy begin makes, never on the
+	// shared renderer.
+	ctx  context.Context
+	done <-chan struct{}
+
+	// undefinedLog is where the render records the misses its undefined policy
+	// answered, nil when ctx carries none.
+	undefinedLog *UndefinedLog
+
+	limits Limits
+	budget *budget
+
+	// err is the reason the configuration was rejected, returned by every render.
+	err error
 }
 
 var _ Renderer = (*TokenRenderer)(nil)
@@ -57,10 +100,21 @@ func NewTokenRenderer(opts ...Option) *TokenRenderer {
 // does not resolve the same options twice.
 func newTokenRenderer(cfg *config) *TokenRenderer {
 	return &TokenRenderer{
-		funcs:    cfg.funcs,
-		ctxFuncs: cfg.ctxFuncs,
-		parser:   NewStringParser(),
-		maxDepth: cfg.maxDepth,
+		funcs:      cfg.funcs,
+		ctxFuncs:   cfg.ctxFuncs,
+		awareFuncs: cfg.awareFuncs,
+		defs:       cfg.defs,
+		parser:     cfg.parser,
+		maxDepth:   cfg.maxDepth,
+		pathAccess: cfg.pathAccess,
+		fields:     cfg.fields,
+		loader:     cfg.loader,
+		loaded:     newLoadCache(),
+		escape:     cfg.escape,
+		undefined:  cfg.undefined,
+		ctx:        context.Background(),
+		limits:     cfg.limits,
+		err:        cfg.err,
 	}
 }
 
@@ -90,87 +144,280 @@ func (r *TokenRenderer) renderNested(template string, vars map[string]any) (any,
 	if r.depth+1 > r.maxDepth {
 		return nil, fmt.Errorf("failed to render nested template: %w", ErrMaxDepthExceeded)
 	}
-	tokens, err := NewStringParser().Parse(template)
+	tokens, err := r.parser.Parse(template)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse nested template: %w", err)
 	}
-	child := &TokenRenderer{funcs: r.funcs, ctxFuncs: r.ctxFuncs, parser: r.parser, maxDepth: r.maxDepth, depth: r.depth + 1}
-	return child.Render(tokens, vars)
+	// the nested template is anonymous, so a failure in it is traced to the tag
+	// that rendered it
+	child := *r
+	child.depth = r.depth + 1
+	child.layout, child.block = nil, nil
+	child.name, child.includes = "", nil
+	child.loop = nil
+	out, err := child.render(tokens, vars)
+	if s, ok := out.(string); ok {
+		// its values were escaped as it rendered, so the result is not again
+		return r.markup(s), err
+	}
+	return out, err
 }
 
 // Render processes the provided tokens and variables, returning a rendered string or any value
 func (r *TokenRenderer) Render(tokens []Token, vars map[string]any) (any, error) {
-	out, _, err := r.renderRange(tokens, 0, len(tokens), vars, true)
-	return out, err
+	return r.RenderContext(context.Background(), tokens, vars)
 }
 
-// renderRange renders tokens[start:end] with the given vars. returns the rendered
-// value, the index at which rendering stopped (one past the last token consumed),
-// and any error.
-//
-// if allowDirect is true and the only meaningful token in the range is a single
-// non-string variable, the value is returned directly rather than stringified,
-// so a template that is nothing but `{{ x }}` yields x's own type. otherwise
-// everything is stringified.
-func (r *TokenRenderer) renderRange(tokens []Token, start, end int, vars map[string]any, allowDirect bool) (any, int, error) {
-	var str strings.Builder
+// RenderContext is Render under ctx. The render checks ctx between tokens and
+// between loop iterations, and stops with an error wrapping ErrCanceled and
+// ctx.Err() once it is done. Context-aware modifiers receive ctx as well.
+func (r *TokenRenderer) RenderContext(ctx context.Context, tokens []Token, vars map[string]any) (any, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	rc := r.begin(ctx)
+	out, err := rc.render(tokens, vars)
+	if err != nil {
+		return nil, rc.stopped(err)
+	}
+	return out, nil
+}
+
+// RenderTo renders tokens against vars straight into w, writing each run of
+// text and each value as it is reached rather than assembling the result
+// first. It walks the same code Render does, so the bytes match what
+// RenderString would return. A lone value is written as its text, since a
+// writer has no way to carry a Go type.
+func (r *TokenRenderer) RenderTo(w io.Writer, tokens []Token, vars map[string]any) error {
+	return r.RenderToContext(context.Background(), w, tokens, vars)
+}
+
+// RenderToContext is RenderTo under ctx, checked the way RenderContext checks it.
+func (r *TokenRenderer) RenderToContext(ctx context.Context, w io.Writer, tokens []Token, vars map[string]any) error {
+	if r.err != nil {
+		return r.err
+	}
+	rc := r.begin(ctx)
+	if err := rc.define(tokens); err != nil {
+		return rc.stopped(err)
+	}
+	if err := rc.renderTemplate(rc.newOutput(w), tokens, vars); err != nil {
+		return rc.stopped(err)
+	}
+	return nil
+}
+
+// render renders tokens against vars into a string, or into the value itself
+// for a lone value token.
+func (r *TokenRenderer) render(tokens []Token, vars map[string]any) (any, error) {
+	if err := r.define(tokens); err != nil {
+		return nil, err
+	}
+	// a template that is nothing but `{{ x }}` yields x's own type rather than
+	// its text, so a caller asking a boolean modifier gets the bool back. this
+	// must stay ahead of the text path, which stringifies whatever it writes.
+	if len(tokens) == 1 && isValueToken(tokens[0].Type()) {
+		if err := r.canceled(); err != nil {
+			return nil, err
+		}
+		v, err := r.renderValueToken(tokens[0], vars)
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case SafeString:
+			return r.lone(string(v))
+		case string, markupText:
+			if s, ok := v.(string); ok && r.escape == EscapeNone {
+				return r.lone(s)
+			}
+			// text is escaped the same as it would be among other text
+			var sb strings.Builder
+			if err := r.newOutput(&sb).writeValue(v); err != nil {
+				return nil, err
+			}
+			return sb.String(), nil
+		default:
+			return v, nil
+		}
+	}
+	var sb strings.Builder
+	if err := r.renderTemplate(r.newOutput(&sb), tokens, vars); err != nil {
+		return nil, err
+	}
+	return sb.String(), nil
+}
+
+// lone returns s, the text of a lone value token, once it is within the output
+// limit that writeString holds text written among other text to.
+func (r *TokenRenderer) lone(s string) (any, error) {
+	if limit := r.budget.maxOutput(); limit > 0 && int64(len(s)) > limit {
+		return nil, &LimitError{Limit: limitOutputBytes, Max: limit}
+	}
+	return s, nil
+}
+
+// begin returns a copy of r for one render under ctx, with a fresh budget
+// against its limits. The copy is what keeps one renderer safe to share
+// between concurrent renders, the same way renderNested copies it to count
+// depth while keeping the context and budget of the render it belongs to.
+func (r *TokenRenderer) begin(ctx context.Context) *TokenRenderer {
+	rc := *r
+	rc.ctx = ctx
+	rc.done = ctx.Done()
+	rc.undefinedLog = undefinedLogFrom(ctx)
+	rc.budget = newBudget(r.limits)
+	return &rc
+}
+
+// canceled reports ctx's error, wrapped in ErrCanceled, once the render's
+// context is done. A context that can never be canceled has a nil Done
+// channel, which makes this a receive that never fires.
+func (r *TokenRenderer) canceled() error {
+	select {
+	case <-r.done:
+		return fmt.Errorf("%w: %w", ErrCanceled, r.ctx.Err())
+	default:
+		return nil
+	}
+}
+
+// stopped marks err with ErrCanceled when the context ended the render from
+// somewhere the engine does not check itself, such as a context-aware modifier
+// that gave up on its deadline, so a caller tests for cancellation one way.
+func (r *TokenRenderer) stopped(err error) error {
+	if r.ctx.Err() == nil || errors.Is(err, ErrCanceled) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrCanceled, err)
+}
+
+// isValueToken reports whether a token stands for a value rather than text or
+// a control tag.
+func isValueToken(t TokenType) bool {
+	return t == VariableToken || t == FilteredVariableToken || t == ShorthandIfToken || t == CallToken
+}
+
+// renderRange renders tokens[start:end] with the given vars into out. returns
+// the index at which rendering stopped (one past the last token consumed), and
+// any error. values are stringified as they are written. inside an included
+// template, an error is marked with the include chain and the failing tag.
+func (r *TokenRenderer) renderRange(out *output, tokens []Token, start, end int, vars map[string]any) (int, error) {
+	i, err := r.renderTokens(out, tokens, start, end, vars)
+	if err != nil && i < len(tokens) {
+		err = r.traced(err, tokens[i])
+	}
+	return i, err
+}
+
+// renderTokens is renderRange without the include chain. On an error the index
+// it returns is that of the failing token.
+func (r *TokenRenderer) renderTokens(out *output, tokens []Token, start, end int, vars map[string]any) (int, error) {
 	i := start
 	for i < end {
+		if err := r.canceled(); err != nil {
+			return i, err
+		}
 		token := tokens[i]
 		switch token.Type() {
 		case TextToken:
-			str.WriteString(token.Raw())
+			if err := out.writeString(token.Raw()); err != nil {
+				return i, err
+			}
 			i++
-		case VariableToken, FilteredVariableToken:
-			variable, err := r.renderVariable(token, vars)
+		case VariableToken, FilteredVariableToken, ShorthandIfToken, CallToken:
+			variable, err := r.renderValueToken(token, vars)
 			if err != nil {
-				return nil, i, fmt.Errorf("failed to render variable token '%s': %w", token.Name(), err)
+				return i, err
 			}
-			if val, ok := variable.(string); ok {
-				str.WriteString(val)
-				i++
-				continue
+			// stringify renders a bool as "true"/"false" and an int in base 10, so
+			// a variable interpolated among text needs no special case to read
+			// naturally.
+			if err := out.writeValue(variable); err != nil {
+				return i, err
 			}
-			// pass a single non-string variable straight through, returning the value
-			// directly. this must stay ahead of any type-specific stringifying, since a
-			// branch that writes to str above it makes the passthrough unreachable for
-			// that type. a bool `{{ flag }}` would render "true" rather than answering
-			// with the bool a caller asked a boolean modifier for.
-			if allowDirect && start == i && i+1 == end && str.Len() == 0 {
-				return variable, i + 1, nil
-			}
-			// fmt.Fprint renders a bool as "true"/"false" and an int in base 10, so a
-			// variable interpolated among text needs no special case to read naturally.
-			fmt.Fprint(&str, variable)
 			i++
 		case IfToken:
-			out, next, err := r.renderIf(tokens, i, end, vars)
+			next, err := r.renderIf(out, tokens, i, end, vars)
 			if err != nil {
-				return nil, i, err
+				return i, err
 			}
-			str.WriteString(out)
 			i = next
 		case ForToken:
-			out, next, err := r.renderFor(tokens, i, end, vars)
+			next, err := r.renderFor(out, tokens, i, end, vars)
+			if err != nil {
+				return i, err
+			}
+			i = next
+		case SetToken:
+			if err := r.assign(token, vars); err != nil {
+				return i, err
+			}
+			i++
+		case CaptureToken:
+			next, err := r.renderCapture(tokens, i, end, vars)
 			if err != nil {
-				return nil, i, err
+				return i, err
 			}
-			str.WriteString(out)
 			i = next
-		case ElseToken, IfEndToken, ForEndToken:
+		case MacroToken:
+			// a macro renders where it is called, not where it is defined
+			endIdx, err := findBlockEnd(tokens, i, end, MacroToken, MacroEndToken)
+			if err != nil {
+				return i, err
+			}
+			i = endIdx + 1
+		case BlockToken:
+			next, err := r.renderBlock(out, tokens, i, end, vars)
+			if err != nil {
+				return i, err
+			}
+			i = next
+		case IncludeToken:
+			if err := r.renderInclude(out, token, vars); err != nil {
+				return i, err
+			}
+			i++
+		case BreakToken:
+			return i, errBreak
+		case ContinueToken:
+			return i, errContinue
+		case ElifToken, ElseToken, IfEndToken, ForEndToken, CaptureEndToken, MacroEndToken, BlockEndToken:
 			// caller should have stopped before this, so reaching here means a stray closer
-			return nil, i, fmt.Errorf("unexpected control token: %s", controlName(token.Type()))
+			return i, fmt.Errorf("unexpected control token: %s", controlName(token.Type()))
 		default:
 			i++
 		}
 	}
-	return str.String(), i, nil
+	return i, nil
+}
+
+// renderValueToken renders a value token, naming the token in the error so a
+// failure deep in a template says which tag it came from.
+func (r *TokenRenderer) renderValueToken(token Token, vars map[string]any) (any, error) {
+	variable, err := r.renderValue(token, vars)
+	if err != nil {
+		// a miss the undefined policy answers renders as what it answered with
+		if value, answered, policyErr := r.answerUndefined(token, err); answered {
+			if policyErr == nil {
+				return value, nil
+			}
+			err = policyErr
+		}
+		if token.Type() == ShorthandIfToken || token.Type() == CallToken {
+			return nil, fmt.Errorf("failed to render expression '%s': %w", strings.TrimSpace(token.Raw()), err)
+		}
+		return nil, fmt.Errorf("failed to render variable token '%s': %w", token.Name(), err)
+	}
+	return variable, nil
 }
 
 func controlName(t TokenType) string {
 	switch t {
 	case IfToken:
 		return "if"
+	case ElifToken:
+		return "elif"
 	case ElseToken:
 		return "else"
 	case IfEndToken:
@@ -179,44 +426,101 @@ func controlName(t TokenType) string {
 		return "for"
 	case ForEndToken:
 		return "endfor"
+	case RawToken:
+		return "raw"
+	case RawEndToken:
+		return "endraw"
+	case SetToken:
+		return "set"
+	case CaptureToken:
+		return "capture"
+	case CaptureEndToken:
+		return "endcapture"
+	case MacroToken:
+		return "macro"
+	case MacroEndToken:
+		return "endmacro"
+	case ImportToken:
+		return "from"
+	case BlockToken:
+		return "block"
+	case BlockEndToken:
+		return "endblock"
+	case ExtendsToken:
+		return "extends"
+	case IncludeToken:
+		return "include"
+	case BreakToken:
+		return "break"
+	case ContinueToken:
+		return "continue"
 	default:
 	}
 	return "?"
 }
 
 // findIfEnd locates the matching `endif` for the IfToken at index `start`. it also
-// records the index of the (top-level) `else` if one exists. nested ifs are
-// counted correctly.
-func findIfEnd(tokens []Token, start, end int) (elseIdx, endIdx int, err error) {
-	elseIdx = -1
+// records the indexes of the top-level `elif` and `else` tokens, in order, which
+// split the block into its branches. nested ifs are counted correctly, and so
+// are nested fors, whose own else is not a branch of the if.
+func findIfEnd(tokens []Token, start, end int) (branches []int, endIdx int, err error) {
 	depth := 0
 	for j := start + 1; j < end; j++ {
 		switch tokens[j].Type() {
-		case IfToken:
+		case IfToken, ForToken:
 			depth++
+		case ForEndToken:
+			depth--
 		case IfEndToken:
 			if depth == 0 {
-				return elseIdx, j, nil
+				return branches, j, nil
 			}
 			depth--
-		case ElseToken:
-			if depth == 0 && elseIdx == -1 {
-				elseIdx = j
+		case ElifToken, ElseToken:
+			if depth == 0 {
+				branches = append(branches, j)
 			}
 		default:
 		}
 	}
-	return -1, -1, errors.New("unterminated if block (missing endif)")
+	return nil, -1, errors.New("unterminated if block (missing endif)")
 }
 
 // findForEnd locates the matching `endfor` for the ForToken at index `start`.
 func findForEnd(tokens []Token, start, end int) (int, error) {
+	return findBlockEnd(tokens, start, end, ForToken, ForEndToken)
+}
+
+// findForElse returns the index of the `else` that splits the for block from
+// `start` to `endIdx` into its body and what renders when it visits nothing, or
+// -1 when it has none. the elses of nested ifs and fors are skipped.
+func findForElse(tokens []Token, start, endIdx int) int {
+	depth := 0
+	for j := start + 1; j < endIdx; j++ {
+		switch tokens[j].Type() {
+		case IfToken, ForToken:
+			depth++
+		case IfEndToken, ForEndToken:
+			depth--
+		case ElseToken:
+			if depth == 0 {
+				return j
+			}
+		default:
+		}
+	}
+	return -1
+}
+
+// findBlockEnd locates the closer matching the opener at index `start`, counting
+// nested blocks of the same kind.
+func findBlockEnd(tokens []Token, start, end int, opener, closer TokenType) (int, error) {
 	depth := 0
 	for j := start + 1; j < end; j++ {
 		switch tokens[j].Type() {
-		case ForToken:
+		case opener:
 			depth++
-		case ForEndToken:
+		case closer:
 			if depth == 0 {
 				return j, nil
 			}
@@ -224,74 +528,141 @@ func findForEnd(tokens []Token, start, end int) (int, error) {
 		default:
 		}
 	}
-	return -1, errors.New("unterminated for block (missing endfor)")
+	return -1, fmt.Errorf("unterminated %s block (missing %s)", controlName(opener), controlName(closer))
 }
 
-func (r *TokenRenderer) renderIf(tokens []Token, start, end int, vars map[string]any) (string, int, error) {
-	elseIdx, endIdx, err := findIfEnd(tokens, start, end)
+func (r *TokenRenderer) renderIf(out *output, tokens []Token, start, end int, vars map[string]any) (int, error) {
+	branches, endIdx, err := findIfEnd(tokens, start, end)
 	if err != nil {
-		return "", start, err
+		return start, err
 	}
-	cond, err := r.evalCondition(tokens[start].Raw(), vars)
-	if err != nil {
-		return "", start, err
-	}
-	var bodyStart, bodyEnd int
-	if cond {
-		bodyStart = start + 1
-		if elseIdx >= 0 {
-			bodyEnd = elseIdx
-		} else {
-			bodyEnd = endIdx
+	// walk the branches in order, the if itself first, and render the body of
+	// the first one whose condition holds. an else has no condition and always
+	// does. conditions after the chosen branch are never evaluated.
+	head := start
+	for b := 0; ; b++ {
+		bodyEnd := endIdx
+		if b < len(branches) {
+			bodyEnd = branches[b]
 		}
-	} else if elseIdx >= 0 {
-		bodyStart = elseIdx + 1
-		bodyEnd = endIdx
-	} else {
-		return "", endIdx + 1, nil
-	}
-	out, _, err := r.renderRange(tokens, bodyStart, bodyEnd, vars, false)
-	if err != nil {
-		return "", start, err
+		taken := tokens[head].Type() == ElseToken
+		if !taken {
+			taken, err = r.evalCondition(tokens[head], vars)
+			if err != nil {
+				return start, err
+			}
+		}
+		if taken {
+			if _, err := r.renderRange(out, tokens, head+1, bodyEnd, vars); err != nil {
+				return start, err
+			}
+			return endIdx + 1, nil
+		}
+		if b == len(branches) {
+			return endIdx + 1, nil
+		}
+		head = branches[b]
 	}
-	s, _ := out.(string)
-	return s, endIdx + 1, nil
 }
 
-func (r *TokenRenderer) renderFor(tokens []Token, start, end int, vars map[string]any) (string, int, error) {
+func (r *TokenRenderer) renderFor(out *output, tokens []Token, start, end int, vars map[string]any) (int, error) {
 	endIdx, err := findForEnd(tokens, start, end)
 	if err != nil {
-		return "", start, err
+		return start, err
 	}
 	tok := tokens[start]
 	spec := tok.Name()
 	expr := tok.LoopExpr()
 	if spec == "" || expr == "" {
-		return "", start, fmt.Errorf("invalid for expression: %q", tok.Raw())
+		return start, fmt.Errorf("invalid for expression: %q", tok.Raw())
 	}
 	keyName, loopVar := "", spec
 	if idx := strings.IndexByte(spec, ','); idx >= 0 {
 		keyName = spec[:idx]
 		loopVar = spec[idx+1:]
 	}
+	bodyEnd := endIdx
+	elseIdx := findForElse(tokens, start, endIdx)
+	if elseIdx >= 0 {
+		bodyEnd = elseIdx
+	}
 
-	iterable, err := r.evalExpr(expr, vars)
+	iterable, err := r.evalParsed(tok, expr, vars)
 	if err != nil {
-		return "", start, err
+		return start, err
 	}
-	if iterable == nil {
-		return "", endIdx + 1, nil
+	src, err := r.loopSource(iterable, expr)
+	if err != nil {
+		return start, err
+	}
+	defer src.close()
+
+	keyKey := loopVar + "_key"
+	// bind sets the loop variable and the key of the item at position i of the
+	// loop. a sequence binds its position under the user-chosen key name, as in
+	// "for i, v in xs", and a map, struct or iter.Seq2 its key, under
+	// loopVar_key when the tag names none.
+	bind := func(scope map[string]any, i int, item loopItem) {
+		scope[loopVar] = item.value
+		switch {
+		case keyName != "" && src.keyed:
+			scope[keyName] = item.key
+		case keyName != "":
+			scope[keyName] = i
+		case src.keyed:
+			scope[keyKey] = item.key
+		default:
+		}
 	}
 
-	var sb strings.Builder
-	rv := reflect.ValueOf(iterable)
-	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
-		if rv.IsNil() {
-			return "", endIdx + 1, nil
+	filter, err := r.forFilterOf(tok)
+	if err != nil {
+		return start, err
+	}
+	if filter != nil {
+		// the filter decides an item before the body sees it, so the positions
+		// and _last bindings count only the items it keeps
+		if err := src.filter(bind, func(scope map[string]any) (bool, error) {
+			// a lazy source may turn down items for as long as it likes, so
+			// each one is a point to notice a canceled render
+			if err := r.canceled(); err != nil {
+				return false, err
+			}
+			ok, err := r.evalTruth(filter, scope)
+			if err != nil {
+				return false, fmt.Errorf("failed to evaluate for filter: %w", err)
+			}
+			// an item the filter turns down spends an iteration too, or a loop
+			// that keeps nothing would run unbounded under MaxLoopIterations
+			if !ok {
+				if err := r.budget.iterate(); err != nil {
+					return false, err
+				}
+			}
+			return ok, nil
+		}, childScope(vars)); err != nil {
+			return start, err
 		}
-		rv = rv.Elem()
 	}
 
+	item, ok, err := src.next()
+	if err != nil {
+		return start, err
+	}
+	if !ok {
+		if elseIdx >= 0 {
+			if _, err := r.renderRange(out, tokens, elseIdx+1, endIdx, vars); err != nil {
+				return start, err
+			}
+		}
+		return endIdx + 1, nil
+	}
+
+	if err := r.budget.enterLoop(); err != nil {
+		return start, err
+	}
+	defer r.budget.leaveLoop()
+
 	// the loop-binding key names are constant across iterations, so build them once
 	// rather than re-concatenating loopVar+"_index" etc. on every pass.
 	idxKey := loopVar + "_index"
@@ -302,66 +673,113 @@ func (r *TokenRenderer) renderFor(tokens []Token, start, end int, vars map[strin
 	// overwritten each pass, so a fresh copy per iteration is unnecessary. this
 	// is sound only because nothing retains the map beyond the synchronous body
 	// render - global modifiers never receive vars, and contextual modifiers
-	// must not hold on to it past their call.
+	// must not hold on to it past their call. a body that sets or captures is
+	// the exception, since what one iteration binds must not reach the next, so
+	// it gets a fresh copy every pass.
 	child := childScope(vars)
+	fresh := assigns(tokens[start+1 : bodyEnd])
+
+	state := &loopState{length: src.length, parent: r.loop}
+	r.loop = state
+	defer func() { r.loop = state.parent }()
+	// the item after the current one is read before the body renders, which is
+	// how a lazy source with no length still knows its last item
+	for i := 0; ok; i++ {
+		next, more, err := src.next()
+		if err != nil {
+			return start, err
+		}
+		if fresh && i > 0 {
+			child = childScope(vars)
+		}
+		state.index, state.last, state.next = i, !more, nil
+		if more {
+			state.next = next.value
+		}
+		child[loopName] = state
+		bind(child, i, item)
+		child[idxKey] = i
+		child[firstKey] = i == 0
+		child[lastKey] = !more
+		if err := r.nextIteration(); err != nil {
+			return start, err
+		}
+		_, err = r.renderRange(out, tokens, start+1, bodyEnd, child)
+		if errors.Is(err, errBreak) {
+			break
+		}
+		if err != nil && !errors.Is(err, errContinue) {
+			return start, err
+		}
+		state.prev = item.value
+		item, ok = next, more
+	}
 
-	switch rv.Kind() {
-	case reflect.Slice, reflect.Array:
-		n := rv.Len()
-		for i := range n {
-			child[loopVar] = rv.Index(i).Interface()
-			if keyName != "" {
-				// "for i, v in xs" binds the index under the user-chosen name
-				child[keyName] = i
-			}
-			child[idxKey] = i
-			child[firstKey] = i == 0
-			child[lastKey] = i == n-1
-			out, _, err := r.renderRange(tokens, start+1, endIdx, child, false)
-			if err != nil {
-				return "", start, err
-			}
-			s, _ := out.(string)
-			sb.WriteString(s)
-		}
-	case reflect.Map:
-		keys := rv.MapKeys()
-		if rv.Type().Key().Kind() == reflect.String {
-			// string-keyed maps (the common case): compare the key strings
-			// directly, avoiding the fmt.Sprint + interface boxing the generic
-			// path allocates on every comparison.
-			sort.Slice(keys, func(a, b int) bool {
-				return keys[a].String() < keys[b].String()
-			})
-		} else {
-			sort.Slice(keys, func(a, b int) bool {
-				return fmt.Sprint(keys[a].Interface()) < fmt.Sprint(keys[b].Interface())
-			})
-		}
-		keyKey := loopVar + "_key"
-		n := len(keys)
-		for i, k := range keys {
-			child[loopVar] = rv.MapIndex(k).Interface()
-			if keyName != "" {
-				child[keyName] = k.Interface()
-			} else {
-				child[keyKey] = k.Interface()
-			}
-			child[idxKey] = i
-			child[firstKey] = i == 0
-			child[lastKey] = i == n-1
-			out, _, err := r.renderRange(tokens, start+1, endIdx, child, false)
-			if err != nil {
-				return "", start, err
-			}
-			s, _ := out.(string)
-			sb.WriteString(s)
+	return endIdx + 1, nil
+}
+
+// assign evaluates a set tag's value and binds it in vars, the scope the tag
+// sits in.
+func (r *TokenRenderer) assign(token Token, vars map[string]any) error {
+	node, err := r.exprOf(token)
+	if err != nil {
+		return err
+	}
+	value, err := node.eval(r, vars)
+	if err != nil {
+		return fmt.Errorf("failed to set '%s': %w", token.Name(), err)
+	}
+	vars[token.Name()] = value
+	return nil
+}
+
+// renderCapture renders the body of the capture block at index `start` into a
+// string and binds it in vars under the capture's name. The body shares the
+// scope the capture sits in, so a set inside it binds there too.
+func (r *TokenRenderer) renderCapture(tokens []Token, start, end int, vars map[string]any) (int, error) {
+	endIdx, err := findBlockEnd(tokens, start, end, CaptureToken, CaptureEndToken)
+	if err != nil {
+		return start, err
+	}
+	var sb strings.Builder
+	if _, err := r.renderRange(r.newOutput(&sb), tokens, start+1, endIdx, vars); err != nil {
+		return start, err
+	}
+	vars[tokens[start].Name()] = r.markup(sb.String())
+	return endIdx + 1, nil
+}
+
+// templateScope returns the scope a template renders in. A template that sets
+// or captures anything gets a copy of vars to bind into, so the caller's map
+// is never written to. One that does not renders in vars itself.
+func templateScope(tokens []Token, vars map[string]any) map[string]any {
+	if !assigns(tokens) {
+		return vars
+	}
+	return childScope(vars)
+}
+
+// assigns reports whether tokens bind any variable, with a set or a capture.
+// A block counts as well, since the body it renders may be an override from
+// another template that binds one.
+func assigns(tokens []Token) bool {
+	for _, tok := range tokens {
+		switch tok.Type() {
+		case SetToken, CaptureToken, BlockToken:
+			return true
+		default:
 		}
-	default:
-		return "", start, fmt.Errorf("for: %q is not iterable (got %s)", expr, rv.Kind())
 	}
+	return false
+}
 
-	return sb.String(), endIdx + 1, nil
+// nextIteration checks the context and spends one loop iteration from the
+// budget, ahead of every pass through a loop body.
+func (r *TokenRenderer) nextIteration() error {
+	if err := r.canceled(); err != nil {
+		return err
+	}
+	return r.budget.iterate()
 }
 
 // childScope returns a shallow copy of parent. loop bindings are added to the
@@ -375,14 +793,45 @@ func childScope(parent map[string]any) map[string]any {
 	return child
 }
 
-// evalCondition renders a single expression and returns its truthiness via
-// functions.ConditionIsTrue.
-func (r *TokenRenderer) evalCondition(expr string, vars map[string]any) (bool, error) {
-	val, err := r.evalExpr(expr, vars)
+// evalCondition evaluates an IfToken's or ElifToken's condition and returns its
+// truthiness via functions.ConditionIsTrue.
+func (r *TokenRenderer) evalCondition(token Token, vars map[string]any) (bool, error) {
+	node, err := r.exprOf(token)
 	if err != nil {
 		return false, err
 	}
-	return functions.ConditionIsTrue(val), nil
+	return r.evalTruth(node, vars)
+}
+
+// evalParsed evaluates the expression a control token carries, preferring the
+// expression cached on BaseToken at parse time and falling back to evalExpr on
+// the raw expression for tokens that lack it. A for iterable may also be a
+// range() call.
+func (r *TokenRenderer) evalParsed(token Token, expr string, vars map[string]any) (any, error) {
+	if bt, ok := token.(BaseToken); ok && bt.parsedExpr != nil {
+		return r.evalLenient(bt.parsedExpr, vars)
+	}
+	if isRangeCall(expr) {
+		node, err := r.parser.parseRange(strings.TrimSpace(expr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q: %s", expr, err.msg)
+		}
+		return node.eval(r, vars)
+	}
+	return r.evalExpr(expr, vars)
+}
+
+// renderValue renders a token that stands for a value: a variable, a modifier
+// pipeline, an inline conditional, or a macro call.
+func (r *TokenRenderer) renderValue(token Token, vars map[string]any) (any, error) {
+	if token.Type() != ShorthandIfToken && token.Type() != CallToken {
+		return r.renderVariable(token, vars)
+	}
+	node, err := r.exprOf(token)
+	if err != nil {
+		return nil, err
+	}
+	return node.eval(r, vars)
 }
 
 // evalExpr evaluates a bare expression (the body of a {{ ... }} without the
@@ -408,14 +857,7 @@ func (r *TokenRenderer) evalExpr(expr string, vars map[string]any) (any, error)
 	if tt != VariableToken && tt != FilteredVariableToken {
 		return nil, fmt.Errorf("expression %q did not parse to a variable", expr)
 	}
-	value, err := r.renderVariable(r.parser.createToken(tt, expr), vars)
-	if err != nil {
-		if errors.Is(err, functions.ErrAllowsDefaultFunc) {
-			return nil, nil //nolint:nilnil // deliberate, absent data is nil here, which reads as false and iterates nothing
-		}
-		return nil, err
-	}
-	return value, nil
+	return r.evalLenient(&termNode{token: r.parser.createToken(tt, expr)}, vars)
 }
 
 // renderVariable renders a single variable token. A miss that nothing in the
@@ -437,13 +879,16 @@ func (r *TokenRenderer) renderVariable(token Token, vars map[string]any) (any, e
 	}
 
 	if token.Type() == VariableToken {
-		varValue, ok := vars[token.Name()]
+		varValue, ok, err := r.lookup(vars, token.Name())
+		if err != nil {
+			return nil, err
+		}
 		if !ok {
 			return nil, fmt.Errorf("simple %w: %s", ErrVariableNotFound, token.Name())
 		}
 
 		// a bare `{{ x }}` answers with x's own value. stringifying by type here
-		// would contradict renderRange's passthrough and does not even reach the
+		// would contradict Render's passthrough and does not even reach the
 		// text path, which formats the value itself when it sits among other tokens.
 		return varValue, nil
 	}
@@ -456,14 +901,25 @@ func (r *TokenRenderer) renderVariable(token Token, vars map[string]any) (any, e
 	// literal string (e.g. {{ "path.tpl" | file }}) rather than a variable name.
 	var varValue any
 	var varExists bool
+	var pathMiss error
 	if isQuotedWith(varName, `"`) {
 		varValue, varExists = unquote(varName, `"`), true
 	} else if isQuotedWith(varName, `'`) {
 		varValue, varExists = unquote(varName, `'`), true
 	} else {
-		varValue, varExists = vars[varName]
+		var err error
+		varValue, varExists, err = r.lookup(vars, varName)
+		if err != nil {
+			if !errors.Is(err, functions.ErrAllowsDefaultFunc) {
+				return nil, err
+			}
+			pathMiss = err
+		}
 	}
 	if !hasFunctionsToApply {
+		if pathMiss != nil {
+			return nil, pathMiss
+		}
 		if !varExists {
 			return nil, fmt.Errorf("complex %w: %s", ErrVariableNotFound, varName)
 		}
@@ -482,17 +938,24 @@ func (r *TokenRenderer) renderVariable(token Token, vars map[string]any) (any, e
 	// missing. It is a plain error, so an unanswered one is simply what this
 	// function returns.
 	var missed error
-	if !varExists {
+	if pathMiss != nil {
+		missed = pathMiss
+	} else if !varExists {
 		missed = functions.Miss("complex %w: %s", ErrVariableNotFound, varName)
 	}
 
 	for _, fn := range funcs {
 		ctxFn, isCtx := r.ctxFuncs[fn.Name]
+		awareFn, isAware := r.awareFuncs[fn.Name]
 		function, ok := r.funcs[fn.Name]
-		if !isCtx && !ok {
+		if !isCtx && !isAware && !ok {
 			return nil, fmt.Errorf("%w: %s", ErrFunctionNotFound, fn.Name)
 		}
 
+		if err := r.budget.call(); err != nil {
+			return nil, err
+		}
+
 		args := make([]any, len(fn.Args))
 		for i, arg := range fn.Args {
 			if arg.Var {
@@ -500,7 +963,10 @@ func (r *TokenRenderer) renderVariable(token Token, vars map[string]any) (any, e
 				if !ok {
 					return nil, fmt.Errorf("function arg: %w: %s", ErrVariableNotFound, arg.Value)
 				}
-				argValue, ok := vars[varName]
+				argValue, ok, err := r.lookup(vars, varName)
+				if err != nil {
+					return nil, fmt.Errorf("function arg: %w", err)
+				}
 				if !ok {
 					return nil, fmt.Errorf("function arg: %w: %s", ErrVariableNotFound, arg.Value)
 				}
@@ -512,10 +978,19 @@ func (r *TokenRenderer) renderVariable(token Token, vars map[string]any) (any, e
 
 		var out any
 		var applyErr error
-		if isCtx {
+		switch {
+		case isCtx:
 			out, applyErr = ctxFn(r.renderNested, vars, varValue, args)
-		} else {
+		case isAware:
+			out, applyErr = awareFn(r.ctx, varValue, args)
+		default:
+			if m, ok := varValue.(markupText); ok {
+				varValue = SafeString(m)
+			}
 			out, applyErr = function(varValue, args)
+			if s, ok := out.(string); ok && r.escape != EscapeNone && functions.IsMarkup(function) {
+				out = r.markedUp(s)
+			}
 		}
 		if applyErr != nil {
 			// a miss already in flight means this modifier was handed the nil standing
@@ -0,0 +1,133 @@
+package sintax
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+// RenderTo shares its walk with Render, so every shape of template must stream
+// the exact bytes RenderString returns for it, lone values included.
+func Test_E2E_RenderTo_MatchesRenderString(t *testing.T) {
+	vars := map[string]any{
+		"name":  "ada",
+		"n":     7,
+		"paid":  true,
+		"items": []any{1, 2, 3},
+		"row":   map[string]any{"b": 2, "a": 1},
+		"tags":  "x,y",
+		"none":  []any{},
+	}
+	templates := []string{
+		"",
+		"plain text",
+		"{{ name }}",
+		"{{ n }}",
+		"{{ paid }}",
+		"{{ tags | split:',' }}",
+		"Dear {{ name | upper }}, you owe {{ n }}.",
+		"{{ paid ? 'paid' : 'due' }}",
+		"{{ if paid }}yes{{ else }}no{{ endif }}",
+		"{{ if n > 9 }}big{{ elif n > 5 }}mid{{ else }}small{{ endif }}",
+		"{{ for i, x in items }}{{ i }}={{ x }}{{ if x_last }}.{{ else }}, {{ endif }}{{ endfor }}",
+		"{{ for k, v in row }}{{ k }}:{{ v }} {{ endfor }}",
+		"{{ for x in none }}never{{ endfor }}after",
+	}
+
+	s := New(builtins())
+	for _, tmpl := range templates {
+		t.Run(tmpl, func(t *testing.T) {
+			want, err := s.RenderString(tmpl, vars)
+			assert.NoError(t, err)
+
+			var buf bytes.Buffer
+			assert.NoError(t, s.RenderTo(&buf, tmpl, vars))
+			assert.Equal(t, want, buf.String())
+		})
+	}
+}
+
+func Test_E2E_RenderTo_CompiledTemplate(t *testing.T) {
+	tmpl, err := New(builtins()).Compile("{{ for x in items }}<{{ x | upper }}>{{ endfor }}")
+	assert.NoError(t, err)
+
+	var sb strings.Builder
+	assert.NoError(t, tmpl.ExecuteTo(&sb, map[string]any{"items": []any{"a", "b"}}))
+	assert.Equal(t, "<A><B>", sb.String())
+}
+
+// A loop writes each iteration as it finishes, so the iterations before a
+// failing one have already reached the writer when the error comes back.
+func Test_E2E_RenderTo_StreamsLoopIterations(t *testing.T) {
+	var w recordingWriter
+	err := New(builtins()).RenderTo(&w, "head|{{ for x in items }}{{ x | key:'name' }};{{ endfor }}", map[string]any{
+		"items": []any{map[string]any{"name": "A"}, map[string]any{"name": "B"}, "not a record"},
+	})
+	var modErr *ModifierError
+	assert.True(t, errors.As(err, &modErr), "expected a *ModifierError, got %v", err)
+	assert.Equal(t, "head|A;B;", w.String())
+	assert.Equal(t, []string{"head|", "A", ";", "B", ";"}, w.writes)
+}
+
+func Test_E2E_RenderTo_WriteError(t *testing.T) {
+	boom := errors.New("disk full")
+	w := &failingWriter{limit: 8, err: boom}
+
+	err := New(builtins()).RenderTo(w, "{{ for x in items }}{{ x }}-line\n{{ endfor }}", map[string]any{
+		"items": []any{1, 2, 3},
+	})
+	assert.ErrorIs(t, err, ErrWriteFailed)
+	assert.ErrorIs(t, err, boom)
+
+	var writeErr *WriteError
+	assert.True(t, errors.As(err, &writeErr), "expected a *WriteError, got %T", err)
+	assert.Equal(t, int64(8), writeErr.Written)
+	// the render stopped at the failing write rather than trying every later one
+	assert.Equal(t, 4, w.calls)
+}
+
+// A writer that takes less than it was given without an error of its own still
+// fails the render, as io.ErrShortWrite.
+func Test_E2E_RenderTo_ShortWrite(t *testing.T) {
+	w := &failingWriter{limit: 2}
+
+	err := New(builtins()).RenderTo(w, "hello {{ name }}", map[string]any{"name": "ada"})
+	assert.ErrorIs(t, err, ErrWriteFailed)
+	assert.ErrorIs(t, err, io.ErrShortWrite)
+}
+
+// recordingWriter keeps every write it receives, in order.
+type recordingWriter struct {
+	bytes.Buffer
+	writes []string
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.writes = append(w.writes, s)
+	return w.Buffer.WriteString(s)
+}
+
+// failingWriter accepts limit bytes and then fails with err, or takes short
+// writes silently when err is nil. It has no WriteString, so it also covers
+// writers that only take []byte.
+type failingWriter struct {
+	limit int
+	err   error
+	n     int
+	calls int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.calls++
+	room := w.limit - w.n
+	if len(p) <= room {
+		w.n += len(p)
+		return len(p), nil
+	}
+	w.n += room
+	return room, w.err
+}
@@ -7,7 +7,7 @@ import (
 
 // Render answers a single-token template with the value's own type rather than
 // its text. These assertions go through Render on purpose. The passthrough lives
-// in renderRange, so a test that calls renderVariable proves nothing about it,
+// in TokenRenderer.Render, so a test that calls renderVariable proves nothing about it,
 // and that gap is what let a bool reach callers as "true" while every test
 // stayed green.
 func Test_Render_PassesValueTypesThrough(t *testing.T) {
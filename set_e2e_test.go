@@ -0,0 +1,147 @@
+package sintax
+
+import (
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_E2E_Set(t *testing.T) {
+	vars := map[string]any{
+		"group": map[string]any{"txs": []any{
+			map[string]any{"amount": 1.5},
+			map[string]any{"amount": 2.25},
+		}},
+		"items": []any{1, 2, 3},
+		"paid":  true,
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "names a pipeline once",
+			template: "{{ set total = group | key:'txs' | sum:'amount' | decimal:2 }}{{ total }} / {{ total }}",
+			want:     "3.75 / 3.75",
+		},
+		{
+			name:     "let is the same tag",
+			template: "{{ let n = 42 }}{{ n }}",
+			want:     "42",
+		},
+		{
+			name:     "value is an expression",
+			template: "{{ set label = paid ? 'Paid' : 'Due' }}{{ set big = items | length > 2 }}{{ label }} {{ big }}",
+			want:     "Paid true",
+		},
+		{
+			name:     "value is a map literal",
+			template: "{{ set m = {\"n\": items | length, label: paid ? 'Paid' : 'Due', 'empty': {} } }}{{ m | key:'n' }} {{ m | key:'label' }} {{ m | key:'empty' | length }}",
+			want:     "3 Paid 0",
+		},
+		{
+			name:     "keeps the value's type",
+			template: "{{ set xs = items }}{{ for x in xs }}{{ x }}{{ endfor }}",
+			want:     "123",
+		},
+		{
+			name:     "set in an if is template-global",
+			template: "{{ if paid }}{{ set state = 'closed' }}{{ endif }}{{ state }}",
+			want:     "closed",
+		},
+		{
+			name:     "global set is visible inside later loops",
+			template: "{{ set sep = '-' }}{{ for x in items }}{{ x }}{{ sep }}{{ endfor }}",
+			want:     "1-2-3-",
+		},
+		{
+			name:     "set in a loop is loop-local",
+			template: "{{ set last = 'none' }}{{ for x in items }}{{ set last = x }}{{ endfor }}{{ last }}",
+			want:     "none",
+		},
+		{
+			name:     "set in a loop does not leak into the next iteration",
+			template: "{{ for x in items }}{{ if x == 1 }}{{ set seen = 'yes' }}{{ endif }}{{ seen | default:'no' }} {{ endfor }}",
+			want:     "yes no no ",
+		},
+		{
+			name:     "reassignment",
+			template: "{{ set n = 'a' }}{{ n }}{{ set n = n | upper }}{{ n }}",
+			want:     "aA",
+		},
+		{
+			name:     "block lines are trimmed",
+			template: "a\n  {{ set x = 'v' }}\nb {{ x }}\n",
+			want:     "a\nb v\n",
+		},
+	}
+
+	s := New(builtins())
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+func Test_E2E_Capture(t *testing.T) {
+	vars := map[string]any{"items": []any{"a", "b"}, "name": "ada"}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "renders a block into a variable",
+			template: "{{ capture greeting }}Hello, {{ name | title }}!{{ endcapture }}[{{ greeting }}] [{{ greeting | upper }}]",
+			want:     "[Hello, Ada!] [HELLO, ADA!]",
+		},
+		{
+			name:     "captures a loop",
+			template: "{{ capture list }}{{ for x in items }}{{ x }};{{ endfor }}{{ endcapture }}{{ list | trim_suffix:';' }}",
+			want:     "a;b",
+		},
+		{
+			name:     "capture in a loop is loop-local",
+			template: "{{ for x in items }}{{ capture row }}<{{ x }}>{{ endcapture }}{{ row }}{{ endfor }}{{ row | default:'-' }}",
+			want:     "<a><b>-",
+		},
+		{
+			name:     "block lines are trimmed",
+			template: "{{ capture body }}\n  line\n{{ endcapture }}\n[{{ body }}]",
+			want:     "[  line\n]",
+		},
+	}
+
+	s := New(builtins())
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+// A set never writes to the caller's map, and a nested template's sets stay in
+// the nested template.
+func Test_E2E_Set_LeavesCallerVarsAlone(t *testing.T) {
+	vars := map[string]any{"partial": "{{ set inner = 'x' }}{{ inner }}"}
+
+	out, err := New(builtins()).RenderString("{{ set outer = 'y' }}{{ partial | template }}{{ inner | default:'-' }}", vars)
+	assert.NoError(t, err)
+	assert.Equal(t, "x-", out)
+	assert.Equal(t, map[string]any{"partial": "{{ set inner = 'x' }}{{ inner }}"}, vars)
+}
+
+// A variable named like the keywords still reads as a variable.
+func Test_E2E_Set_KeywordVariables(t *testing.T) {
+	out, err := New(builtins()).RenderString("{{ set | upper }} {{ capture | lower }}", map[string]any{"set": "a", "capture": "B"})
+	assert.NoError(t, err)
+	assert.Equal(t, "A b", out)
+}
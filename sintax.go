@@ -1,8 +1,12 @@
 package sintax
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"maps"
+
+	"github.com/toaweme/sintax/functions"
 )
 
 // Option configures an engine. Options are applied in order, so a later option
@@ -11,9 +15,27 @@ type Option func(*config)
 
 // config is the engine configuration an Option writes to.
 type config struct {
-	funcs    map[string]GlobalModifier
-	ctxFuncs map[string]ContextualModifier
-	maxDepth int
+	funcs      map[string]GlobalModifier
+	ctxFuncs   map[string]ContextualModifier
+	awareFuncs map[string]ContextAwareModifier
+	defs       map[string]ModifierDef
+	maxDepth   int
+	cacheSize  int
+	pathAccess bool
+	fields     functions.Fields
+	limits     Limits
+	escape     AutoEscape
+	undefined  UndefinedPolicy
+	validate   bool
+	opener     string
+	closer     string
+	loader     Loader
+
+	// parser is resolved from opener and closer once every option has run.
+	// err holds the reason the pair was rejected, in which case parser is the
+	// standard one and the engine refuses to render.
+	parser *StringParser
+	err    error
 }
 
 // newConfig resolves opts over an empty modifier set. The zero configuration
@@ -21,21 +43,33 @@ type config struct {
 // passed it and importing sintax links no modifier code on its own.
 func newConfig(opts []Option) *config {
 	cfg := &config{
-		funcs:    make(map[string]GlobalModifier),
-		ctxFuncs: make(map[string]ContextualModifier),
-		maxDepth: defaultMaxTemplateDepth,
+		funcs:      make(map[string]GlobalModifier),
+		ctxFuncs:   make(map[string]ContextualModifier),
+		awareFuncs: make(map[string]ContextAwareModifier),
+		defs:       make(map[string]ModifierDef),
+		maxDepth:   defaultMaxTemplateDepth,
+		opener:     defaultOpener,
+		closer:     defaultCloser,
 	}
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	cfg.parser, cfg.err = NewStringParserWithDelimiters(cfg.opener, cfg.closer)
+	if cfg.err != nil {
+		cfg.parser = NewStringParser()
+	}
 	return cfg
 }
 
 // WithModifiers adds global modifiers keyed by their template names. It merges
 // rather than replaces, so several groups compose in one New call and a later
-// group overrides an earlier one that registered the same name.
+// group overrides an earlier one that registered the same name, whichever kind
+// of modifier that was.
 func WithModifiers(funcs map[string]GlobalModifier) Option {
-	return func(c *config) { maps.Copy(c.funcs, funcs) }
+	return func(c *config) {
+		forget(c, funcs)
+		maps.Copy(c.funcs, funcs)
+	}
 }
 
 // WithContextualModifiers adds contextual modifiers keyed by their template
@@ -43,12 +77,38 @@ func WithModifiers(funcs map[string]GlobalModifier) Option {
 // state rather than only their piped value, so they are wired separately from
 // the global set. Pass none and a template calling one fails to resolve it.
 func WithContextualModifiers(funcs map[string]ContextualModifier) Option {
-	return func(c *config) { maps.Copy(c.ctxFuncs, funcs) }
+	return func(c *config) {
+		forget(c, funcs)
+		maps.Copy(c.ctxFuncs, funcs)
+	}
+}
+
+// WithContextAwareModifiers adds modifiers that receive the render's context,
+// merging on the same terms as WithModifiers. They are handed the context
+// RenderContext was called with, and context.Background() otherwise.
+func WithContextAwareModifiers(funcs map[string]ContextAwareModifier) Option {
+	return func(c *config) {
+		forget(c, funcs)
+		maps.Copy(c.awareFuncs, funcs)
+	}
+}
+
+// forget drops every name in funcs from all three modifier sets, so the option
+// registering them next holds the name alone and a template name always calls
+// the modifier registered for it last. The name's definition goes too, since it
+// described the modifier being replaced.
+func forget[M ~map[string]V, V any](c *config, funcs M) {
+	for name := range funcs {
+		delete(c.funcs, name)
+		delete(c.ctxFuncs, name)
+		delete(c.awareFuncs, name)
+		delete(c.defs, name)
+	}
 }
 
-// WithMaxDepth bounds how deeply the `template` modifier may re-enter the
-// engine before ErrMaxDepthExceeded, guarding against self-referential
-// templates that would otherwise recurse forever. Depths below 1 are ignored,
+// WithMaxDepth bounds how deeply the `template` modifier, macro calls, imports,
+// extends and includes may nest before ErrMaxDepthExceeded, guarding against
+// self-referential templates that would otherwise recurse forever. Depths below 1 are ignored,
 // since an engine that cannot render once is not a useful configuration.
 func WithMaxDepth(depth int) Option {
 	return func(c *config) {
@@ -58,6 +118,74 @@ func WithMaxDepth(depth int) Option {
 	}
 }
 
+// WithCache keeps up to size compiled templates in a least-recently-used cache
+// keyed by a hash of their source, so Render on a template the engine has seen
+// before skips the parser entirely and reuses the compiled token stream. It is
+// off by default, since a cache only pays for itself when the same template
+// text comes around again. Sizes below 1 leave it off.
+func WithCache(size int) Option {
+	return func(c *config) {
+		if size >= 1 {
+			c.cacheSize = size
+		}
+	}
+}
+
+// WithPathAccess makes a dotted or bracketed variable name a path into its
+// value, so `{{ user.name }}`, `{{ items[0] }}` and `{{ headers["X-Id"] }}`
+// walk maps, slices, structs and pointers rather than looking up a variable
+// literally named `user.name`. A segment that is not there is a miss, caught by
+// `default` and read as false by a condition, the way the `key` modifier
+// reports one. A key that itself contains a dot stays reachable by escaping the
+// dot, `{{ user\.name }}`, or by quoting it in brackets, `{{ meta["a.b"] }}`.
+// It is off by default, since it changes what an existing dotted name means.
+func WithPathAccess() Option {
+	return func(c *config) { c.pathAccess = true }
+}
+
+// WithFields sets how the engine itself sees structs: which fields a variable
+// path under WithPathAccess reaches, and which a for loop over a struct visits.
+// The modifiers that read records take their own functions.Fields through
+// their group's ModifiersWith, and defaults.AllWith sets both at once.
+func WithFields(fields functions.Fields) Option {
+	return func(c *config) { c.fields = fields }
+}
+
+// WithLimits caps what each render may spend: the bytes it writes, the loop
+// iterations it runs, the modifier calls it makes and how deeply its loops
+// nest. A render that trips one stops with a *LimitError naming the cap, which
+// matches ErrLimitExceeded. Zero fields are unlimited, and so is an engine
+// without WithLimits. WithMaxDepth still bounds `template` recursion on its own.
+func WithLimits(limits Limits) Option {
+	return func(c *config) { c.limits = limits }
+}
+
+// WithDelimiters sets the pair that marks a tag, in place of "{{" and "}}",
+// for templates of formats that use those themselves, such as Helm charts and
+// GitHub Actions workflows. It reaches every template the engine parses,
+// including those the `template` modifier renders, so a partial uses the same
+// pair as its parent. Trim markers and block auto-trim keep working, so
+// "[[- x -]]" trims like "{{- x -}}". A pair the parser could not read
+// unambiguously (see NewStringParserWithDelimiters) makes every Compile and
+// Render fail with ErrInvalidDelimiters.
+func WithDelimiters(opener, closer string) Option {
+	return func(c *config) {
+		c.opener = opener
+		c.closer = closer
+	}
+}
+
+// WithLoader sets where the engine finds a template that another one refers to
+// by name, such as the file a `{{ from "sepa.tpl" import address }}` reads its
+// macros from, the layout an `{{ extends "base.tpl" }}` renders through, or the
+// partial an `{{ include "row.tpl" }}` renders. Without a loader, such a
+// reference fails the render. The fs modifier package has loaders over a
+// directory allowlist, an io/fs.FS such as an embed.FS, a map and a chain of
+// others; defaults.WithLoader points the `file` modifier at the same one.
+func WithLoader(loader Loader) Option {
+	return func(c *config) { c.loader = loader }
+}
+
 // WithOptions bundles opts into a single Option, so a package can hand out a
 // whole preconfigured engine setup as one value that callers can still layer
 // their own options on top of. See defaults.All.
@@ -71,7 +199,14 @@ func WithOptions(opts ...Option) Option {
 
 type sintax struct {
 	parser Parser
-	render Renderer
+	render *TokenRenderer
+	cache  *templateCache
+	err    error
+
+	// pathAccess is the engine's WithPathAccess, for Inspect to tell a path
+	// from a name, and validate its WithValidation.
+	pathAccess bool
+	validate   bool
 }
 
 var _ Sintax = (*sintax)(nil)
@@ -81,27 +216,73 @@ var _ Sintax = (*sintax)(nil)
 // with the groups you want.
 func New(opts ...Option) *sintax { //nolint:revive // Sintax is the public contract
 	cfg := newConfig(opts)
-	return &sintax{
-		parser: NewStringParser(),
+	s := &sintax{
+		parser: cfg.parser,
 		render: newTokenRenderer(cfg),
+		err:    cfg.err,
+
+		pathAccess: cfg.pathAccess,
+		validate:   cfg.validate,
+	}
+	if cfg.cacheSize > 0 {
+		s.cache = newTemplateCache(cfg.cacheSize)
+	}
+	return s
+}
+
+// Compile parses template once into a Template that can be executed any number
+// of times, from any number of goroutines, without parsing it again. With
+// WithCache configured, Compile consults and fills the same cache Render uses.
+func (s *sintax) Compile(template string) (*Template, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.cache != nil {
+		if tmpl, ok := s.cache.get(template); ok {
+			return tmpl, nil
+		}
+	}
+
+	tokens, err := s.parser.Parse(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	if s.validate {
+		if err := s.render.Validate(tokens); err != nil {
+			return nil, fmt.Errorf("failed to validate template: %w", err)
+		}
+	}
+	tmpl := &Template{source: template, tokens: tokens, render: s.render}
+
+	if s.cache != nil {
+		s.cache.put(tmpl)
 	}
+	return tmpl, nil
 }
 
 // Render parses template and renders it against vars, returning the rendered
 // value. A template that is a single variable or modifier pipeline yields that
 // value's own Go type, while anything with surrounding text renders to a string.
 func (s *sintax) Render(template string, vars map[string]any) (any, error) {
-	tokens, err := s.parser.Parse(template)
+	tmpl, err := s.Compile(template)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse template: %w", err)
+		return nil, err
 	}
+	return tmpl.Execute(vars)
+}
 
-	result, err := s.render.Render(tokens, vars)
+// RenderContext is Render under ctx. The render checks ctx between tokens and
+// between loop iterations, so a template looping over more data than the
+// caller has time for stops once ctx is canceled or its deadline passes,
+// returning an error that matches both ErrCanceled and ctx.Err(). Modifiers
+// registered with WithContextAwareModifiers receive ctx too, so a `file` read
+// gives up on the same deadline.
+func (s *sintax) RenderContext(ctx context.Context, template string, vars map[string]any) (any, error) {
+	tmpl, err := s.Compile(template)
 	if err != nil {
-		return nil, fmt.Errorf("failed to render template: %w", err)
+		return nil, err
 	}
-
-	return result, nil
+	return tmpl.ExecuteContext(ctx, vars)
 }
 
 // Render parses and renders template against vars in one call, using an engine
@@ -111,6 +292,13 @@ func Render(template string, vars map[string]any, opts ...Option) (any, error) {
 	return New(opts...).Render(template, vars)
 }
 
+// RenderContext parses and renders template against vars under ctx in one call,
+// using an engine configured by opts. Prefer New when rendering more than once,
+// so the engine is built once rather than per call.
+func RenderContext(ctx context.Context, template string, vars map[string]any, opts ...Option) (any, error) {
+	return New(opts...).RenderContext(ctx, template, vars)
+}
+
 // RenderString renders template against vars and returns the result as text. It
 // is the ergonomic path for document generation, where the output is always a
 // string rather than the Go value Render hands back for a lone expression. The
@@ -125,11 +313,45 @@ func (s *sintax) RenderString(template string, vars map[string]any) (string, err
 	return stringify(result), nil
 }
 
+// RenderTo parses template and renders it against vars straight into w. Text
+// and values are written as the render reaches them, and a loop writes each
+// iteration as it goes, so a long document never sits in memory whole. The
+// bytes match what RenderString returns for the same input. The writes are as
+// small as the template's pieces, so wrap a writer where each write is costly,
+// such as a network connection, in a bufio.Writer and flush it afterwards.
+//
+// A failed write stops the render and comes back as a *WriteError. Whatever
+// was written before a failure, of either kind, stays written.
+func (s *sintax) RenderTo(w io.Writer, template string, vars map[string]any) error {
+	tmpl, err := s.Compile(template)
+	if err != nil {
+		return err
+	}
+	return tmpl.ExecuteTo(w, vars)
+}
+
+// RenderToContext is RenderTo under ctx, stopped the way RenderContext is. What
+// was written before the render stopped stays written.
+func (s *sintax) RenderToContext(ctx context.Context, w io.Writer, template string, vars map[string]any) error {
+	tmpl, err := s.Compile(template)
+	if err != nil {
+		return err
+	}
+	return tmpl.ExecuteToContext(ctx, w, vars)
+}
+
+// RenderTo parses and renders template against vars straight into w in one
+// call, using an engine configured by opts. Prefer New when rendering more than
+// once, so the engine is built once rather than per call.
+func RenderTo(w io.Writer, template string, vars map[string]any, opts ...Option) error {
+	return New(opts...).RenderTo(w, template, vars)
+}
+
 // stringify renders a value as text the way the engine does when interpolating
 // it into surrounding template text: a string passes through untouched, and
 // anything else is formatted with fmt.Sprint (a bool as "true"/"false", an int
-// in base 10). Keeping this identical to renderRange's interpolation path is
-// what lets RenderString and inline interpolation never diverge.
+// in base 10). renderRange writes every value through it, which is what lets
+// RenderString, RenderTo and inline interpolation never diverge.
 func stringify(v any) string {
 	if str, ok := v.(string); ok {
 		return str
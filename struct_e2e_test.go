@@ -0,0 +1,110 @@
+package sintax
+
+import (
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions"
+	"github.com/toaweme/sintax/functions/collections/access"
+	collquery "github.com/toaweme/sintax/functions/collections/query"
+	"github.com/toaweme/sintax/functions/collections/transform"
+)
+
+type structPayment struct {
+	Reference string  `json:"reference"`
+	Amount    float64 `json:"amount"`
+	Status    string  `json:"status"`
+	internal  string
+}
+
+type structStatement struct {
+	structHeader
+	Payments []structPayment `json:"payments"`
+}
+
+type structHeader struct {
+	Account string `json:"account"`
+}
+
+func (s structStatement) Count() int { return len(s.Payments) }
+
+func structVars() map[string]any {
+	return map[string]any{
+		"statement": &structStatement{
+			structHeader: structHeader{Account: "LT01"},
+			Payments: []structPayment{
+				{Reference: "a", Amount: 10, Status: "paid", internal: "x"},
+				{Reference: "b", Amount: 5.5, Status: "open"},
+			},
+		},
+	}
+}
+
+// structs configures the engine and the record-reading modifiers with the same
+// Fields, the way defaults.AllWith does.
+func structs(fields functions.Fields) Option {
+	return WithOptions(
+		builtins(),
+		WithModifiers(access.ModifiersWith(fields)),
+		WithModifiers(collquery.ModifiersWith(fields)),
+		WithModifiers(transform.ModifiersWith(fields)),
+		WithFields(fields),
+	)
+}
+
+func Test_E2E_Structs_GoNames(t *testing.T) {
+	s := New(builtins())
+
+	out, err := s.Render(
+		`{{ statement | key:'Account' }}: {{ statement | key:'Payments' | pluck:'Reference' | join:',' }} = {{ statement | key:'Payments' | sum:'Amount' }}`,
+		structVars(),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "LT01: a,b = 15.5", out)
+}
+
+func Test_E2E_Structs_TagsMethodsAndPaths(t *testing.T) {
+	s := New(structs(functions.Fields{Tag: "json", Methods: true}), WithPathAccess())
+
+	out, err := s.Render(
+		`{{ statement.account }}/{{ statement.Count }}/{{ statement.payments[1].reference }}/`+
+			`{{ statement | key:'payments' | filter:'status','paid' | pluck:'reference' | join:',' }}/`+
+			`{{ statement.payments | sum:'amount' }}/`+
+			`{{ statement.payments[0].internal | default:'hidden' }}`,
+		structVars(),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "LT01/2/b/a/15.5/hidden", out)
+}
+
+type StructAddress struct {
+	City string
+}
+
+type structOrder struct {
+	StructAddress
+	Number int
+}
+
+// An exported embedded struct is reachable by its type name as well as through
+// the fields it promotes, as in Go.
+func Test_E2E_Structs_EmbeddedName(t *testing.T) {
+	s := New(builtins(), WithPathAccess())
+
+	vars := map[string]any{"o": structOrder{StructAddress: StructAddress{City: "Vilnius"}, Number: 3}}
+	out, err := s.Render("{{ o.City }}/{{ o.StructAddress.City }}/{{ o | key:'StructAddress' | key:'City' }}", vars)
+	assert.NoError(t, err)
+	assert.Equal(t, "Vilnius/Vilnius/Vilnius", out)
+}
+
+func Test_E2E_Structs_Loops(t *testing.T) {
+	s := New(structs(functions.Fields{Tag: "json"}), WithPathAccess())
+
+	out, err := s.Render(
+		`{{ for p in statement.payments }}{{ p | key:'reference' }}={{ p.amount }};{{ endfor }}`+
+			`{{ for k, v in statement.payments[0] }}{{ k }}:{{ v }}{{ if not v_last }},{{ endif }}{{ endfor }}`,
+		structVars(),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "a=10;b=5.5;reference:a,amount:10,status:paid", out)
+}
@@ -0,0 +1,66 @@
+package sintax
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Template is a compiled template, parsed once and ready to render any number of
+// times. Compiling hoists everything the parser does, including the modifier
+// pipelines cached on each token, out of the render path, so executing a
+// Template only walks tokens that are already classified.
+//
+// A Template is immutable once Compile returns it, and rendering keeps no state
+// on it, so one Template may be executed from many goroutines at once.
+type Template struct {
+	source string
+	tokens []Token
+	render *TokenRenderer
+}
+
+// Source returns the template text the Template was compiled from.
+func (t *Template) Source() string { return t.source }
+
+// Execute renders the compiled template against vars. Like Sintax.Render, a
+// template that is a single variable or modifier pipeline yields that value's
+// own Go type, while anything with surrounding text renders to a string.
+func (t *Template) Execute(vars map[string]any) (any, error) {
+	return t.ExecuteContext(context.Background(), vars)
+}
+
+// ExecuteContext is Execute under ctx, stopped the way the engine's
+// RenderContext is.
+func (t *Template) ExecuteContext(ctx context.Context, vars map[string]any) (any, error) {
+	result, err := t.render.RenderContext(ctx, t.tokens, vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	return result, nil
+}
+
+// ExecuteString renders the compiled template against vars and returns the
+// result as text, stringified by the same rule RenderString applies.
+func (t *Template) ExecuteString(vars map[string]any) (string, error) {
+	result, err := t.Execute(vars)
+	if err != nil {
+		return "", err
+	}
+	return stringify(result), nil
+}
+
+// ExecuteTo renders the compiled template against vars straight into w, the
+// way the engine's RenderTo does. A failed write stops the render and comes
+// back as a *WriteError.
+func (t *Template) ExecuteTo(w io.Writer, vars map[string]any) error {
+	return t.ExecuteToContext(context.Background(), w, vars)
+}
+
+// ExecuteToContext is ExecuteTo under ctx, stopped the way the engine's
+// RenderContext is.
+func (t *Template) ExecuteToContext(ctx context.Context, w io.Writer, vars map[string]any) error {
+	if err := t.render.RenderToContext(ctx, w, t.tokens, vars); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	return nil
+}
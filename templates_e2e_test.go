@@ -295,6 +295,65 @@ func Test_If_Else(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, "10110", out)
 	})
+
+	t.Run("elif chain", func(t *testing.T) {
+		tpl := "{{ if a }}A{{ elif b }}B{{ else if c }}C{{ else }}D{{ endif }}"
+		for _, tc := range []struct {
+			vars map[string]any
+			want string
+		}{
+			{map[string]any{"a": true, "b": true, "c": true}, "A"},
+			{map[string]any{"a": false, "b": true, "c": true}, "B"},
+			{map[string]any{"a": false, "b": false, "c": true}, "C"},
+			{map[string]any{"a": false, "b": false, "c": false}, "D"},
+		} {
+			out, err := Render(tpl, tc.vars, funcs)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, out)
+		}
+	})
+
+	t.Run("elif without else", func(t *testing.T) {
+		out, err := Render("a{{ if x }}1{{ elif y }}2{{ endif }}b", map[string]any{"x": false, "y": false}, funcs)
+		assert.NoError(t, err)
+		assert.Equal(t, "ab", out)
+	})
+
+	t.Run("missing value in elif reads false", func(t *testing.T) {
+		out, err := Render("{{ if x }}1{{ elif items | first }}2{{ else }}3{{ endif }}", map[string]any{"x": false, "items": []any{}}, funcs)
+		assert.NoError(t, err)
+		assert.Equal(t, "3", out)
+	})
+
+	t.Run("later conditions are not evaluated", func(t *testing.T) {
+		out, err := Render("{{ if x }}1{{ elif nope }}2{{ endif }}", map[string]any{"x": true}, funcs)
+		assert.NoError(t, err)
+		assert.Equal(t, "1", out)
+	})
+
+	t.Run("nested elif belongs to the inner if", func(t *testing.T) {
+		tpl := "{{ if a }}{{ if b }}ab{{ elif c }}ac{{ endif }}{{ elif d }}d{{ else }}none{{ endif }}"
+		out, err := Render(tpl, map[string]any{"a": true, "b": false, "c": true, "d": true}, funcs)
+		assert.NoError(t, err)
+		assert.Equal(t, "ac", out)
+
+		out, err = Render(tpl, map[string]any{"a": false, "b": false, "c": true, "d": true}, funcs)
+		assert.NoError(t, err)
+		assert.Equal(t, "d", out)
+	})
+
+	t.Run("elif lines are trimmed", func(t *testing.T) {
+		tpl := "<status>\n  {{ if paid }}\n  paid\n  {{ elif due }}\n  due\n  {{ else }}\n  open\n  {{ endif }}\n</status>"
+		out, err := Render(tpl, map[string]any{"paid": false, "due": true}, funcs)
+		assert.NoError(t, err)
+		assert.Equal(t, "<status>\n  due\n</status>", out)
+	})
+
+	t.Run("else prefix is not an else", func(t *testing.T) {
+		out, err := Render("{{ elsewhere }}", map[string]any{"elsewhere": "here"}, funcs)
+		assert.NoError(t, err)
+		assert.Equal(t, "here", out)
+	})
 }
 
 func Test_Whitespace_Trim(t *testing.T) {
@@ -314,6 +373,13 @@ func Test_Whitespace_Trim(t *testing.T) {
 		assert.Equal(t, "PRE\n- a\n- b\nPOST", out)
 	})
 
+	t.Run("control tags on consecutive lines each auto-trim", func(t *testing.T) {
+		input := "PRE\n{{ for x in xs }}\n  {{ if x }}\n- {{ x }}\n  {{ endif }}\n{{ endfor }}\nPOST"
+		out, err := Render(input, map[string]any{"xs": []any{"a", "b"}}, funcs)
+		assert.NoError(t, err)
+		assert.Equal(t, "PRE\n- a\n- b\nPOST", out)
+	})
+
 	t.Run("explicit -}} strips following whitespace", func(t *testing.T) {
 		out, err := Render("a{{ x -}}    b", map[string]any{"x": "X"}, funcs)
 		assert.NoError(t, err)
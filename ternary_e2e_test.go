@@ -0,0 +1,116 @@
+package sintax
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_E2E_Ternary_Renders(t *testing.T) {
+	vars := map[string]any{
+		"paid":  true,
+		"due":   false,
+		"name":  "ada",
+		"other": "bob",
+		"count": 0,
+		"vip":   true,
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		want     any
+	}{
+		{name: "string literals", template: `{{ paid ? 'Paid' : 'Open' }}`, want: "Paid"},
+		{name: "false condition", template: `{{ due ? 'Due' : "Not due" }}`, want: "Not due"},
+		{name: "variables in branches", template: `{{ due ? name : other }}`, want: "bob"},
+		{name: "pipeline in a branch", template: `{{ paid ? name | upper : other }}`, want: "ADA"},
+		{name: "pipeline with an argument", template: `{{ paid ? name | default:'x' : other }}`, want: "ada"},
+		{name: "pipeline as condition", template: `{{ name | upper ? 'yes' : 'no' }}`, want: "yes"},
+		{name: "zero is false", template: `{{ count ? 'some' : 'none' }}`, want: "none"},
+		{name: "numbers", template: `{{ paid ? 1 : 2 }}`, want: 1},
+		{name: "nested in the else branch", template: `{{ due ? 'a' : vip ? 'b' : 'c' }}`, want: "b"},
+		{name: "nested in the then branch", template: `{{ paid ? (vip ? 'a' : 'b') : 'c' }}`, want: "a"},
+		{name: "surrounded by text", template: `Status: {{ paid ? 'Paid' : 'Open' }}.`, want: "Status: Paid."},
+		{name: "missing pipeline condition reads false", template: `{{ nope | first ? 'yes' : 'no' }}`, want: "no"},
+		{name: "branch not taken is not evaluated", template: `{{ paid ? 'ok' : nope }}`, want: "ok"},
+		{name: "trim markers", template: "a\n{{- paid ? 'x' : 'y' -}}\nb", want: "axb"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(builtins())
+
+			out, err := s.Render(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+// A lone expression keeps its Go value, like a lone variable does, so a
+// fallback collection arrives as a collection rather than as its text.
+func Test_E2E_Ternary_Passthrough(t *testing.T) {
+	s := New(builtins())
+
+	out, err := s.Render(`{{ has ? items : [] }}`, map[string]any{"has": false, "items": []any{1}})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{}, out)
+
+	out, err = s.Render(`{{ has ? items : [] }}`, map[string]any{"has": true, "items": []any{1}})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{1}, out)
+}
+
+func Test_E2E_Ternary_InsideLoop(t *testing.T) {
+	s := New(builtins())
+
+	out, err := s.Render(`{{ for p in people }}{{ p | key:'vip' ? '*' : '-' }}{{ endfor }}`, map[string]any{
+		"people": []any{
+			map[string]any{"vip": true},
+			map[string]any{"vip": false},
+			map[string]any{},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "*--", out)
+}
+
+// The branch that is taken is a value, so a miss there fails the render the
+// way it would in its own tag.
+func Test_E2E_Ternary_MissInBranchFails(t *testing.T) {
+	s := New(builtins())
+
+	_, err := s.Render(`{{ ok ? nope : 'x' }}`, map[string]any{"ok": true})
+	assert.Error(t, err)
+}
+
+func Test_E2E_Ternary_SyntaxErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		column   int
+	}{
+		{name: "missing else branch value", template: `{{ a ? 'x' : }}`, column: 13},
+		{name: "missing else branch", template: `{{ a ? 'x' }}`, column: 11},
+		{name: "missing then branch value", template: `{{ a ? : 'y' | x ? 'z' : 'w' }}`, column: 8},
+		{name: "unbalanced parenthesis", template: `{{ (a ? 'x' : 'y' }}`, column: 18},
+		{name: "broken pipeline in a branch", template: `{{ a ? b | | upper : 'y' }}`, column: 12},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(builtins())
+
+			_, err := s.Render(tt.template, map[string]any{})
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("got %v, want a *SyntaxError", err)
+			}
+			if syntaxErr.Column != tt.column {
+				t.Errorf("got column %d (%v), want %d", syntaxErr.Column, syntaxErr, tt.column)
+			}
+		})
+	}
+}
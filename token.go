@@ -1,5 +1,7 @@
 package sintax
 
+import "unicode/utf8"
+
 // TokenType identifies the syntactic kind of a parsed Token.
 type TokenType int
 
@@ -15,8 +17,48 @@ const (
 	ShorthandIfToken
 	ForToken
 	ForEndToken
+	ElifToken
+	CommentToken
+	RawToken
+	RawEndToken
+	SetToken
+	CaptureToken
+	CaptureEndToken
+	MacroToken
+	MacroEndToken
+	CallToken
+	ImportToken
+	BlockToken
+	BlockEndToken
+	ExtendsToken
+	IncludeToken
+	BreakToken
+	ContinueToken
 )
 
+// Position is where a token starts in its template source. Line and Column are
+// 1-based, and Column counts characters rather than bytes, so it matches what
+// an editor shows. Offset is the 0-based byte offset.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// advance returns the position just past text, which starts at p.
+func (p Position) advance(text string) Position {
+	for _, r := range text {
+		p.Offset += utf8.RuneLen(r)
+		if r == '\n' {
+			p.Line++
+			p.Column = 1
+			continue
+		}
+		p.Column++
+	}
+	return p
+}
+
 // Token is a single parsed unit of a template, such as a text run, a
 // variable reference, or a control-flow marker.
 type Token interface {
@@ -27,6 +69,22 @@ type Token interface {
 	LoopExpr() string
 }
 
+// Positioned is a Token that knows where it starts in its template source.
+// Every token Parser.Parse returns is one. The renderer reads a token's position
+// through it where an error or a miss reports one, and takes the zero Position
+// for a Token that is not one.
+type Positioned interface {
+	Pos() Position
+}
+
+// tokenPos returns where token starts, the zero Position when it cannot say.
+func tokenPos(token Token) Position {
+	if p, ok := token.(Positioned); ok {
+		return p.Pos()
+	}
+	return Position{}
+}
+
 // BaseToken is the concrete Token implementation shared by every token kind.
 type BaseToken struct {
 	TokenType TokenType
@@ -35,8 +93,15 @@ type BaseToken struct {
 	ParamVars []string
 	// LoopExprValue holds the iteration expression for ForToken (e.g. "groups",
 	// "items | filter:'a','b'"). For ForToken, Var holds the loop variable name
-	// (e.g. "tx") and LoopExprValue holds the right-hand-side expression.
+	// (e.g. "tx") and LoopExprValue holds the right-hand-side expression, less
+	// any trailing "if" filter clause.
 	LoopExprValue string
+	// PosValue is where the token starts in the template: the opening delimiter
+	// of a tag, or the first character of a text run.
+	PosValue Position
+	// tag is the tag's source text as written, delimiters and trim markers
+	// included, "" for a text run or a token built by hand.
+	tag string
 	// parsedVar and parsedFuncs cache the result of getVarAndFunctions for
 	// FilteredVariableToken, computed once at parse time. renderVariable would
 	// otherwise re-split and re-classify RawValue on every render, which
@@ -44,6 +109,22 @@ type BaseToken struct {
 	// "not cached" and the renderer falls back to parsing on demand.
 	parsedVar   string
 	parsedFuncs []Func
+	// parsedExpr caches the parsed expression of an IfToken's condition, a
+	// ForToken's iterable, a SetToken's value, a CallToken, or a
+	// ShorthandIfToken, built once at parse time for the same reason as
+	// parsedFuncs: a condition inside a loop body is evaluated once per
+	// iteration, and re-tokenizing it each time is pure waste once the template
+	// is compiled. nil means "not cached" and the renderer parses on demand.
+	parsedExpr exprNode
+	// parsedMacro, parsedImport and parsedInclude cache the signature of a
+	// MacroToken, the source and names of an ImportToken, and what an
+	// IncludeToken asks for, for the same reason.
+	parsedMacro   *macroSig
+	parsedImport  *importSpec
+	parsedInclude *includeSpec
+	// parsedFilter caches the condition of a ForToken's "if" filter clause, nil
+	// when the loop has none or the token was built by hand.
+	parsedFilter exprNode
 }
 
 // Type returns the token's kind.
@@ -60,3 +141,6 @@ func (bt BaseToken) Params() []string { return bt.ParamVars }
 
 // LoopExpr returns the iteration expression for a ForToken.
 func (bt BaseToken) LoopExpr() string { return bt.LoopExprValue }
+
+// Pos returns where the token starts in its template source.
+func (bt BaseToken) Pos() Position { return bt.PosValue }
@@ -3,6 +3,7 @@ package sintax
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // Sentinel errors returned by the parser and renderer.
@@ -12,8 +13,92 @@ var (
 	ErrFunctionNotFound    = errors.New("function not found")
 	ErrFunctionApplyFailed = errors.New("function failed to apply")
 	ErrMaxDepthExceeded    = errors.New("max template nesting depth exceeded")
+	ErrInvalidSyntax       = errors.New("invalid template syntax")
+	ErrWriteFailed         = errors.New("failed to write rendered output")
+	ErrCanceled            = errors.New("render canceled")
+	ErrLimitExceeded       = errors.New("render limit exceeded")
+	ErrInvalidDelimiters   = errors.New("invalid delimiters")
+	ErrMacroNotFound       = errors.New("macro not found")
+	ErrInvalidArity        = errors.New("wrong number of modifier arguments")
+	ErrUnescapable         = errors.New("value cannot be escaped where it sits")
 )
 
+// SyntaxError reports a template the parser rejected, with the position of the
+// offending spot so an editor can point a template author straight at it. Reach
+// it with errors.As. errors.Is matches it against ErrInvalidSyntax.
+type SyntaxError struct {
+	// Msg describes what is wrong, such as "unclosed tag, missing \"}}\"".
+	Msg string
+	// Line and Column are the 1-based position of the offending spot. Column
+	// counts characters rather than bytes.
+	Line   int
+	Column int
+	// Offset is the 0-based byte offset of the offending spot.
+	Offset int
+	// Snippet is the full source line the offending spot sits on.
+	Snippet string
+}
+
+var _ error = (*SyntaxError)(nil)
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Unwrap lets errors.Is match a syntax error against ErrInvalidSyntax.
+func (e *SyntaxError) Unwrap() error { return ErrInvalidSyntax }
+
+// Excerpt renders the offending source line with a caret under the offending
+// column, ready to show under the message:
+//
+//	Hello {{ name | | upper }}
+//	               ^
+//
+// Tabs before the caret are kept as tabs, so the caret lines up however wide the
+// reader's tab stops are.
+func (e *SyntaxError) Excerpt() string {
+	var pad strings.Builder
+	col := 1
+	for _, r := range e.Snippet {
+		if col >= e.Column {
+			break
+		}
+		if r == '\t' {
+			pad.WriteByte('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+		col++
+	}
+	return e.Snippet + "\n" + pad.String() + "^"
+}
+
+// CallError reports a modifier call Validate rejected before rendering, with the
+// position of the tag it sits in. Err is ErrFunctionNotFound for a name the
+// engine does not know and ErrInvalidArity for a call with the wrong number of
+// arguments, so errors.Is tells them apart.
+type CallError struct {
+	// Modifier is the name the call uses, such as "upper".
+	Modifier string
+	// Line and Column are the 1-based position of the tag the call sits in.
+	// Column counts characters rather than bytes.
+	Line   int
+	Column int
+	// Offset is the 0-based byte offset of the tag.
+	Offset int
+	// Err is what is wrong with the call.
+	Err error
+}
+
+var _ error = (*CallError)(nil)
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("line %d, column %d: modifier %q: %v", e.Line, e.Column, e.Modifier, e.Err)
+}
+
+// Unwrap exposes ErrFunctionNotFound or ErrInvalidArity to errors.Is.
+func (e *CallError) Unwrap() error { return e.Err }
+
 // ModifierError reports a modifier that failed while rendering a variable's
 // pipeline. A chain such as `{{ text | trim | upper:'z' | lower }}` has several
 // places to fail, and the message alone cannot say which one did, so the failing
@@ -42,7 +127,99 @@ func (e *ModifierError) Error() string {
 // Unwrap exposes the underlying failure to errors.Is and errors.As.
 func (e *ModifierError) Unwrap() error { return e.Err }
 
+// WriteError reports a writer that failed while RenderTo or ExecuteTo streamed
+// output into it. The render stops at the failing write, so everything before
+// Written bytes reached the writer and nothing after it was attempted. Reach it
+// with errors.As. errors.Is matches it against ErrWriteFailed and against
+// whatever the writer itself returned.
+type WriteError struct {
+	// Written is the number of bytes the writer accepted before it failed.
+	Written int64
+	// Err is the failure the writer reported, io.ErrShortWrite for a writer
+	// that accepted less than it was given without saying why.
+	Err error
+}
+
+var _ error = (*WriteError)(nil)
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("%v after %d bytes: %v", ErrWriteFailed, e.Written, e.Err)
+}
+
+// Unwrap exposes both ErrWriteFailed and the writer's own failure to errors.Is
+// and errors.As.
+func (e *WriteError) Unwrap() []error { return []error{ErrWriteFailed, e.Err} }
+
+// LimitError reports a render that tripped one of the caps set with WithLimits.
+// Reach it with errors.As to learn which. errors.Is matches it against
+// ErrLimitExceeded.
+type LimitError struct {
+	// Limit is the name of the Limits field that tripped, such as
+	// "MaxLoopIterations".
+	Limit string
+	// Max is the value that field was set to.
+	Max int64
+}
+
+var _ error = (*LimitError)(nil)
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %s of %d", ErrLimitExceeded, e.Limit, e.Max)
+}
+
+// Unwrap lets errors.Is match a limit error against ErrLimitExceeded.
+func (e *LimitError) Unwrap() error { return ErrLimitExceeded }
+
+// IncludeError reports a failure inside a template an include tag rendered,
+// with the chain of includes that led to it, so the message says which file
+// failed and how the render got there:
+//
+//	line 4 -> group.tpl:12 -> row.tpl:3: failed to render variable token 'tx': variable not found
+//
+// Reach it with errors.As. errors.Is and errors.As see through it to Err.
+type IncludeError struct {
+	// Chain is the path to the failure, outermost first. Every frame but the
+	// last is an include tag, and the last is the tag that failed.
+	Chain []IncludeFrame
+	// Err is the failure itself.
+	Err error
+}
+
+// IncludeFrame is one step of an IncludeError's chain.
+type IncludeFrame struct {
+	// Name is the template the tag sits in, as the loader knows it, or "" for
+	// the template the render started from.
+	Name string
+	// Line is the 1-based line of the tag.
+	Line int
+}
+
+func (f IncludeFrame) String() string {
+	if f.Name == "" {
+		return fmt.Sprintf("line %d", f.Line)
+	}
+	return fmt.Sprintf("%s:%d", f.Name, f.Line)
+}
+
+var _ error = (*IncludeError)(nil)
+
+func (e *IncludeError) Error() string {
+	steps := make([]string, len(e.Chain))
+	for i, f := range e.Chain {
+		steps[i] = f.String()
+	}
+	return fmt.Sprintf("%s: %v", strings.Join(steps, " -> "), e.Err)
+}
+
+// Unwrap exposes the underlying failure to errors.Is and errors.As.
+func (e *IncludeError) Unwrap() error { return e.Err }
+
 // Sintax renders a template string against a variable set.
+//
+// The engine New returns has more than this: Compile, RenderTo, RenderContext,
+// RenderToContext, Inspect, Validate and Modifiers. They are methods of the
+// engine rather than of Sintax, so an implementation or mock of Sintax outside
+// the package keeps satisfying it.
 type Sintax interface {
 	Render(template string, vars map[string]any) (any, error)
 	RenderString(template string, vars map[string]any) (string, error)
@@ -53,7 +230,9 @@ type Parser interface {
 	Parse(template string) ([]Token, error)
 }
 
-// Renderer renders a token stream against a variable set.
+// Renderer renders a token stream against a variable set. TokenRenderer also
+// streams and stops on a context, with RenderTo, RenderContext and
+// RenderToContext, kept off Renderer for the same reason as Sintax's.
 type Renderer interface {
 	Render(tokens []Token, vars map[string]any) (any, error)
 }
@@ -0,0 +1,152 @@
+package sintax
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/toaweme/sintax/functions"
+)
+
+// undefinedMode is what an UndefinedPolicy does with a miss.
+type undefinedMode int
+
+const (
+	undefinedStrict undefinedMode = iota
+	undefinedEmpty
+	undefinedKeepTag
+	undefinedCallback
+)
+
+// UndefinedPolicy is what a render does with a value tag whose value is
+// missing: a variable that does not exist, or a miss nothing in its pipeline
+// answered. It covers tags that write a value, `{{ x }}` and its pipelines,
+// ternaries and calls. A condition, a loop's iterable and a set are not
+// covered, since a placeholder there would change what renders rather than
+// mark where a value goes. See WithUndefined.
+type UndefinedPolicy struct {
+	mode     undefinedMode
+	callback func(name string) (any, error)
+}
+
+var (
+	// UndefinedStrict fails the render with the miss, wrapping
+	// ErrVariableNotFound for an absent variable. It is the default.
+	UndefinedStrict = UndefinedPolicy{}
+	// UndefinedEmpty renders a missing value as nothing.
+	UndefinedEmpty = UndefinedPolicy{mode: undefinedEmpty}
+	// UndefinedKeepTag renders a missing value as the tag that asked for it,
+	// `{{ user.name }}`, exactly as the template wrote it, so a draft shows what
+	// is still to be filled in.
+	UndefinedKeepTag = UndefinedPolicy{mode: undefinedKeepTag}
+)
+
+// UndefinedCallback renders a missing value as whatever fn returns for it. fn
+// is handed the variable the tag reads, or the tag's expression when it reads
+// no single variable, such as a ternary. An error from fn fails the render.
+// A nil fn is UndefinedEmpty.
+func UndefinedCallback(fn func(name string) (any, error)) UndefinedPolicy {
+	if fn == nil {
+		return UndefinedEmpty
+	}
+	return UndefinedPolicy{mode: undefinedCallback, callback: fn}
+}
+
+// WithUndefined sets what a render does with a missing value. Under any policy
+// but UndefinedStrict, each miss the policy answered is recorded in the
+// render's UndefinedLog, when it has one, see LogUndefined.
+func WithUndefined(policy UndefinedPolicy) Option {
+	return func(c *config) { c.undefined = policy }
+}
+
+// Undefined is a missing value a render went past under its UndefinedPolicy.
+type Undefined struct {
+	// Name is the variable the tag reads, or its expression, as UndefinedCallback
+	// is handed it.
+	Name string
+	// Template is the loader name of the template the tag is in, "" for the one
+	// the render started from, and Pos where the tag starts in it.
+	Template string
+	Pos      Position
+	// Err is the miss the policy answered, the error UndefinedStrict would have
+	// failed with.
+	Err error
+}
+
+// UndefinedLog collects the misses of the renders it is attached to, in the
+// order they happened. It is safe to share between concurrent renders.
+type UndefinedLog struct {
+	mu     sync.Mutex
+	misses []Undefined
+}
+
+// Misses returns what the log has collected.
+func (l *UndefinedLog) Misses() []Undefined {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Undefined(nil), l.misses...)
+}
+
+func (l *UndefinedLog) add(u Undefined) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.misses = append(l.misses, u)
+}
+
+type undefinedLogKey struct{}
+
+// LogUndefined returns a copy of ctx that makes a render under it record each
+// miss its UndefinedPolicy answered into log, so a preview can highlight them.
+func LogUndefined(ctx context.Context, log *UndefinedLog) context.Context {
+	return context.WithValue(ctx, undefinedLogKey{}, log)
+}
+
+func undefinedLogFrom(ctx context.Context) *UndefinedLog {
+	log, _ := ctx.Value(undefinedLogKey{}).(*UndefinedLog)
+	return log
+}
+
+// answerUndefined answers err, the failure of the value token, under the
+// render's policy. It reports false when err is no miss or the policy is
+// strict, leaving the failure to the caller.
+func (r *TokenRenderer) answerUndefined(token Token, err error) (any, bool, error) {
+	if r.undefined.mode == undefinedStrict {
+		return nil, false, nil
+	}
+	if !errors.Is(err, ErrVariableNotFound) && !errors.Is(err, functions.ErrAllowsDefaultFunc) {
+		return nil, false, nil
+	}
+	name := token.Name()
+	if name == "" {
+		name = strings.TrimSpace(token.Raw())
+	}
+
+	var value any
+	switch r.undefined.mode {
+	case undefinedEmpty:
+		value = ""
+	case undefinedKeepTag:
+		// the tag is template text, so auto-escaping leaves it as written
+		value = SafeString(r.tagSource(token))
+	default:
+		v, cbErr := r.undefined.callback(name)
+		if cbErr != nil {
+			return nil, true, cbErr
+		}
+		value = v
+	}
+	if r.undefinedLog != nil {
+		r.undefinedLog.add(Undefined{Name: name, Template: r.name, Pos: tokenPos(token), Err: err})
+	}
+	return value, true, nil
+}
+
+// tagSource returns token's tag as the template wrote it, or rebuilt from its
+// contents for a token built by hand.
+func (r *TokenRenderer) tagSource(token Token) string {
+	if bt, ok := token.(BaseToken); ok && bt.tag != "" {
+		return bt.tag
+	}
+	return r.parser.opener + " " + strings.TrimSpace(token.Raw()) + " " + r.parser.closer
+}
@@ -0,0 +1,132 @@
+package sintax
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+	"github.com/toaweme/sintax/functions/fs"
+)
+
+func Test_E2E_Undefined(t *testing.T) {
+	vars := map[string]any{
+		"name": "ada",
+		"cfg":  map[string]any{"host": "db"},
+		"pair": []any{1, 2},
+	}
+	placeholder := UndefinedCallback(func(name string) (any, error) {
+		return "[" + name + "?]", nil
+	})
+
+	testCases := []struct {
+		name     string
+		policy   UndefinedPolicy
+		template string
+		want     string
+	}{
+		{
+			name:     "empty",
+			policy:   UndefinedEmpty,
+			template: "Hi {{ name }} {{ missing }}{{ cfg | key:'port' }}!",
+			want:     "Hi ada !",
+		},
+		{
+			name:     "keep tag",
+			policy:   UndefinedKeepTag,
+			template: "Hi {{ name }} {{missing|upper}} {{ cfg | key:'port' }}",
+			want:     "Hi ada {{missing|upper}} {{ cfg | key:'port' }}",
+		},
+		{
+			name:     "a kept tag keeps its trim markers",
+			policy:   UndefinedKeepTag,
+			template: "a \n{{-  missing   -}}\n b",
+			want:     "a{{-  missing   -}}b",
+		},
+		{
+			name:     "callback",
+			policy:   placeholder,
+			template: "{{ user.email }} {{ missing ? 'a' : 'b' }} {{ nope | upper }}",
+			want:     "[user.email?] [missing ? 'a' : 'b'?] [nope?]",
+		},
+		{
+			name:     "a default still answers first",
+			policy:   UndefinedKeepTag,
+			template: "{{ missing | default:'x' }}",
+			want:     "x",
+		},
+		{
+			name:     "inside a loop and a macro",
+			policy:   UndefinedEmpty,
+			template: "{{ macro m() }}<{{ gone }}>{{ endmacro }}{{ for x in pair }}{{ m() }}{{ endfor }}",
+			want:     "<><>",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(builtins(), WithUndefined(tt.policy))
+			out, err := s.RenderString(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+func Test_E2E_Undefined_Errors(t *testing.T) {
+	// strict is the default
+	_, err := New(builtins()).Render("{{ missing }}", nil)
+	assert.ErrorIs(t, err, ErrVariableNotFound)
+
+	_, err = New(builtins(), WithUndefined(UndefinedStrict)).Render("{{ missing }}", nil)
+	assert.ErrorIs(t, err, ErrVariableNotFound)
+
+	// a condition is not a value tag
+	_, err = New(builtins(), WithUndefined(UndefinedKeepTag)).Render("{{ if missing }}yes{{ endif }}", nil)
+	assert.ErrorIs(t, err, ErrVariableNotFound)
+
+	// a failure that is no miss is not the policy's to answer
+	_, err = New(builtins(), WithUndefined(UndefinedEmpty)).Render("{{ name | nosuchmodifier }}", map[string]any{"name": "x"})
+	assert.ErrorIs(t, err, ErrFunctionNotFound)
+
+	refused := errors.New("refused")
+	_, err = New(builtins(), WithUndefined(UndefinedCallback(func(string) (any, error) {
+		return nil, refused
+	}))).Render("{{ missing }}", nil)
+	assert.ErrorIs(t, err, refused)
+	assert.True(t, strings.Contains(err.Error(), "failed to render variable token 'missing'"), "unexpected error %v", err)
+
+	// a nil callback renders a miss as nothing rather than panicking mid-render
+	out, err := New(builtins(), WithUndefined(UndefinedCallback(nil))).Render("a{{ missing }}b", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "ab", out)
+}
+
+// Each answered miss is recorded with where its tag sits, so a preview can
+// highlight it, including one in an included template.
+func Test_E2E_Undefined_Log(t *testing.T) {
+	loader := fs.MapLoader{"row.tpl": "<td>\n{{ price }}</td>"}
+	s := New(builtins(), WithLoader(loader), WithUndefined(UndefinedKeepTag))
+
+	var log UndefinedLog
+	out, err := s.RenderContext(LogUndefined(context.Background(), &log), "Hi {{ name }}\n{{ include \"row.tpl\" }}", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi {{ name }}\n<td>\n{{ price }}</td>", out)
+
+	misses := log.Misses()
+	assert.Len(t, misses, 2)
+	assert.Equal(t, "name", misses[0].Name)
+	assert.Equal(t, "", misses[0].Template)
+	assert.Equal(t, Position{Offset: 3, Line: 1, Column: 4}, misses[0].Pos)
+	assert.ErrorIs(t, misses[0].Err, ErrVariableNotFound)
+	assert.Equal(t, "price", misses[1].Name)
+	assert.Equal(t, "row.tpl", misses[1].Template)
+	assert.Equal(t, 2, misses[1].Pos.Line)
+
+	// a strict render records nothing
+	var strict UndefinedLog
+	_, err = New(builtins()).RenderContext(LogUndefined(context.Background(), &strict), "{{ name }}", nil)
+	assert.Error(t, err)
+	assert.Len(t, strict.Misses(), 0)
+}
@@ -0,0 +1,88 @@
+package sintax
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/toaweme/sintax/functions"
+)
+
+// modifierCall is one modifier call of a template and the position of its tag.
+type modifierCall struct {
+	fn  Func
+	pos Position
+}
+
+// WithValidation makes Compile, and so every render, check a template with
+// Validate before it is used, so a call that could only fail in a branch that
+// rarely renders fails every time instead. With WithCache the check runs once
+// per template.
+func WithValidation() Option {
+	return func(c *config) { c.validate = true }
+}
+
+// Validate parses template and checks every modifier call in it, whether or not
+// the branch it sits in would render: the name must be a modifier the engine
+// knows, and a modifier that knows its arities, see functions.ArityOf, or has a
+// definition, see WithModifierDefs, must be handed a number of arguments it
+// accepts. Each rejected call is a *CallError, and together they are joined
+// into the error returned.
+func (s *sintax) Validate(template string) error {
+	if s.err != nil {
+		return s.err
+	}
+	tokens, err := s.parser.Parse(template)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	return s.render.Validate(tokens)
+}
+
+// Validate parses template and checks its modifier calls against an engine
+// configured by opts. See the engine's Validate.
+func Validate(template string, opts ...Option) error {
+	return New(opts...).Validate(template)
+}
+
+// Validate checks every modifier call in tokens against the renderer's
+// modifiers. See the engine's Validate.
+func (r *TokenRenderer) Validate(tokens []Token) error {
+	var errs []error
+	for _, call := range walk(tokens, r.pathAccess).calls {
+		if err := r.checkCall(call.fn); err != nil {
+			errs = append(errs, &CallError{
+				Modifier: call.fn.Name,
+				Line:     call.pos.Line,
+				Column:   call.pos.Column,
+				Offset:   call.pos.Offset,
+				Err:      err,
+			})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkCall resolves fn the way renderVariable does and checks its argument
+// count where the modifier knows its arities. A modifier that does not, such as
+// a contextual one or one written by hand, is checked against its definition
+// when the engine has one.
+func (r *TokenRenderer) checkCall(fn Func) error {
+	var arities functions.Arities
+	var known bool
+	if _, ok := r.ctxFuncs[fn.Name]; ok {
+		// a contextual modifier is a plain function, with no arities to ask
+	} else if aware, ok := r.awareFuncs[fn.Name]; ok {
+		arities, known = functions.ContextArityOf(aware)
+	} else if global, ok := r.funcs[fn.Name]; ok {
+		arities, known = functions.ArityOf(global)
+	} else {
+		return ErrFunctionNotFound
+	}
+	if def, ok := r.defs[fn.Name]; ok && !known {
+		arities, known = functions.Arities{def.Arity()}, true
+	}
+	if known && !arities.Accepts(len(fn.Args)) {
+		return fmt.Errorf("%w: takes %s, got %d", ErrInvalidArity, arities, len(fn.Args))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package sintax
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toaweme/sintax/assert"
+)
+
+func Test_E2E_Validate(t *testing.T) {
+	s := New(builtins())
+
+	valid := []string{
+		"{{ name | upper | default:'x' }}",
+		"{{ if x | eq:1 }}{{ else }}{{ items | join:', ' }}{{ endif }}",
+		"{{ for x in items if x | gt:1 }}{{ x | decimal:2 }}{{ endfor }}",
+		"{{ body | template }}",
+	}
+	for _, template := range valid {
+		assert.NoError(t, s.Validate(template))
+	}
+
+	// a typo in a branch that never renders is caught up front
+	template := "{{ if false }}\n{{ name }}\n  {{ name | uppr }}{{ name | upper:'x' }}\n{{ endif }}"
+	err := s.Validate(template)
+	assert.ErrorIs(t, err, ErrFunctionNotFound)
+	assert.ErrorIs(t, err, ErrInvalidArity)
+
+	var callErr *CallError
+	assert.True(t, errors.As(err, &callErr), "expected a *CallError, got %v", err)
+	assert.Equal(t, "uppr", callErr.Modifier)
+	assert.Equal(t, 3, callErr.Line)
+	assert.Equal(t, 3, callErr.Column)
+	assert.True(t, strings.Contains(err.Error(), `line 3, column 20: modifier "upper": wrong number of modifier arguments: takes 0, got 1`), "unexpected error %v", err)
+
+	// the same template renders, since the bad branch is never taken
+	_, err = s.Render(template, map[string]any{"name": "ada"})
+	assert.NoError(t, err)
+
+	err = Validate("{{ x | default }}", builtins())
+	assert.ErrorIs(t, err, ErrInvalidArity)
+}
+
+// WithValidation checks a template when it is compiled, so it fails every
+// render rather than only the ones that reach the bad call.
+func Test_E2E_Validate_OnCompile(t *testing.T) {
+	s := New(builtins(), WithValidation())
+
+	_, err := s.Render("{{ if false }}{{ x | nope }}{{ endif }}", nil)
+	assert.ErrorIs(t, err, ErrFunctionNotFound)
+	assert.True(t, strings.Contains(err.Error(), "failed to validate template"), "unexpected error %v", err)
+
+	out, err := s.RenderString("{{ x | upper }}", map[string]any{"x": "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "A", out)
+}
+
+// A modifier written by hand has no arities to check, and is never run to ask.
+func Test_E2E_Validate_HandWritten(t *testing.T) {
+	s := New(builtins(), WithContextAwareModifiers(map[string]ContextAwareModifier{
+		"slow": func(ctx context.Context, _ any, _ []any) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}))
+	assert.NoError(t, s.Validate("{{ x | slow:1:2 }}"))
+}